@@ -0,0 +1,273 @@
+// cmd/api-gateway/apikeys.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/handlers"
+)
+
+const consumersUsage = `Penggunaan: gateway consumers <perintah> [opsi] <nama>
+
+Perintah:
+  add <nama> [--roles a,b] [--rate-limit N --rate-window DETIK]   Tambah consumer
+  set <nama> [--roles a,b] [--rate-limit N --rate-window DETIK]   Ubah role/rate limit
+  delete <nama>                                                   Hapus consumer dan semua key-nya
+  list                                                            Tampilkan semua consumer
+`
+
+const apiKeysUsage = `Penggunaan: gateway apikeys <perintah> [opsi] <argumen>
+
+Perintah:
+  create <consumer> [--name label] [--expires-in 720h]   Buat API key (hanya ditampilkan sekali)
+  list [consumer]                                        Tampilkan API key
+  revoke <prefix>                                        Cabut API key
+  expire <prefix> [--in 24h | --at 2026-01-02T15:04:05Z] Atur kedaluwarsa (default sekarang)
+`
+
+// openAPIKeyRepository membuka database untuk subcommand consumers/apikeys.
+func openAPIKeyRepository(cfg config.Config) (*database.APIKeyRepository, error) {
+	db, err := database.InitDB(cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	return database.NewAPIKeyRepository(db), nil
+}
+
+// runConsumersCommand menjalankan subcommand "consumers" dan mengembalikan exit code.
+func runConsumersCommand(cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, consumersUsage)
+		return 2
+	}
+
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("consumers "+cmd, flag.ContinueOnError)
+	roles := fs.String("roles", "", "Daftar role dipisah koma")
+	rateLimit := fs.Int("rate-limit", 0, "Jumlah request maksimum per --rate-window, 0 = hanya RATE_LIMIT global")
+	rateWindow := fs.Int("rate-window", 0, "Jendela rate limit dalam detik")
+	if err := fs.Parse(reorderFlags(fs, args)); err != nil {
+		return 2
+	}
+	if (*rateLimit > 0) != (*rateWindow > 0) || *rateLimit < 0 || *rateWindow < 0 {
+		fmt.Fprintln(os.Stderr, "--rate-limit dan --rate-window harus diisi bersamaan")
+		return 2
+	}
+
+	keys, err := openAPIKeyRepository(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal membuka database: %v\n", err)
+		return 1
+	}
+	ctx := context.Background()
+
+	if cmd == "list" {
+		return listConsumers(ctx, keys)
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, consumersUsage)
+		return 2
+	}
+	name := fs.Arg(0)
+
+	switch cmd {
+	case "add":
+		consumer := &database.Consumer{
+			Name:               name,
+			Roles:              splitRoles(*roles),
+			RateLimitRequests:  *rateLimit,
+			RateLimitWindowSec: *rateWindow,
+		}
+		if err := keys.CreateConsumer(ctx, consumer); err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal menambah consumer: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Consumer %s ditambahkan (id %s, role %v)\n", consumer.Name, consumer.ID, consumer.Roles)
+	case "set":
+		consumer, err := keys.FindConsumer(ctx, name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %s\n", err, name)
+			return 1
+		}
+		// Hanya flag yang diberikan yang mengubah nilai
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "roles":
+				consumer.Roles = splitRoles(*roles)
+			case "rate-limit", "rate-window":
+				consumer.RateLimitRequests, consumer.RateLimitWindowSec = *rateLimit, *rateWindow
+			}
+		})
+		if err := keys.UpdateConsumer(ctx, name, consumer.Roles, consumer.RateLimitRequests, consumer.RateLimitWindowSec); err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal mengubah consumer: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Consumer %s diubah (role %v, rate limit %s)\n", consumer.Name, consumer.Roles, formatRateLimit(*consumer))
+	case "delete":
+		if err := keys.DeleteConsumer(ctx, name); err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal menghapus consumer: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Consumer %s dan semua API key-nya dihapus\n", name)
+	default:
+		fmt.Fprint(os.Stderr, consumersUsage)
+		return 2
+	}
+	return 0
+}
+
+func listConsumers(ctx context.Context, keys *database.APIKeyRepository) int {
+	list, err := keys.ListConsumers(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal membaca consumer: %v\n", err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tID\tROLES\tRATE LIMIT")
+	for _, c := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Name, c.ID, strings.Join(c.Roles, ","), formatRateLimit(c))
+	}
+	w.Flush()
+	return 0
+}
+
+func formatRateLimit(c database.Consumer) string {
+	if c.RateLimitRequests == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%ds", c.RateLimitRequests, c.RateLimitWindowSec)
+}
+
+// runAPIKeysCommand menjalankan subcommand "apikeys" dan mengembalikan exit code.
+func runAPIKeysCommand(cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, apiKeysUsage)
+		return 2
+	}
+
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("apikeys "+cmd, flag.ContinueOnError)
+	label := fs.String("name", "", "Label API key (hanya untuk create)")
+	expiresIn := fs.String("expires-in", "", "Masa berlaku API key, misal 720h (hanya untuk create)")
+	in := fs.String("in", "", "Kedaluwarsa setelah durasi ini (hanya untuk expire)")
+	at := fs.String("at", "", "Kedaluwarsa pada waktu RFC 3339 (hanya untuk expire)")
+	if err := fs.Parse(reorderFlags(fs, args)); err != nil {
+		return 2
+	}
+
+	keys, err := openAPIKeyRepository(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal membuka database: %v\n", err)
+		return 1
+	}
+	ctx := context.Background()
+
+	if cmd == "list" {
+		if fs.NArg() > 1 {
+			fmt.Fprint(os.Stderr, apiKeysUsage)
+			return 2
+		}
+		return listAPIKeys(ctx, keys, fs.Arg(0))
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, apiKeysUsage)
+		return 2
+	}
+	arg := fs.Arg(0)
+
+	switch cmd {
+	case "create":
+		expiresAt, err := handlers.ResolveExpiry(*expiresIn, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "--expires-in tidak valid: %v\n", err)
+			return 2
+		}
+		consumer, err := keys.FindConsumer(ctx, arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %s\n", err, arg)
+			return 1
+		}
+		key, record, err := handlers.IssueAPIKey(ctx, keys, consumer, *label, expiresAt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal membuat API key: %v\n", err)
+			return 1
+		}
+		// Key di stdout agar bisa langsung ditangkap skrip, keterangan di stderr
+		fmt.Fprintf(os.Stderr, "API key %s dibuat untuk consumer %s. Simpan sekarang, key tidak bisa ditampilkan lagi:\n", record.Prefix, consumer.Name)
+		fmt.Println(key)
+	case "revoke":
+		if err := keys.RevokeKey(ctx, arg); err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal mencabut API key: %v\n", err)
+			return 1
+		}
+		fmt.Printf("API key %s dicabut\n", arg)
+	case "expire":
+		var expiresAt *time.Time
+		if *at != "" {
+			t, err := time.Parse(time.RFC3339, *at)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "--at tidak valid: %v\n", err)
+				return 2
+			}
+			expiresAt = &t
+		}
+		expiresAt, err := handlers.ResolveExpiry(*in, expiresAt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 2
+		}
+		if expiresAt == nil {
+			t := time.Now()
+			expiresAt = &t
+		}
+		if err := keys.SetKeyExpiry(ctx, arg, *expiresAt); err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal mengatur kedaluwarsa API key: %v\n", err)
+			return 1
+		}
+		fmt.Printf("API key %s kedaluwarsa pada %s\n", arg, expiresAt.Format(time.RFC3339))
+	default:
+		fmt.Fprint(os.Stderr, apiKeysUsage)
+		return 2
+	}
+	return 0
+}
+
+func listAPIKeys(ctx context.Context, keys *database.APIKeyRepository, consumerName string) int {
+	var consumerID string
+	if consumerName != "" {
+		consumer, err := keys.FindConsumer(ctx, consumerName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %s\n", err, consumerName)
+			return 1
+		}
+		consumerID = consumer.ID
+	}
+	list, err := keys.ListKeys(ctx, consumerID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal membaca API key: %v\n", err)
+		return 1
+	}
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PREFIX\tCONSUMER\tNAME\tSTATUS\tEXPIRES\tLAST USED")
+	for _, k := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", k.Prefix, k.Consumer.Name, k.Name, k.Status(now), formatTime(k.ExpiresAt), formatTime(k.LastUsedAt))
+	}
+	w.Flush()
+	return 0
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,205 @@
+// cmd/api-gateway/clients.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/handlers"
+
+	"gorm.io/gorm"
+)
+
+const clientsUsage = `Penggunaan: gateway clients <perintah> [opsi] <client_id>
+
+Perintah:
+  add <client_id> [--scopes a,b] [--roles a,b] [--ttl 30m]   Daftarkan OAuth client (secret hanya ditampilkan sekali)
+  set <client_id> [--scopes a,b] [--roles a,b] [--ttl 30m]   Ubah scope/role/TTL token
+  rotate-secret <client_id>                                  Buat secret baru, secret lama langsung tidak berlaku
+  disable <client_id>                                        Nonaktifkan client
+  enable <client_id>                                         Aktifkan kembali client
+  delete <client_id>                                         Hapus client
+  list                                                       Tampilkan semua client
+
+disable, delete, dan set yang memperpendek TTL mencabut token yang sudah
+terbit. Pencabutan ditulis ke database dan berlaku di gateway yang sedang
+berjalan setelah AUTH.REVOCATION.CLEANUP_INTERVAL berikutnya; untuk efek
+langsung pakai admin API (PUT/DELETE /admin/clients/<client_id>).
+`
+
+// runClientsCommand menjalankan subcommand "clients" untuk mengelola OAuth
+// client dan mengembalikan exit code.
+func runClientsCommand(cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, clientsUsage)
+		return 2
+	}
+
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("clients "+cmd, flag.ContinueOnError)
+	scopes := fs.String("scopes", "", "Daftar scope yang boleh diminta, dipisah koma")
+	roles := fs.String("roles", "", "Daftar role dipisah koma")
+	ttl := fs.String("ttl", "", "Masa berlaku token, misal 30m; kosong = AUTH.CLIENT_CREDENTIALS.TOKEN_TTL")
+	if err := fs.Parse(reorderFlags(fs, args)); err != nil {
+		return 2
+	}
+
+	db, err := database.InitDB(cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal membuka database: %v\n", err)
+		return 1
+	}
+	clients := database.NewOAuthClientRepository(db)
+	ctx := context.Background()
+
+	cc := cfg.Auth.ClientCredentials
+	if cmd == "list" {
+		return listClients(ctx, clients, cc)
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, clientsUsage)
+		return 2
+	}
+	clientID := fs.Arg(0)
+
+	switch cmd {
+	case "add":
+		client := &database.OAuthClient{ClientID: clientID}
+		if err := handlers.ApplyOAuthClientSettings(client, splitRoles(*scopes), splitRoles(*roles), *ttl, cc.MaxTokenTTL); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		secret, err := handlers.RegisterOAuthClient(ctx, clients, client)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal mendaftarkan client: %v\n", err)
+			return 1
+		}
+		// Secret di stdout agar bisa langsung ditangkap skrip, keterangan di stderr
+		fmt.Fprintf(os.Stderr, "OAuth client %s didaftarkan (scope %v, role %v). Simpan secret sekarang, tidak bisa ditampilkan lagi:\n", client.ClientID, client.Scopes, client.Roles)
+		fmt.Println(secret)
+	case "set":
+		client, err := clients.Find(ctx, clientID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %s\n", err, clientID)
+			return 1
+		}
+		// Hanya flag yang diberikan yang mengubah nilai
+		prevTTL := client.TokenTTL(cc.TokenTTL)
+		newScopes, newRoles, newTTL := client.Scopes, client.Roles, ""
+		if client.TokenTTLSeconds > 0 {
+			newTTL = client.TokenTTL(0).String()
+		}
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "scopes":
+				newScopes = splitRoles(*scopes)
+			case "roles":
+				newRoles = splitRoles(*roles)
+			case "ttl":
+				newTTL = *ttl
+			}
+		})
+		if err := handlers.ApplyOAuthClientSettings(client, newScopes, newRoles, newTTL, cc.MaxTokenTTL); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if err := clients.Update(ctx, client); err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal mengubah client: %v\n", err)
+			return 1
+		}
+		fmt.Printf("OAuth client %s diubah (scope %v, role %v, TTL %s)\n", client.ClientID, client.Scopes, client.Roles, formatClientTTL(*client, cc))
+		if client.TokenTTL(cc.TokenTTL) < prevTTL {
+			return revokeClientTokens(ctx, cfg, db, client, prevTTL)
+		}
+	case "rotate-secret":
+		secret, err := handlers.RotateOAuthClientSecret(ctx, clients, clientID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal merotasi secret: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Secret baru OAuth client %s. Simpan sekarang, tidak bisa ditampilkan lagi:\n", clientID)
+		fmt.Println(secret)
+	case "disable", "enable":
+		client, err := clients.Find(ctx, clientID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %s\n", err, clientID)
+			return 1
+		}
+		client.Disabled = cmd == "disable"
+		if err := clients.Update(ctx, client); err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal mengubah client: %v\n", err)
+			return 1
+		}
+		fmt.Printf("OAuth client %s di-%s\n", client.ClientID, cmd)
+		if client.Disabled {
+			return revokeClientTokens(ctx, cfg, db, client, 0)
+		}
+	case "delete":
+		client, err := clients.Find(ctx, clientID)
+		if err == nil {
+			err = clients.Delete(ctx, clientID)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal menghapus client: %v\n", err)
+			return 1
+		}
+		fmt.Printf("OAuth client %s dihapus\n", clientID)
+		return revokeClientTokens(ctx, cfg, db, client, 0)
+	default:
+		fmt.Fprint(os.Stderr, clientsUsage)
+		return 2
+	}
+	return 0
+}
+
+// revokeClientTokens menyimpan pencabutan semua token client ke database.
+// prevTTL adalah TTL client sebelum diubah, atau 0.
+func revokeClientTokens(ctx context.Context, cfg config.Config, db *gorm.DB, client *database.OAuthClient, prevTTL time.Duration) int {
+	ttl := handlers.ClientRevocationTTL(cfg.Auth.ClientCredentials, prevTTL, client.TokenTTL(0))
+	revocations := auth.NewRevocationStore(database.NewRevocationRepository(db))
+	if err := revocations.RevokeUser(ctx, client.ID, ttl); err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal menyimpan pencabutan token client: %v\n", err)
+		return 1
+	}
+	if cfg.Auth.Revocation.Persist {
+		fmt.Printf("Token client yang sudah terbit ditolak gateway dalam %s\n", cfg.Auth.Revocation.CleanupInterval)
+	} else {
+		fmt.Println("Peringatan: AUTH.REVOCATION.PERSIST nonaktif; token client yang sudah terbit tetap berlaku sampai kedaluwarsa. Gunakan admin API")
+	}
+	return 0
+}
+
+func listClients(ctx context.Context, clients *database.OAuthClientRepository, cc config.ClientCredentialsConfig) int {
+	list, err := clients.List(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal membaca client: %v\n", err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CLIENT ID\tSCOPES\tROLES\tTOKEN TTL\tSTATUS\tLAST USED")
+	for _, c := range list {
+		status := "active"
+		if c.Disabled {
+			status = "disabled"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", c.ClientID, strings.Join(c.Scopes, " "), strings.Join(c.Roles, ","), formatClientTTL(c, cc), status, formatTime(c.LastUsedAt))
+	}
+	w.Flush()
+	return 0
+}
+
+// formatClientTTL menampilkan TTL token client, ditandai jika memakai default.
+func formatClientTTL(c database.OAuthClient, cc config.ClientCredentialsConfig) string {
+	if c.TokenTTLSeconds == 0 {
+		return cc.TokenTTL.String() + " (default)"
+	}
+	return c.TokenTTL(0).String()
+}
@@ -2,11 +2,19 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"api-gateway-go/pkg/auth"
 	"api-gateway-go/pkg/config"
 	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/middleware"
 	"api-gateway-go/pkg/routes"
 
 	"github.com/gin-gonic/gin"
@@ -19,7 +27,25 @@ func main() {
 		log.Fatalf("Gagal memuat konfigurasi: %v", err)
 	}
 
-	// Inisialisasi Database GORM
+	// Subcommand CLI, misal "gateway users add alice"
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "users":
+			os.Exit(runUsersCommand(cfg, os.Args[2:]))
+		case "consumers":
+			os.Exit(runConsumersCommand(cfg, os.Args[2:]))
+		case "apikeys":
+			os.Exit(runAPIKeysCommand(cfg, os.Args[2:]))
+		case "clients":
+			os.Exit(runClientsCommand(cfg, os.Args[2:]))
+		case "serve":
+		default:
+			fmt.Fprintf(os.Stderr, "Perintah tidak dikenal: %s\nPenggunaan: gateway [serve | users ... | consumers ... | apikeys ... | clients ...]\n", os.Args[1])
+			os.Exit(2)
+		}
+	}
+
+	// Inisialisasi Database GORM (termasuk migrasi skema)
 	var db *gorm.DB // Sekarang bertipe *gorm.DB
 	db, err = database.InitDB(cfg.Database)
 	if err != nil {
@@ -45,10 +71,59 @@ func main() {
 	} else {
 		gin.SetMode(gin.DebugMode)
 	}
-	router := gin.Default()
+	// Gateway menyimpan generasi rute aktif dan menukarnya saat konfigurasi di-reload
+	deps := routes.Dependencies{
+		Users:         database.NewUserRepository(db),
+		RefreshTokens: database.NewRefreshTokenRepository(db),
+		Revocations:   newRevocationStore(cfg.Auth.Revocation, db),
+		APIKeys:       database.NewAPIKeyRepository(db),
+		OAuthClients:  database.NewOAuthClientRepository(db),
+		Passwords:     auth.NewPasswordLimiter(cfg.Auth.PasswordVerify),
+	}
+	go purgeExpiredRefreshTokens(deps.RefreshTokens)
+	go deps.Revocations.RunJanitor(context.Background(), cfg.Auth.Revocation.CleanupInterval)
+	if cfg.Auth.LoginProtection.Enabled {
+		deps.LoginGuard = auth.NewLoginGuard(cfg.Auth.LoginProtection)
+		go deps.LoginGuard.RunJanitor(context.Background(), time.Minute)
+	}
+	gateway, err := routes.NewGateway(cfg, func() (config.Config, error) {
+		return config.LoadConfig(".")
+	}, deps)
+	if err != nil {
+		log.Fatalf("Gagal menyiapkan rute: %v", err)
+	}
 
-	// Setup Rute, sekarang teruskan *gorm.DB
-	routes.SetupRoutes(router, cfg, db)
+	// Reload saat config.yml berubah atau saat menerima SIGHUP
+	stopWatch, err := config.WatchConfig(".", func() { gateway.Reload("file-change") })
+	if err != nil {
+		log.Printf("Peringatan: tidak bisa memantau file konfigurasi, reload hanya lewat SIGHUP: %v", err)
+	} else {
+		defer stopWatch()
+	}
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	go func() {
+		for range sighup {
+			gateway.Reload("SIGHUP")
+		}
+	}()
+
+	if cfg.Admin.Enabled {
+		adminRouter := gin.New()
+		adminRouter.Use(gin.CustomRecovery(middleware.RecoveryHandler), middleware.RequestIDMiddleware(cfg.RequestID))
+		routes.SetupAdminRoutes(adminRouter, gateway, cfg.Admin)
+		adminServer := &http.Server{
+			Addr:              cfg.Admin.Addr,
+			Handler:           adminRouter,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		}
+		go func() {
+			log.Printf("Server admin berjalan di %s", cfg.Admin.Addr)
+			if err := adminServer.ListenAndServe(); err != nil {
+				log.Printf("Server admin berhenti: %v", err)
+			}
+		}()
+	}
 
 	port := cfg.ServerPort
 	if port == "" {
@@ -62,7 +137,47 @@ func main() {
 
 	log.Printf("API Gateway (dengan GORM) siap dijalankan di port :%s", port)
 	// ... log lainnya
-	if err := router.Run(":" + port); err != nil {
+	// Timeout server dari SERVER; timeout ke upstream diatur per rute
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           gateway,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatalf("Gagal menjalankan server Gin: %v", err)
 	}
 }
+
+// newRevocationStore membuat daftar pencabutan access token. Jika PERSIST
+// aktif, entri disimpan di database dan dimuat ulang saat start.
+func newRevocationStore(cfg config.RevocationConfig, db *gorm.DB) *auth.RevocationStore {
+	if !cfg.Persist {
+		return auth.NewRevocationStore(nil)
+	}
+	store := auth.NewRevocationStore(database.NewRevocationRepository(db))
+	n, err := store.Load(context.Background())
+	if err != nil {
+		log.Fatalf("Gagal memuat daftar pencabutan token: %v", err)
+	}
+	log.Printf("%d entri pencabutan token dimuat dari database", n)
+	return store
+}
+
+// purgeExpiredRefreshTokens menghapus refresh token kedaluwarsa setiap jam agar
+// tabel tidak terus membesar. Token dibiarkan sehari setelah kedaluwarsa supaya
+// percobaan reuse yang terlambat masih tercatat sebagai reuse.
+func purgeExpiredRefreshTokens(repo *database.RefreshTokenRepository) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		n, err := repo.DeleteExpired(context.Background(), time.Now().Add(-24*time.Hour))
+		if err != nil {
+			log.Printf("Gagal menghapus refresh token kedaluwarsa: %v", err)
+		} else if n > 0 {
+			log.Printf("%d refresh token kedaluwarsa dihapus", n)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// cmd/api-gateway/users.go
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+
+	"golang.org/x/term"
+	"gorm.io/gorm"
+)
+
+const usersUsage = `Penggunaan: gateway users <perintah> [opsi] <username>
+
+Perintah:
+  add <username> [--roles a,b] [--password-stdin]   Tambah pengguna
+  passwd <username> [--password-stdin]              Ganti password
+  disable <username>                                Nonaktifkan pengguna
+  enable <username>                                 Aktifkan kembali pengguna
+  reset-mfa <username>                              Nonaktifkan MFA (authenticator hilang)
+  list                                              Tampilkan semua pengguna
+`
+
+// runUsersCommand menjalankan subcommand "users" untuk mengelola akun di
+// database dan mengembalikan exit code.
+func runUsersCommand(cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usersUsage)
+		return 2
+	}
+
+	db, err := database.InitDB(cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal membuka database: %v\n", err)
+		return 1
+	}
+	users := database.NewUserRepository(db)
+	ctx := context.Background()
+
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("users "+cmd, flag.ContinueOnError)
+	roles := fs.String("roles", "", "Daftar role dipisah koma (hanya untuk add)")
+	passwordStdin := fs.Bool("password-stdin", false, "Baca password dari stdin, bukan dari prompt")
+	if err := fs.Parse(reorderFlags(fs, args)); err != nil {
+		return 2
+	}
+
+	if cmd == "list" {
+		return listUsers(ctx, users)
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, usersUsage)
+		return 2
+	}
+	username := fs.Arg(0)
+
+	switch cmd {
+	case "add":
+		hash, err := promptPasswordHash(cfg, *passwordStdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		user := &database.User{Username: username, PasswordHash: hash, Roles: splitRoles(*roles)}
+		if err := users.Create(ctx, user); err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal menambah pengguna: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Pengguna %s ditambahkan (id %s, role %v)\n", user.Username, user.ID, user.Roles)
+	case "passwd":
+		if _, err := users.FindByUsername(ctx, username); err != nil {
+			fmt.Fprintf(os.Stderr, "%v: %s\n", err, username)
+			return 1
+		}
+		hash, err := promptPasswordHash(cfg, *passwordStdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		if err := users.SetPasswordHash(ctx, username, hash); err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal mengganti password: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Password %s diganti\n", username)
+	case "disable", "enable":
+		if err := users.SetDisabled(ctx, username, cmd == "disable"); err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal mengubah status pengguna: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Pengguna %s di-%s\n", username, cmd)
+		if cmd == "disable" {
+			return revokeDisabledUser(ctx, cfg, db, users, username)
+		}
+	case "reset-mfa":
+		user, err := users.FindByUsername(ctx, username)
+		if err == nil {
+			err = users.DisableMFA(ctx, user.ID)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Gagal me-reset MFA: %v\n", err)
+			return 1
+		}
+		fmt.Printf("MFA pengguna %s dinonaktifkan; pengguna bisa mendaftar ulang setelah login\n", username)
+	default:
+		fmt.Fprint(os.Stderr, usersUsage)
+		return 2
+	}
+	return 0
+}
+
+// revokeDisabledUser mencabut token pengguna yang baru dinonaktifkan. Refresh
+// token langsung dicabut di database; pencabutan access token disimpan di
+// daftar pencabutan dan dimuat gateway yang sedang berjalan pada
+// AUTH.REVOCATION.CLEANUP_INTERVAL berikutnya.
+func revokeDisabledUser(ctx context.Context, cfg config.Config, db *gorm.DB, users *database.UserRepository, username string) int {
+	user, err := users.FindByUsername(ctx, username)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal membaca pengguna: %v\n", err)
+		return 1
+	}
+	revocations := auth.NewRevocationStore(database.NewRevocationRepository(db))
+	if err := revocations.RevokeUser(ctx, user.ID, cfg.Auth.AccessTokenTTL); err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal menyimpan pencabutan access token: %v\n", err)
+		return 1
+	}
+	n, err := database.NewRefreshTokenRepository(db).RevokeUser(ctx, user.ID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal mencabut refresh token: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%d refresh token dicabut\n", n)
+	if cfg.Auth.Revocation.Persist {
+		fmt.Printf("Access token yang masih berlaku ditolak gateway dalam %s\n", cfg.Auth.Revocation.CleanupInterval)
+	} else {
+		fmt.Println("Peringatan: AUTH.REVOCATION.PERSIST nonaktif; access token yang masih berlaku tetap diterima sampai kedaluwarsa. Gunakan POST /admin/users/<username>/disable")
+	}
+	return 0
+}
+
+func listUsers(ctx context.Context, users *database.UserRepository) int {
+	list, err := users.List(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Gagal membaca pengguna: %v\n", err)
+		return 1
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "USERNAME\tID\tROLES\tDISABLED\tMFA\tLAST LOGIN")
+	for _, u := range list {
+		lastLogin := "-"
+		if u.LastLoginAt != nil {
+			lastLogin = u.LastLoginAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%v\t%v\t%s\n", u.Username, u.ID, strings.Join(u.Roles, ","), u.Disabled, u.MFAEnabled, lastLogin)
+	}
+	w.Flush()
+	return 0
+}
+
+// promptPasswordHash membaca password (dari terminal tanpa echo, atau satu
+// baris dari stdin) lalu mengembalikan hash-nya.
+func promptPasswordHash(cfg config.Config, fromStdin bool) (string, error) {
+	var password string
+	if fromStdin || !term.IsTerminal(int(os.Stdin.Fd())) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("gagal membaca password dari stdin: %w", err)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	} else {
+		fmt.Fprint(os.Stderr, "Password: ")
+		first, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprint(os.Stderr, "Ulangi password: ")
+		second, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+		if string(first) != string(second) {
+			return "", errors.New("password tidak sama")
+		}
+		password = string(first)
+	}
+	if len(password) < 8 {
+		return "", errors.New("password minimal 8 karakter")
+	}
+	return auth.HashPassword(password, cfg.Auth.PasswordHash)
+}
+
+func splitRoles(s string) []string {
+	var roles []string
+	for _, r := range strings.Split(s, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// reorderFlags memindahkan flag ke depan agar "add alice --roles admin" dan
+// "add --roles admin alice" sama-sama diterima oleh package flag. Flag non-bool
+// di fs dianggap membawa nilai di argumen berikutnya.
+func reorderFlags(fs *flag.FlagSet, args []string) []string {
+	var flags, positional []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		if !strings.HasPrefix(a, "-") {
+			positional = append(positional, a)
+			continue
+		}
+		flags = append(flags, a)
+		// Flag dengan nilai terpisah, misal --roles admin
+		if strings.Contains(a, "=") || i+1 >= len(args) {
+			continue
+		}
+		if f := fs.Lookup(strings.TrimLeft(a, "-")); f != nil {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				i++
+				flags = append(flags, args[i])
+			}
+		}
+	}
+	return append(flags, positional...)
+}
@@ -0,0 +1,396 @@
+// Package idp adalah identity provider tiruan untuk menguji AUTH.TRUSTED_ISSUERS
+// dan AUTH.OIDC secara lokal. Menyajikan dokumen discovery OIDC dan JWKS,
+// authorization code flow dengan PKCE (/authorize dan /token), serta endpoint
+// /dev/token untuk menerbitkan token uji dan /dev/rotate untuk mensimulasikan
+// rotasi kunci. Dipakai oleh dummy-services/oidc-provider dan oleh test
+// gateway lewat httptest.
+// JANGAN dipakai di produksi: siapa pun bisa login sebagai siapa pun.
+package idp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type signingKey struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+// authCode adalah authorization code yang belum ditukar.
+type authCode struct {
+	clientID    string
+	redirectURI string
+	challenge   string // PKCE code challenge S256
+	nonce       string
+	username    string
+	roles       []string
+	amr         []string // Metode autentikasi untuk klaim amr ID token
+	expiresAt   time.Time
+}
+
+// Provider adalah state identity provider tiruan.
+type Provider struct {
+	issuer       string
+	clientSecret string // Jika diisi, /token mewajibkan client_secret
+
+	mu    sync.RWMutex
+	keys  []signingKey // keys[0] adalah kunci aktif; kunci lama tetap dipublikasikan
+	codes map[string]*authCode
+}
+
+// New membuat provider untuk issuer dengan satu kunci aktif. Jika
+// clientSecret diisi, /token mewajibkan client_secret.
+func New(issuer, clientSecret string) (*Provider, error) {
+	p := &Provider{
+		issuer:       strings.TrimRight(issuer, "/"),
+		clientSecret: clientSecret,
+		codes:        make(map[string]*authCode),
+	}
+	if _, err := p.Rotate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Issuer mengembalikan issuer provider tanpa garis miring di akhir.
+func (p *Provider) Issuer() string {
+	return p.issuer
+}
+
+// Rotate membuat kunci penandatangan baru dan menjadikannya aktif. Kunci
+// sebelumnya tetap dipublikasikan di JWKS agar token lama masih valid.
+func (p *Provider) Rotate() (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	kid := fmt.Sprintf("key-%d", time.Now().UnixNano())
+	p.keys = append([]signingKey{{kid: kid, key: key}}, p.keys...)
+	if len(p.keys) > 2 {
+		p.keys = p.keys[:2]
+	}
+	return kid, nil
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"issuer":                                p.issuer,
+		"jwks_uri":                              p.issuer + "/jwks.json",
+		"authorization_endpoint":                p.issuer + "/authorize",
+		"token_endpoint":                        p.issuer + "/token",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"subject_types_supported":               []string{"public"},
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	keys := make([]map[string]string, 0, len(p.keys))
+	for _, k := range p.keys {
+		keys = append(keys, map[string]string{
+			"kty": "RSA",
+			"kid": k.kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(k.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes()),
+		})
+	}
+	log.Printf("[OIDC_PROVIDER] JWKS diminta (%d kunci)", len(keys))
+	writeJSON(w, map[string]interface{}{"keys": keys})
+}
+
+// devToken menerbitkan access token uji. Parameter query: sub (wajib), aud,
+// username, roles (dipisah koma), scope, ttl (durasi Go, default 5m).
+func (p *Provider) devToken(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	sub := q.Get("sub")
+	if sub == "" {
+		http.Error(w, "parameter sub wajib diisi", http.StatusBadRequest)
+		return
+	}
+	ttl := 5 * time.Minute
+	if v := q.Get("ttl"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			http.Error(w, "ttl tidak valid", http.StatusBadRequest)
+			return
+		}
+		ttl = d
+	}
+	aud := q.Get("aud")
+	if aud == "" {
+		aud = "api-gateway"
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss": p.issuer,
+		"sub": sub,
+		"aud": aud,
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(ttl).Unix(),
+	}
+	if v := q.Get("username"); v != "" {
+		claims["preferred_username"] = v
+	}
+	if v := q.Get("roles"); v != "" {
+		claims["roles"] = strings.Split(v, ",")
+	}
+	if v := q.Get("scope"); v != "" {
+		claims["scope"] = v
+	}
+
+	signed, err := p.sign(claims)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[OIDC_PROVIDER] Token diterbitkan untuk sub=%s aud=%s", sub, aud)
+	writeJSON(w, map[string]interface{}{
+		"access_token": signed,
+		"token_type":   "Bearer",
+		"expires_in":   int(ttl.Seconds()),
+	})
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<title>OIDC Provider tiruan</title>
+<h1>Login ke {{.ClientID}}</h1>
+<form method="post" action="/authorize">
+{{range $k, $v := .Params}}<input type="hidden" name="{{$k}}" value="{{index $v 0}}">
+{{end}}<p><label>Username <input name="username" autofocus required></label></p>
+<p><label>Roles (dipisah koma) <input name="roles"></label></p>
+<p><label>Metode autentikasi / amr (dipisah koma, misal pwd,mfa) <input name="amr"></label></p>
+<p><button>Login</button> <button name="deny" value="1">Tolak</button></p>
+</form>`))
+
+// authorize menampilkan form login (GET) lalu menerbitkan authorization code
+// untuk username, roles, dan amr yang diisi (POST). Tidak ada password: identitas
+// apa pun diterima. PKCE S256 wajib.
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "form tidak valid", http.StatusBadRequest)
+		return
+	}
+	params := r.Form
+	redirectURI := params.Get("redirect_uri")
+	target, err := url.Parse(redirectURI)
+	if err != nil || target.Host == "" || params.Get("client_id") == "" {
+		http.Error(w, "client_id dan redirect_uri wajib diisi", http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodGet {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		params.Del("username")
+		params.Del("roles")
+		params.Del("amr")
+		loginPage.Execute(w, map[string]interface{}{"ClientID": params.Get("client_id"), "Params": params})
+		return
+	}
+
+	// Error dikirim kembali ke relying party seperti IdP sungguhan
+	q := url.Values{"state": {params.Get("state")}}
+	switch {
+	case params.Get("deny") != "":
+		q.Set("error", "access_denied")
+	case params.Get("response_type") != "code":
+		q.Set("error", "unsupported_response_type")
+	case params.Get("code_challenge") == "" || params.Get("code_challenge_method") != "S256":
+		q.Set("error", "invalid_request")
+		q.Set("error_description", "PKCE S256 wajib")
+	case params.Get("username") == "":
+		q.Set("error", "access_denied")
+		q.Set("error_description", "username kosong")
+	default:
+		code := randomString()
+		var roles, amr []string
+		if v := params.Get("roles"); v != "" {
+			roles = strings.Split(v, ",")
+		}
+		if v := params.Get("amr"); v != "" {
+			amr = strings.Split(v, ",")
+		}
+		p.mu.Lock()
+		p.codes[code] = &authCode{
+			clientID:    params.Get("client_id"),
+			redirectURI: redirectURI,
+			challenge:   params.Get("code_challenge"),
+			nonce:       params.Get("nonce"),
+			username:    params.Get("username"),
+			roles:       roles,
+			amr:         amr,
+			expiresAt:   time.Now().Add(time.Minute),
+		}
+		p.mu.Unlock()
+		q.Set("code", code)
+		log.Printf("[OIDC_PROVIDER] Authorization code diterbitkan untuk %s (client %s)", params.Get("username"), params.Get("client_id"))
+	}
+	target.RawQuery = q.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+// exchange menukar authorization code dengan ID token dan access token
+// (grant authorization_code dengan PKCE). Code hanya bisa dipakai sekali.
+func (p *Provider) exchange(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "gunakan POST", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request", "form tidak valid")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+	clientID, secret, basic := r.BasicAuth()
+	if basic {
+		clientID, _ = url.QueryUnescape(clientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		clientID, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if p.clientSecret != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(p.clientSecret)) != 1 {
+		tokenError(w, http.StatusUnauthorized, "invalid_client", "")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	ac := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	switch {
+	case ac == nil || time.Now().After(ac.expiresAt):
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "code tidak dikenal, kedaluwarsa, atau sudah dipakai")
+		return
+	case ac.clientID != clientID || ac.redirectURI != r.PostForm.Get("redirect_uri"):
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "client_id atau redirect_uri tidak cocok")
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != ac.challenge:
+		tokenError(w, http.StatusBadRequest, "invalid_grant", "code_verifier tidak cocok")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                p.issuer,
+		"sub":                "user-" + ac.username,
+		"aud":                ac.clientID,
+		"azp":                ac.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"auth_time":          now.Unix(),
+		"preferred_username": ac.username,
+		"email":              ac.username + "@example.com",
+	}
+	if ac.nonce != "" {
+		claims["nonce"] = ac.nonce
+	}
+	if len(ac.roles) > 0 {
+		claims["roles"] = ac.roles
+	}
+	if len(ac.amr) > 0 {
+		claims["amr"] = ac.amr
+	}
+	idToken, err := p.sign(claims)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	accessToken, err := p.sign(jwt.MapClaims{"iss": p.issuer, "sub": claims["sub"], "aud": ac.clientID, "iat": now.Unix(), "exp": now.Add(5 * time.Minute).Unix()})
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	log.Printf("[OIDC_PROVIDER] Code ditukar untuk %s (client %s)", ac.username, clientID)
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, map[string]interface{}{
+		"access_token": accessToken,
+		"id_token":     idToken,
+		"token_type":   "Bearer",
+		"expires_in":   300,
+	})
+}
+
+// Handler mengembalikan semua endpoint provider, termasuk /dev/token dan
+// /dev/rotate.
+func (p *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks.json", p.jwks)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.exchange)
+	mux.HandleFunc("/dev/token", p.devToken)
+	mux.HandleFunc("/dev/rotate", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "gunakan POST", http.StatusMethodNotAllowed)
+			return
+		}
+		kid, err := p.Rotate()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("[OIDC_PROVIDER] Kunci dirotasi, kid aktif %s", kid)
+		writeJSON(w, map[string]string{"kid": kid})
+	})
+	return mux
+}
+
+// sign menandatangani claims dengan kunci aktif.
+func (p *Provider) sign(claims jwt.MapClaims) (string, error) {
+	p.mu.RLock()
+	active := p.keys[0]
+	p.mu.RUnlock()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = active.kid
+	return token.SignedString(active.key)
+}
+
+func tokenError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	body := map[string]string{"error": code}
+	if description != "" {
+		body["error_description"] = description
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+func randomString() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
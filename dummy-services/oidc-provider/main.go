@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"api-gateway-go/dummy-services/oidc-provider/idp"
+)
+
+// Identity provider tiruan untuk menguji AUTH.TRUSTED_ISSUERS dan AUTH.OIDC
+// secara lokal. Endpoint dan perilakunya ada di package idp, yang juga dipakai
+// test gateway. JANGAN dipakai di produksi: siapa pun bisa login sebagai
+// siapa pun.
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8095"
+	}
+	issuer := os.Getenv("ISSUER")
+	if issuer == "" {
+		issuer = "http://localhost:" + port
+	}
+
+	p, err := idp.New(issuer, os.Getenv("CLIENT_SECRET"))
+	if err != nil {
+		log.Fatalf("Gagal membuat kunci: %v", err)
+	}
+
+	fmt.Printf("OIDC Provider tiruan berjalan di port :%s (issuer %s)\n", port, p.Issuer())
+	log.Fatal(http.ListenAndServe(":"+port, p.Handler()))
+}
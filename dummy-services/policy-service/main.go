@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkRequest adalah bagian body ext_authz dari gateway yang dipakai policy ini.
+type checkRequest struct {
+	Route    string            `json:"route"`
+	Method   string            `json:"method"`
+	Path     string            `json:"path"`
+	Headers  map[string]string `json:"headers"`
+	Identity *struct {
+		UserID   string   `json:"user_id"`
+		Username string   `json:"username"`
+		Roles    []string `json:"roles"`
+		Machine  bool     `json:"machine"`
+	} `json:"identity"`
+}
+
+func main() {
+	// DELAY_MS memperlambat setiap keputusan untuk menguji TIMEOUT dan FAIL_OPEN
+	delay, _ := strconv.Atoi(os.Getenv("DELAY_MS"))
+
+	http.HandleFunc("/check", func(w http.ResponseWriter, r *http.Request) {
+		var req checkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "body tidak valid", http.StatusBadRequest)
+			return
+		}
+		if delay > 0 {
+			time.Sleep(time.Duration(delay) * time.Millisecond)
+		}
+		log.Printf("[POLICY_SERVICE] Cek %s %s (rute %s)", req.Method, req.Path, req.Route)
+		w.Header().Set("Content-Type", "application/json")
+
+		// Aturan contoh: request tanpa identitas hanya boleh membaca, dan
+		// path yang mengandung /admin hanya untuk role admin
+		if req.Identity == nil && req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]any{"allow": false, "reason": "Login required for write access"})
+			return
+		}
+		if strings.Contains(req.Path, "/admin") && (req.Identity == nil || !slices.Contains(req.Identity.Roles, "admin")) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"allow": false, "reason": "Admin area is restricted to the admin role"})
+			return
+		}
+		if req.Headers["X-Block"] != "" {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"allow": false, "reason": "Blocked by X-Block header"})
+			return
+		}
+
+		user := "anonymous"
+		if req.Identity != nil {
+			user = req.Identity.Username
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"allow": true,
+			"headers": map[string]string{
+				"X-Policy-Decision": "allow",
+				"X-Policy-Subject":  user,
+			},
+		})
+	})
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8096"
+	}
+	fmt.Printf("Policy Service berjalan di port :%s\n", port)
+	log.Fatal(http.ListenAndServe(":"+port, nil))
+}
@@ -5,10 +5,17 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 )
 
 func main() {
+	// Endpoint untuk health check aktif dari gateway
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
 	http.HandleFunc("/api/products/", func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/api/products/")
 		log.Printf("[PRODUCT_SERVICE] Menerima request: %s %s", r.Method, r.URL.Path)
@@ -33,7 +40,10 @@ func main() {
 			http.Error(w, "Endpoint tidak ditemukan di Product Service", http.StatusNotFound)
 		}
 	})
-	port := "8082"
+	port := os.Getenv("PORT") // Set PORT untuk menjalankan beberapa replika
+	if port == "" {
+		port = "8082"
+	}
 	fmt.Printf("Product Service berjalan di port :%s\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
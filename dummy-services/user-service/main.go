@@ -5,10 +5,17 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 )
 
 func main() {
+	// Endpoint untuk health check aktif dari gateway
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+
 	http.HandleFunc("/api/users/", func(w http.ResponseWriter, r *http.Request) {
 		path := strings.TrimPrefix(r.URL.Path, "/api/users/")
 		log.Printf("[USER_SERVICE] Menerima request: %s %s", r.Method, r.URL.Path)
@@ -17,6 +24,14 @@ func main() {
 
 		if r.Method == "GET" && path == "profile" {
 			json.NewEncoder(w).Encode(map[string]string{"userID": "user123", "name": "John Doe", "email": "john.doe@example.com"})
+		} else if r.Method == "GET" && path == "whoami" {
+			// Identitas dari header yang diisi gateway (IDENTITY_HEADERS)
+			json.NewEncoder(w).Encode(map[string]string{
+				"userID":   r.Header.Get("X-User-ID"),
+				"username": r.Header.Get("X-Username"),
+				"roles":    r.Header.Get("X-User-Roles"),
+				"identity": r.Header.Get("X-Gateway-Identity"),
+			})
 		} else if r.Method == "GET" && path == "" {
 			json.NewEncoder(w).Encode([]map[string]string{
 				{"userID": "user123", "name": "John Doe"},
@@ -29,7 +44,10 @@ func main() {
 			http.Error(w, "Endpoint tidak ditemukan di User Service", http.StatusNotFound)
 		}
 	})
-	port := "8081"
+	port := os.Getenv("PORT") // Set PORT untuk menjalankan beberapa replika
+	if port == "" {
+		port = "8081"
+	}
 	fmt.Printf("User Service berjalan di port :%s\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, nil))
 }
@@ -0,0 +1,58 @@
+// pkg/auth/apikey.go
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// apiKeyScheme mengawali setiap API key agar mudah dikenali (misalnya oleh
+// secret scanner) dan dibedakan dari JWT.
+const apiKeyScheme = "gwk_"
+
+// apiKeyPrefixLen adalah panjang bagian acak (hex) pada prefix key.
+const apiKeyPrefixLen = 12
+
+// NewAPIKey membuat API key baru dengan format gwk_<12 hex>_<secret>.
+// Mengembalikan key untuk diberikan ke consumer (hanya sekali), prefix
+// (gwk_<12 hex>) yang disimpan apa adanya untuk lookup, dan hash key. Prefix
+// bukan rahasia dan boleh ditampilkan di daftar key.
+func NewAPIKey() (key, prefix, hash string, err error) {
+	p := make([]byte, apiKeyPrefixLen/2)
+	if _, err = rand.Read(p); err != nil {
+		return "", "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	prefix = apiKeyScheme + hex.EncodeToString(p)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, HashAPIKey(key), nil
+}
+
+// ParseAPIKey mengambil prefix dari API key. ok bernilai false jika format
+// key tidak dikenal, sehingga tidak perlu lookup ke database.
+func ParseAPIKey(key string) (prefix string, ok bool) {
+	rest, found := strings.CutPrefix(key, apiKeyScheme)
+	if !found {
+		return "", false
+	}
+	random, secret, found := strings.Cut(rest, "_")
+	if !found || len(random) != apiKeyPrefixLen || secret == "" {
+		return "", false
+	}
+	return apiKeyScheme + random, true
+}
+
+// HashAPIKey mengembalikan hash API key yang disimpan di database.
+func HashAPIKey(key string) string {
+	return hashOpaqueSecret(key)
+}
+
+// APIKeyMatches membandingkan key dengan hash tersimpan dalam waktu konstan.
+func APIKeyMatches(key, hash string) bool {
+	return opaqueSecretMatches(key, hash)
+}
@@ -0,0 +1,43 @@
+// pkg/auth/client.go
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+// clientSecretScheme mengawali client secret OAuth agar mudah dikenali oleh
+// secret scanner dan dibedakan dari API key.
+const clientSecretScheme = "gcs_"
+
+// NewClientSecret membuat client secret acak 256 bit untuk grant
+// client_credentials beserta hash-nya. Secret hanya diberikan sekali; yang
+// disimpan hanya hash.
+func NewClientSecret() (secret, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret = clientSecretScheme + base64.RawURLEncoding.EncodeToString(b)
+	return secret, HashClientSecret(secret), nil
+}
+
+// HashClientSecret mengembalikan hash client secret yang disimpan di database.
+func HashClientSecret(secret string) string {
+	return hashOpaqueSecret(secret)
+}
+
+// ClientSecretMatches membandingkan secret dengan hash tersimpan dalam waktu
+// konstan.
+func ClientSecretMatches(secret, hash string) bool {
+	return opaqueSecretMatches(secret, hash)
+}
+
+// ValidScope melaporkan apakah s adalah satu scope-token OAuth2 yang sah
+// (RFC 6749 bagian 3.3): tidak kosong, tanpa spasi, '"', dan '\\'.
+func ValidScope(s string) bool {
+	return s != "" && !strings.ContainsFunc(s, func(r rune) bool {
+		return r <= 0x20 || r >= 0x7f || r == '"' || r == '\\'
+	})
+}
@@ -0,0 +1,48 @@
+// pkg/auth/csrf_test.go
+package auth
+
+import "testing"
+
+func TestCSRFMatches(t *testing.T) {
+	token, err := NewCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := HashCSRFToken(token)
+
+	tests := []struct {
+		name  string
+		token string
+		hash  string
+		want  bool
+	}{
+		{"token cocok", token, hash, true},
+		{"token lain", other, hash, false},
+		{"token kosong", "", hash, false},
+		{"hash kosong", token, "", false},
+		{"keduanya kosong", "", "", false},
+		{"hash dikirim sebagai token", hash, hash, false},
+		{"hash huruf besar", token, toUpperHex(hash), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CSRFMatches(tt.token, tt.hash); got != tt.want {
+				t.Errorf("CSRFMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func toUpperHex(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'a' && c <= 'f' {
+			b[i] = c - 'a' + 'A'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,37 @@
+// pkg/auth/identity.go
+package auth
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// IdentityTokenType adalah header typ identity assertion. Verifier menolak
+// token dengan typ ini agar assertion yang bocor dari upstream tidak bisa
+// dipakai sebagai access token.
+const IdentityTokenType = "gateway-identity+jwt"
+
+// SignIdentity membuat identity assertion untuk upstream: JWT berumur pendek
+// berisi claims pemanggil, ditandatangani kunci aktif dan ditujukan ke
+// audience (nama service upstream). Upstream memverifikasinya dengan
+// /.well-known/jwks.json.
+func (ks *KeySet) SignIdentity(claims *Claims, audience string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	assertion := &Claims{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		Roles:    claims.Roles,
+		Scope:    claims.Scope,
+		ClientID: claims.ClientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			Subject:   claims.UserID,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        claims.ID, // jti token asli untuk korelasi, kosong untuk API key
+		},
+	}
+	return ks.sign(assertion, IdentityTokenType)
+}
@@ -0,0 +1,264 @@
+// pkg/auth/jwks.go
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jwksMinRefetch membatasi refetch karena kid tidak dikenal, supaya token
+// dengan kid acak tidak bisa dipakai untuk membanjiri identity provider.
+const jwksMinRefetch = 10 * time.Second
+
+// maxJWKSBody adalah ukuran maksimum dokumen discovery atau JWKS.
+const maxJWKSBody = 1 << 20
+
+// ErrUnknownKID dikembalikan jika kid tidak ada di JWKS meskipun sudah di-refetch.
+var ErrUnknownKID = errors.New("kid tidak dikenal")
+
+// publicJWK adalah public key hasil parsing satu JWK.
+type publicJWK struct {
+	alg string // Boleh kosong jika JWK tidak mencantumkan alg
+	key crypto.PublicKey
+}
+
+// jwksCache menyimpan public key satu issuer eksternal. Key di-refresh berkala
+// di background dan di-refetch saat token memakai kid yang belum dikenal
+// (misalnya setelah IdP merotasi kunci). Jika jwksURL kosong, jwks_uri dicari
+// ulang lewat discoveryURL setiap fetch sehingga perpindahan JWKS di IdP ikut
+// terbaca.
+type jwksCache struct {
+	issuer       string
+	discoveryURL string
+	jwksURL      string
+	client       *http.Client
+
+	mu   sync.RWMutex
+	keys map[string]publicJWK
+
+	fetchMu  sync.Mutex // Hanya satu fetch berjalan pada satu waktu
+	lastMiss time.Time  // Refetch terakhir karena kid tidak dikenal
+}
+
+func newJWKSCache(issuer, discoveryURL, jwksURL string) *jwksCache {
+	return &jwksCache{
+		issuer:       issuer,
+		discoveryURL: discoveryURL,
+		jwksURL:      jwksURL,
+		client:       &http.Client{Timeout: 5 * time.Second},
+		keys:         make(map[string]publicJWK),
+	}
+}
+
+// key mengembalikan public key untuk kid. Jika kid belum dikenal, JWKS
+// di-refetch (paling sering sekali per jwksMinRefetch).
+func (c *jwksCache) key(ctx context.Context, kid string) (publicJWK, error) {
+	if k, ok := c.lookup(kid); ok {
+		return k, nil
+	}
+
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	// Request lain mungkin sudah mengambil JWKS terbaru selama menunggu lock
+	if k, ok := c.lookup(kid); ok {
+		return k, nil
+	}
+	if time.Since(c.lastMiss) < jwksMinRefetch {
+		return publicJWK{}, fmt.Errorf("%w: %q", ErrUnknownKID, kid)
+	}
+	c.lastMiss = time.Now()
+	if err := c.fetchLocked(ctx); err != nil {
+		return publicJWK{}, err
+	}
+	if k, ok := c.lookup(kid); ok {
+		return k, nil
+	}
+	return publicJWK{}, fmt.Errorf("%w: %q", ErrUnknownKID, kid)
+}
+
+// lookup mencari kid di cache. Token tanpa kid diterima jika JWKS hanya berisi
+// satu kunci.
+func (c *jwksCache) lookup(kid string) (publicJWK, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if kid == "" && len(c.keys) == 1 {
+		for _, k := range c.keys {
+			return k, true
+		}
+	}
+	k, ok := c.keys[kid]
+	return k, ok
+}
+
+// refresh mengambil JWKS terbaru.
+func (c *jwksCache) refresh(ctx context.Context) error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	return c.fetchLocked(ctx)
+}
+
+func (c *jwksCache) fetchLocked(ctx context.Context) error {
+	jwksURL := c.jwksURL
+	if jwksURL == "" {
+		var doc struct {
+			Issuer  string `json:"issuer"`
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := c.getJSON(ctx, c.discoveryURL, &doc); err != nil {
+			return fmt.Errorf("discovery %s: %w", c.discoveryURL, err)
+		}
+		if doc.Issuer != c.issuer {
+			return fmt.Errorf("discovery %s: issuer %q tidak sama dengan %q", c.discoveryURL, doc.Issuer, c.issuer)
+		}
+		if doc.JWKSURI == "" {
+			return fmt.Errorf("discovery %s: jwks_uri kosong", c.discoveryURL)
+		}
+		jwksURL = doc.JWKSURI
+	}
+
+	var set struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := c.getJSON(ctx, jwksURL, &set); err != nil {
+		return fmt.Errorf("JWKS %s: %w", jwksURL, err)
+	}
+	keys := make(map[string]publicJWK, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		pub, err := parseJWK(jwk)
+		if err != nil {
+			log.Printf("JWKS %s: kunci %q dilewati: %v", jwksURL, jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = publicJWK{alg: jwk.Alg, key: pub}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *jwksCache) getJSON(ctx context.Context, url string, v interface{}) error {
+	return fetchJSON(ctx, c.client, url, v)
+}
+
+// fetchJSON mengambil dokumen JSON (discovery atau JWKS) dari identity provider.
+func fetchJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, maxJWKSBody)).Decode(v)
+}
+
+// run me-refresh JWKS setiap interval sampai ctx selesai. Jika cache masih
+// kosong (bukan warisan generasi sebelumnya), refresh pertama langsung
+// dijalankan agar request awal tidak menunggu fetch.
+func (c *jwksCache) run(ctx context.Context, interval time.Duration) {
+	c.mu.RLock()
+	loaded := len(c.keys) > 0
+	c.mu.RUnlock()
+	if !loaded {
+		c.refreshLogged(ctx)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.refreshLogged(ctx)
+		}
+	}
+}
+
+func (c *jwksCache) refreshLogged(ctx context.Context) {
+	if err := c.refresh(ctx); err != nil && ctx.Err() == nil {
+		log.Printf("Gagal me-refresh JWKS issuer %s: %v", c.issuer, err)
+	}
+}
+
+// parseJWK mengubah JWK publik (RSA, EC, atau OKP Ed25519) menjadi public key.
+func parseJWK(jwk JWK) (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("n: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("e: %w", err)
+		}
+		eInt := new(big.Int).SetBytes(e)
+		if !eInt.IsInt64() || eInt.Int64() > 1<<31-1 || eInt.Int64() < 3 {
+			return nil, errors.New("eksponen RSA tidak valid")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(eInt.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("kurva %q tidak didukung", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		// ECDH memvalidasi bahwa titik berada di kurva
+		if _, err := pub.ECDH(); err != nil {
+			return nil, errors.New("titik EC tidak berada di kurva")
+		}
+		return pub, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("kurva %q tidak didukung", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("x Ed25519 tidak valid")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("kty %q tidak didukung", jwk.Kty)
+	}
+}
@@ -0,0 +1,247 @@
+// pkg/auth/jwks_test.go
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"api-gateway-go/dummy-services/oidc-provider/idp"
+	"api-gateway-go/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testIdP menjalankan identity provider tiruan dari dummy-services di
+// httptest dan menghitung berapa kali JWKS-nya diambil.
+type testIdP struct {
+	*idp.Provider
+	server    *httptest.Server
+	jwksCalls atomic.Int32
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	// Issuer harus sama dengan URL server, jadi listener dibuat lebih dulu
+	server := httptest.NewUnstartedServer(nil)
+	provider, err := idp.New("http://"+server.Listener.Addr().String(), "")
+	if err != nil {
+		t.Fatalf("idp.New: %v", err)
+	}
+	ti := &testIdP{Provider: provider, server: server}
+	handler := provider.Handler()
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/jwks.json" {
+			ti.jwksCalls.Add(1)
+		}
+		handler.ServeHTTP(w, r)
+	})
+	server.Start()
+	t.Cleanup(server.Close)
+	return ti
+}
+
+// token menerbitkan access token lewat /dev/token dengan parameter query q.
+func (ti *testIdP) token(t *testing.T, q url.Values) string {
+	t.Helper()
+	resp, err := http.Get(ti.server.URL + "/dev/token?" + q.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	if resp.StatusCode != http.StatusOK || json.NewDecoder(resp.Body).Decode(&body) != nil {
+		t.Fatalf("/dev/token status %d", resp.StatusCode)
+	}
+	return body.AccessToken
+}
+
+func (ti *testIdP) rotate(t *testing.T) {
+	t.Helper()
+	if _, err := ti.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newExternalVerifier membuat Verifier yang mempercayai ti lewat discovery.
+func newExternalVerifier(t *testing.T, ti *testIdP) *Verifier {
+	t.Helper()
+	var cfg config.Config
+	cfg.AuthSecret = "test-secret-yang-cukup-panjang-untuk-hs256"
+	cfg.Auth.TrustedIssuers = []config.TrustedIssuerConfig{{
+		Issuer:       ti.Issuer(),
+		DiscoveryURL: ti.Issuer() + "/.well-known/openid-configuration",
+		Audiences:    []string{"api-gateway", "reporting"},
+	}}
+	v, err := NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	return v
+}
+
+func TestVerifierExternalIssuer(t *testing.T) {
+	ti := newTestIdP(t)
+	v := newExternalVerifier(t, ti)
+
+	// Token HS256 dengan kid aktif: verifier tidak boleh memakai kunci RSA
+	// sebagai secret HMAC
+	valid := ti.token(t, url.Values{"sub": {"u-1"}})
+	parsed, _, err := jwt.NewParser().ParseUnverified(valid, jwt.MapClaims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": ti.Issuer(), "sub": "u-1", "aud": "api-gateway", "exp": time.Now().Add(time.Minute).Unix(),
+	})
+	hmacToken.Header["kid"] = parsed.Header["kid"]
+	forged, _ := hmacToken.SignedString([]byte("secret-apa-saja"))
+
+	tests := []struct {
+		name     string
+		token    string
+		wantErr  error // nil = harus diterima
+		wantUser string
+	}{
+		{"token valid", ti.token(t, url.Values{"sub": {"u-1"}, "username": {"alice"}, "roles": {"employee,admin"}, "scope": {"read write"}}), nil, "alice"},
+		{"audience lain yang dipercaya", ti.token(t, url.Values{"sub": {"u-2"}, "aud": {"reporting"}}), nil, "u-2"},
+		{"audience tidak dipercaya", ti.token(t, url.Values{"sub": {"u-1"}, "aud": {"aplikasi-lain"}}), jwt.ErrTokenInvalidAudience, ""},
+		{"kedaluwarsa dalam toleransi skew", ti.token(t, url.Values{"sub": {"u-1"}, "ttl": {"-10s"}}), nil, "u-1"},
+		{"kedaluwarsa", ti.token(t, url.Values{"sub": {"u-1"}, "ttl": {"-2m"}}), jwt.ErrTokenExpired, ""},
+		{"signature diubah", valid[:len(valid)-4] + "AAAA", jwt.ErrTokenSignatureInvalid, ""},
+		{"alg HS256 ditolak", forged, jwt.ErrTokenSignatureInvalid, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tt.token)
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Verify() error = %v", err)
+				}
+				if claims.Username != tt.wantUser || claims.Issuer != ti.Issuer() || claims.Machine() {
+					t.Errorf("claims = %+v, want username %s dari %s", claims, tt.wantUser, ti.Issuer())
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Verify() diterima, want %v", tt.wantErr)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	claims, err := v.Verify(context.Background(), tests[0].token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != "u-1" || !claims.HasRole("admin") || !claims.HasScope("write") {
+		t.Errorf("klaim eksternal tidak dipetakan: %+v", claims)
+	}
+}
+
+func TestVerifierJWKSKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	ti := newTestIdP(t)
+	v := newExternalVerifier(t, ti)
+	cache := v.issuers[ti.Issuer()].jwks
+
+	verify := func(name, token string, wantErr error, wantCalls int32) {
+		t.Helper()
+		_, err := v.Verify(ctx, token)
+		if wantErr == nil && err != nil {
+			t.Errorf("%s: Verify() error = %v", name, err)
+		}
+		if wantErr != nil && !errors.Is(err, wantErr) {
+			t.Errorf("%s: Verify() error = %v, want %v", name, err, wantErr)
+		}
+		if got := ti.jwksCalls.Load(); got != wantCalls {
+			t.Errorf("%s: JWKS diambil %d kali, want %d", name, got, wantCalls)
+		}
+	}
+
+	// Fetch awal seperti yang dijalankan Verifier.Start
+	if err := cache.refresh(ctx); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	first := ti.token(t, url.Values{"sub": {"u-1"}})
+	verify("kid dari cache", first, nil, 1)
+
+	// IdP merotasi kunci: kid baru memicu refetch, kid lama masih dipublikasikan
+	ti.rotate(t)
+	second := ti.token(t, url.Values{"sub": {"u-1"}})
+	verify("kid baru di-refetch", second, nil, 2)
+	verify("kid lama masih valid", first, nil, 2)
+
+	// Kid acak tidak boleh memicu refetch berulang dalam jwksMinRefetch
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bogus := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss": ti.Issuer(), "sub": "u-1", "aud": "api-gateway", "exp": time.Now().Add(time.Minute).Unix(),
+	})
+	bogus.Header["kid"] = "kid-palsu"
+	bogusToken, err := bogus.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify("kid tidak dikenal dibatasi", bogusToken, ErrUnknownKID, 2)
+	verify("kid tidak dikenal tetap dibatasi", bogusToken, ErrUnknownKID, 2)
+
+	// Setelah dua rotasi kunci pertama tidak lagi dipublikasikan; refresh
+	// background membuang kunci itu dari cache
+	ti.rotate(t)
+	if err := cache.refresh(ctx); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	verify("kunci yang sudah dibuang", first, ErrUnknownKID, 3)
+	third := ti.token(t, url.Values{"sub": {"u-1"}})
+	verify("kunci aktif dari refresh", third, nil, 3)
+}
+
+func TestJWKSCacheDiscoveryIssuerMismatch(t *testing.T) {
+	ti := newTestIdP(t)
+	cache := newJWKSCache("https://idp.lain.example", ti.Issuer()+"/.well-known/openid-configuration", "")
+	if err := cache.refresh(context.Background()); err == nil {
+		t.Fatal("discovery dengan issuer berbeda diterima")
+	}
+	if got := ti.jwksCalls.Load(); got != 0 {
+		t.Errorf("JWKS diambil %d kali meskipun discovery ditolak", got)
+	}
+}
+
+func TestJWKSCacheDiscoveryRefresh(t *testing.T) {
+	ti := newTestIdP(t)
+	moved := newTestIdP(t)
+	var jwksURI atomic.Value
+	jwksURI.Store(ti.Issuer() + "/jwks.json")
+	discovery := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": ti.Issuer(), "jwks_uri": jwksURI.Load().(string)})
+	}))
+	t.Cleanup(discovery.Close)
+
+	cache := newJWKSCache(ti.Issuer(), discovery.URL, "")
+	if err := cache.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	// IdP memindahkan JWKS; refresh berikutnya harus membaca jwks_uri baru
+	jwksURI.Store(moved.Issuer() + "/jwks.json")
+	if err := cache.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if old, updated := ti.jwksCalls.Load(), moved.jwksCalls.Load(); old != 1 || updated != 1 {
+		t.Errorf("JWKS lama diambil %d kali dan JWKS baru %d kali, want 1 dan 1", old, updated)
+	}
+}
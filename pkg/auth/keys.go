@@ -0,0 +1,238 @@
+// pkg/auth/keys.go
+package auth
+
+import (
+	"api-gateway-go/pkg/config"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey adalah satu kunci di KeySet. private nil untuk kunci yang hanya
+// dipakai verifikasi.
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// KeySet berisi kunci untuk menandatangani dan memverifikasi access token.
+// Dengan AUTH.SIGNING.KEYS kosong, KeySet memakai HS256 dengan AUTH_SECRET.
+type KeySet struct {
+	active       *signingKey
+	keys         map[string]*signingKey
+	order        []string // Urutan kid sesuai konfigurasi, untuk JWKS
+	hmacSecret   []byte
+	acceptHMAC   bool
+	validMethods []string
+}
+
+// LoadKeySet membaca kunci dari file PEM sesuai AUTH.SIGNING.
+func LoadKeySet(cfg config.Config) (*KeySet, error) {
+	sc := cfg.Auth.Signing
+	ks := &KeySet{keys: make(map[string]*signingKey), hmacSecret: []byte(cfg.AuthSecret)}
+	if len(sc.Keys) == 0 {
+		ks.acceptHMAC = true
+		ks.validMethods = []string{jwt.SigningMethodHS256.Alg()}
+		return ks, nil
+	}
+
+	methods := make(map[string]bool)
+	for _, kc := range sc.Keys {
+		key, err := loadSigningKey(kc)
+		if err != nil {
+			return nil, fmt.Errorf("kunci %q: %w", kc.KID, err)
+		}
+		ks.keys[kc.KID] = key
+		ks.order = append(ks.order, kc.KID)
+		methods[key.method.Alg()] = true
+	}
+	ks.active = ks.keys[sc.ActiveKID]
+	if ks.active == nil || ks.active.private == nil {
+		return nil, fmt.Errorf("kunci aktif %q tidak ditemukan atau tidak memiliki private key", sc.ActiveKID)
+	}
+	if sc.AcceptHS256 {
+		ks.acceptHMAC = true
+		methods[jwt.SigningMethodHS256.Alg()] = true
+	}
+	for alg := range methods {
+		ks.validMethods = append(ks.validMethods, alg)
+	}
+	return ks, nil
+}
+
+func loadSigningKey(kc config.SigningKeyConfig) (*signingKey, error) {
+	key := &signingKey{kid: kc.KID}
+	switch kc.Algorithm {
+	case config.AlgRS256:
+		key.method = jwt.SigningMethodRS256
+	case config.AlgES256:
+		key.method = jwt.SigningMethodES256
+	case config.AlgEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("algoritma %q tidak didukung", kc.Algorithm)
+	}
+
+	if kc.PrivateKeyFile != "" {
+		pem, err := os.ReadFile(kc.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		switch kc.Algorithm {
+		case config.AlgRS256:
+			k, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.private, key.public = k, &k.PublicKey
+		case config.AlgES256:
+			k, err := jwt.ParseECPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.private, key.public = k, &k.PublicKey
+		case config.AlgEdDSA:
+			k, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			edKey, ok := k.(ed25519.PrivateKey)
+			if !ok {
+				return nil, errors.New("bukan private key Ed25519")
+			}
+			key.private, key.public = edKey, edKey.Public()
+		}
+	} else {
+		pem, err := os.ReadFile(kc.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		switch kc.Algorithm {
+		case config.AlgRS256:
+			key.public, err = jwt.ParseRSAPublicKeyFromPEM(pem)
+		case config.AlgES256:
+			key.public, err = jwt.ParseECPublicKeyFromPEM(pem)
+		case config.AlgEdDSA:
+			key.public, err = jwt.ParseEdPublicKeyFromPEM(pem)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch pub := key.public.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("kunci RSA minimal 2048 bit, didapat %d", pub.N.BitLen())
+		}
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return nil, errors.New("ES256 membutuhkan kurva P-256")
+		}
+	}
+	return key, nil
+}
+
+// Sign menandatangani claims dengan kunci aktif dan menambahkan header kid.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	return ks.sign(claims, "")
+}
+
+// sign menandatangani claims; typ, jika diisi, menggantikan header typ "JWT".
+func (ks *KeySet) sign(claims jwt.Claims, typ string) (string, error) {
+	method := jwt.SigningMethod(jwt.SigningMethodHS256)
+	var key interface{} = ks.hmacSecret
+	if ks.active != nil {
+		method, key = ks.active.method, ks.active.private
+	}
+	token := jwt.NewWithClaims(method, claims)
+	if ks.active != nil {
+		token.Header["kid"] = ks.active.kid
+	}
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+	return token.SignedString(key)
+}
+
+// Keyfunc memilih kunci verifikasi berdasarkan header kid dan memastikan alg
+// token sesuai dengan algoritma kunci tersebut.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if !ks.acceptHMAC {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return ks.hmacSecret, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v for kid %q", token.Header["alg"], kid)
+	}
+	return key.public, nil
+}
+
+// ValidMethods mengembalikan nilai alg yang diterima, untuk jwt.WithValidMethods.
+func (ks *KeySet) ValidMethods() []string {
+	return ks.validMethods
+}
+
+// JWK adalah public key dalam format JSON Web Key (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS mengembalikan semua public key sebagai JWK Set. Secret HS256 tidak
+// pernah ikut dipublikasikan.
+func (ks *KeySet) JWKS() []JWK {
+	jwks := make([]JWK, 0, len(ks.order))
+	for _, kid := range ks.order {
+		key := ks.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = b64(pub.N.Bytes())
+			jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			jwk.Kty = "EC"
+			jwk.Crv = "P-256"
+			jwk.X = b64(pub.X.FillBytes(make([]byte, 32)))
+			jwk.Y = b64(pub.Y.FillBytes(make([]byte, 32)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = b64(pub)
+		default:
+			continue
+		}
+		jwks = append(jwks, jwk)
+	}
+	return jwks
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
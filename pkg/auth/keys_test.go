@@ -0,0 +1,179 @@
+// pkg/auth/keys_test.go
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"api-gateway-go/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testAuthSecret = "test-secret-yang-cukup-panjang-untuk-hs256"
+
+// writePEM menyimpan key dalam format PEM ke direktori sementara test.
+func writePEM(t *testing.T, name, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testKeys membuat kunci aktif ES256 "es-1" dan kunci verifikasi EdDSA "ed-old"
+// beserta private key masing-masing untuk menandatangani token test.
+func testKeys(t *testing.T) (config.Config, *ecdsa.PrivateKey, ed25519.PrivateKey) {
+	t.Helper()
+	esKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	esDER, err := x509.MarshalPKCS8PrivateKey(esKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edDER, err := x509.MarshalPKIXPublicKey(edPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg config.Config
+	cfg.AuthSecret = testAuthSecret
+	cfg.Auth.Signing = config.SigningConfig{
+		ActiveKID: "es-1",
+		Keys: []config.SigningKeyConfig{
+			{KID: "es-1", Algorithm: config.AlgES256, PrivateKeyFile: writePEM(t, "es.pem", "PRIVATE KEY", esDER)},
+			{KID: "ed-old", Algorithm: config.AlgEdDSA, PublicKeyFile: writePEM(t, "ed.pub", "PUBLIC KEY", edDER)},
+		},
+	}
+	return cfg, esKey, edKey
+}
+
+// signWith menandatangani claims test dengan method, kid, dan key tertentu.
+func signWith(t *testing.T, method jwt.SigningMethod, kid string, key interface{}) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.RegisteredClaims{Subject: "alice", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))})
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestKeySetKeyfunc(t *testing.T) {
+	cfg, esKey, edKey := testKeys(t)
+
+	tests := []struct {
+		name        string
+		acceptHS256 bool
+		method      jwt.SigningMethod
+		kid         string
+		wantKey     interface{} // nil berarti token harus ditolak
+	}{
+		{"kunci aktif", false, jwt.SigningMethodES256, "es-1", &esKey.PublicKey},
+		{"kunci verifikasi saja", false, jwt.SigningMethodEdDSA, "ed-old", edKey.Public()},
+		{"kid tidak dikenal", false, jwt.SigningMethodES256, "es-2", nil},
+		{"tanpa kid", false, jwt.SigningMethodES256, "", nil},
+		{"alg tidak cocok dengan kid", false, jwt.SigningMethodEdDSA, "es-1", nil},
+		{"RS256 memakai kid ES256", false, jwt.SigningMethodRS256, "es-1", nil},
+		{"HS256 ditolak tanpa ACCEPT_HS256", false, jwt.SigningMethodHS256, "es-1", nil},
+		{"HS256 diterima dengan ACCEPT_HS256", true, jwt.SigningMethodHS256, "", []byte(testAuthSecret)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := cfg
+			cfg.Auth.Signing.AcceptHS256 = tt.acceptHS256
+			ks, err := LoadKeySet(cfg)
+			if err != nil {
+				t.Fatalf("LoadKeySet: %v", err)
+			}
+			token := &jwt.Token{Method: tt.method, Header: map[string]interface{}{"alg": tt.method.Alg()}}
+			if tt.kid != "" {
+				token.Header["kid"] = tt.kid
+			}
+			key, err := ks.Keyfunc(token)
+			if tt.wantKey == nil {
+				if err == nil {
+					t.Errorf("Keyfunc() = %T, want error", key)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Keyfunc() error = %v", err)
+			}
+			if !reflect.DeepEqual(key, tt.wantKey) {
+				t.Errorf("Keyfunc() = %T, want %T", key, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestKeySetParse(t *testing.T) {
+	cfg, esKey, _ := testKeys(t)
+	otherES, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := LoadKeySet(cfg)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		wantValid bool
+	}{
+		{"ditandatangani kunci aktif", signWith(t, jwt.SigningMethodES256, "es-1", esKey), true},
+		{"kid benar tapi kunci lain", signWith(t, jwt.SigningMethodES256, "es-1", otherES), false},
+		{"HS256 dengan AUTH_SECRET", signWith(t, jwt.SigningMethodHS256, "", []byte(testAuthSecret)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := jwt.Parse(tt.token, ks.Keyfunc, jwt.WithValidMethods(ks.ValidMethods()))
+			if (err == nil) != tt.wantValid {
+				t.Errorf("Parse() error = %v, want valid %v", err, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestKeySetSign(t *testing.T) {
+	cfg, _, _ := testKeys(t)
+	ks, err := LoadKeySet(cfg)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	signed, err := ks.Sign(jwt.RegisteredClaims{Subject: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Parse(signed, ks.Keyfunc, jwt.WithValidMethods(ks.ValidMethods()))
+	if err != nil {
+		t.Fatalf("token sendiri ditolak: %v", err)
+	}
+	if kid := token.Header["kid"]; kid != "es-1" || token.Method.Alg() != config.AlgES256 {
+		t.Errorf("header kid %v alg %s, want es-1 ES256", kid, token.Method.Alg())
+	}
+	if jwks := ks.JWKS(); len(jwks) != 2 {
+		t.Errorf("JWKS() berisi %d kunci, want 2 tanpa secret HS256", len(jwks))
+	}
+}
@@ -0,0 +1,240 @@
+// pkg/auth/loginguard.go
+package auth
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"api-gateway-go/pkg/config"
+)
+
+// Jenis subjek yang dihitung LoginGuard.
+const (
+	LoginSubjectUser = "user"
+	LoginSubjectIP   = "ip"
+)
+
+// loginState adalah hitungan kegagalan satu username atau IP.
+type loginState struct {
+	failures    int
+	lastAttempt time.Time
+	lockedUntil time.Time
+}
+
+// Lockout adalah username atau IP yang sedang dikunci.
+type Lockout struct {
+	Kind        string    `json:"kind"`
+	Subject     string    `json:"subject"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// LoginGuard menghitung kegagalan login per username dan per IP di memori.
+// Username dihitung apa adanya (setelah dinormalisasi) tanpa melihat apakah
+// terdaftar, sehingga perilaku dan waktu respons tidak membocorkan keberadaan
+// akun.
+//
+// Setiap percobaan langsung dihitung sebagai kegagalan di Attempt dan baru
+// dibatalkan oleh Succeeded, sehingga request paralel tidak bisa melewati
+// backoff dengan memulai banyak percobaan sekaligus.
+type LoginGuard struct {
+	cfg config.LoginProtectionConfig
+
+	mu    sync.Mutex
+	users map[string]*loginState
+	ips   map[string]*loginState
+}
+
+// NewLoginGuard membuat LoginGuard dengan cfg yang sudah lolos validasi.
+func NewLoginGuard(cfg config.LoginProtectionConfig) *LoginGuard {
+	return &LoginGuard{
+		cfg:   cfg,
+		users: make(map[string]*loginState),
+		ips:   make(map[string]*loginState),
+	}
+}
+
+// normalizeLoginUsername menyamakan username seperti yang disimpan database.
+func normalizeLoginUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Attempt mencatat percobaan login. Jika username atau IP sedang dikunci atau
+// masih dalam masa backoff, percobaan tidak dicatat dan Attempt mengembalikan
+// lama waktu tunggu (> 0).
+func (g *LoginGuard) Attempt(username, ip string) time.Duration {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	user := g.state(g.users, normalizeLoginUsername(username), now)
+	addr := g.state(g.ips, ip, now)
+	wait := g.wait(user, g.cfg.FreeAttempts, now)
+	if w := g.wait(addr, g.cfg.IPFreeAttempts, now); w > wait {
+		wait = w
+	}
+	if wait > 0 {
+		return wait
+	}
+	for _, s := range []*loginState{user, addr} {
+		s.failures++
+		s.lastAttempt = now
+	}
+	return 0
+}
+
+// Failed mengonfirmasi bahwa percobaan yang dicatat Attempt gagal. Subjek yang
+// mencapai batas kegagalannya dikunci; jenis subjek yang baru dikunci
+// dikembalikan agar pemanggil bisa mencatat audit.
+func (g *LoginGuard) Failed(username, ip string) []string {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var locked []string
+	if lock(g.users[normalizeLoginUsername(username)], g.cfg.UserMaxFailures, g.cfg.LockoutDuration, now) {
+		locked = append(locked, LoginSubjectUser)
+	}
+	if lock(g.ips[ip], g.cfg.IPMaxFailures, g.cfg.LockoutDuration, now) {
+		locked = append(locked, LoginSubjectIP)
+	}
+	return locked
+}
+
+// Succeeded membatalkan hitungan percobaan yang berhasil: semua kegagalan
+// username dilupakan, sedangkan IP hanya dikurangi satu agar login yang
+// berhasil dengan akun milik penyerang tidak mereset hitungan IP.
+func (g *LoginGuard) Succeeded(username, ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.users, normalizeLoginUsername(username))
+	if s, ok := g.ips[ip]; ok && s.failures > 0 {
+		s.failures--
+	}
+}
+
+// Release membatalkan hitungan satu percobaan yang dicatat Attempt tanpa
+// mereset kegagalan sebelumnya, misalnya saat password benar tetapi login
+// masih menunggu faktor kedua.
+func (g *LoginGuard) Release(username, ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, s := range []*loginState{g.users[normalizeLoginUsername(username)], g.ips[ip]} {
+		if s != nil && s.failures > 0 {
+			s.failures--
+		}
+	}
+}
+
+// Unlock menghapus penguncian dan hitungan kegagalan satu username atau IP.
+// Mengembalikan false jika tidak ada yang perlu dihapus.
+func (g *LoginGuard) Unlock(kind, subject string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	m := g.users
+	if kind == LoginSubjectIP {
+		m = g.ips
+	} else {
+		subject = normalizeLoginUsername(subject)
+	}
+	_, ok := m[subject]
+	delete(m, subject)
+	return ok
+}
+
+// Lockouts mengembalikan semua username dan IP yang sedang dikunci.
+func (g *LoginGuard) Lockouts() []Lockout {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	list := []Lockout{}
+	for kind, m := range map[string]map[string]*loginState{LoginSubjectUser: g.users, LoginSubjectIP: g.ips} {
+		for subject, s := range m {
+			if now.Before(s.lockedUntil) {
+				list = append(list, Lockout{Kind: kind, Subject: subject, LockedUntil: s.lockedUntil})
+			}
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].LockedUntil.Before(list[j].LockedUntil) })
+	return list
+}
+
+// Purge membuang hitungan yang sudah melewati FAILURE_TTL dan tidak dikunci.
+func (g *LoginGuard) Purge() int {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	removed := 0
+	for _, m := range []map[string]*loginState{g.users, g.ips} {
+		for key, s := range m {
+			if g.stale(s, now) {
+				delete(m, key)
+				removed++
+			}
+		}
+	}
+	return removed
+}
+
+// RunJanitor menjalankan Purge secara berkala sampai ctx selesai.
+func (g *LoginGuard) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			g.Purge()
+		}
+	}
+}
+
+// state mengembalikan hitungan key, membuat yang baru jika belum ada atau
+// sudah kedaluwarsa.
+func (g *LoginGuard) state(m map[string]*loginState, key string, now time.Time) *loginState {
+	s, ok := m[key]
+	if !ok || g.stale(s, now) {
+		s = &loginState{}
+		m[key] = s
+	}
+	return s
+}
+
+func (g *LoginGuard) stale(s *loginState, now time.Time) bool {
+	return !now.Before(s.lockedUntil) && now.Sub(s.lastAttempt) >= g.cfg.FailureTTL
+}
+
+// wait menghitung sisa waktu penguncian atau backoff untuk s. Backoff mulai
+// berlaku setelah free kegagalan.
+func (g *LoginGuard) wait(s *loginState, free int, now time.Time) time.Duration {
+	if now.Before(s.lockedUntil) {
+		return s.lockedUntil.Sub(now)
+	}
+	over := s.failures - free + 1
+	if s.failures == 0 || over <= 0 {
+		return 0
+	}
+	backoff := g.cfg.BackoffBase
+	for i := 1; i < over && backoff < g.cfg.BackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > g.cfg.BackoffMax {
+		backoff = g.cfg.BackoffMax
+	}
+	return s.lastAttempt.Add(backoff).Sub(now)
+}
+
+// lock mengunci s jika kegagalannya mencapai max. Hitungan direset agar
+// setelah penguncian selesai subjek mendapat jatah percobaan baru.
+func lock(s *loginState, max int, d time.Duration, now time.Time) bool {
+	if s == nil || s.failures < max {
+		return false
+	}
+	s.failures = 0
+	s.lockedUntil = now.Add(d)
+	s.lastAttempt = now
+	return true
+}
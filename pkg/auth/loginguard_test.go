@@ -0,0 +1,206 @@
+// pkg/auth/loginguard_test.go
+package auth
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"api-gateway-go/pkg/config"
+)
+
+// testLoginProtection mengembalikan konfigurasi dengan jeda panjang agar
+// hasil test tidak bergantung pada kecepatan mesin.
+func testLoginProtection() config.LoginProtectionConfig {
+	return config.LoginProtectionConfig{
+		Enabled:         true,
+		FreeAttempts:    3,
+		IPFreeAttempts:  100,
+		BackoffBase:     time.Minute,
+		BackoffMax:      5 * time.Minute,
+		UserMaxFailures: 10,
+		IPMaxFailures:   100,
+		LockoutDuration: time.Hour,
+		FailureTTL:      time.Hour,
+	}
+}
+
+// fail menjalankan satu percobaan login yang gagal dan mengembalikan subjek
+// yang baru dikunci.
+func fail(t *testing.T, g *LoginGuard, username, ip string) []string {
+	t.Helper()
+	if wait := g.Attempt(username, ip); wait > 0 {
+		t.Fatalf("Attempt(%s, %s) ditahan %v, want diizinkan", username, ip, wait)
+	}
+	return g.Failed(username, ip)
+}
+
+func TestLoginGuardBackoff(t *testing.T) {
+	g := NewLoginGuard(testLoginProtection())
+	now := time.Now()
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, time.Minute},
+		{4, 2 * time.Minute},
+		{5, 4 * time.Minute},
+		{6, 5 * time.Minute}, // Dibatasi BACKOFF_MAX
+		{20, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		s := &loginState{failures: tt.failures, lastAttempt: now}
+		if got := g.wait(s, g.cfg.FreeAttempts, now); got != tt.want {
+			t.Errorf("wait() setelah %d kegagalan = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginGuardAttemptBackoff(t *testing.T) {
+	g := NewLoginGuard(testLoginProtection())
+	for i := 0; i < 3; i++ {
+		fail(t, g, "alice", "10.0.0.1")
+	}
+	wait := g.Attempt("Alice ", "10.0.0.2")
+	if wait <= 0 || wait > time.Minute {
+		t.Fatalf("Attempt setelah FREE_ATTEMPTS = %v, want backoff sampai 1m", wait)
+	}
+	// Percobaan yang ditahan tidak menambah hitungan
+	if got := g.users["alice"].failures; got != 3 {
+		t.Errorf("failures = %d setelah percobaan ditahan, want 3", got)
+	}
+	if wait := g.Attempt("bob", "10.0.0.1"); wait != 0 {
+		t.Errorf("username lain dari IP yang sama ditahan %v", wait)
+	}
+}
+
+func TestLoginGuardLockout(t *testing.T) {
+	cfg := testLoginProtection()
+	cfg.FreeAttempts = 100
+	cfg.UserMaxFailures = 3
+	cfg.IPMaxFailures = 5
+	g := NewLoginGuard(cfg)
+
+	for i := 1; i <= 2; i++ {
+		if locked := fail(t, g, "alice", "10.0.0.1"); len(locked) != 0 {
+			t.Fatalf("kegagalan ke-%d mengunci %v", i, locked)
+		}
+	}
+	if locked := fail(t, g, "alice", "10.0.0.1"); !slices.Equal(locked, []string{LoginSubjectUser}) {
+		t.Fatalf("kegagalan ke-3 mengunci %v, want [user]", locked)
+	}
+	if wait := g.Attempt("alice", "10.0.0.9"); wait <= 59*time.Minute {
+		t.Errorf("Attempt saat dikunci = %v, want sekitar LOCKOUT_DURATION", wait)
+	}
+
+	// IP mencapai batasnya lewat username lain
+	fail(t, g, "bob", "10.0.0.1")
+	if locked := fail(t, g, "carol", "10.0.0.1"); !slices.Equal(locked, []string{LoginSubjectIP}) {
+		t.Fatalf("kegagalan ke-5 dari IP mengunci %v, want [ip]", locked)
+	}
+	if wait := g.Attempt("dave", "10.0.0.1"); wait <= 0 {
+		t.Error("IP yang dikunci masih bisa mencoba username lain")
+	}
+
+	lockouts := g.Lockouts()
+	if len(lockouts) != 2 {
+		t.Fatalf("Lockouts() = %+v, want user dan ip", lockouts)
+	}
+	if !g.Unlock(LoginSubjectUser, "ALICE") || g.Unlock(LoginSubjectUser, "alice") {
+		t.Error("Unlock harus berhasil sekali untuk username yang dikunci")
+	}
+	if wait := g.Attempt("alice", "10.0.0.9"); wait != 0 {
+		t.Errorf("Attempt setelah Unlock = %v, want 0", wait)
+	}
+}
+
+func TestLoginGuardSucceeded(t *testing.T) {
+	g := NewLoginGuard(testLoginProtection())
+	fail(t, g, "alice", "10.0.0.1")
+	fail(t, g, "alice", "10.0.0.1")
+	fail(t, g, "mallory", "10.0.0.1")
+
+	if wait := g.Attempt("alice", "10.0.0.1"); wait != 0 {
+		t.Fatalf("Attempt = %v, want 0", wait)
+	}
+	g.Succeeded("alice", "10.0.0.1")
+	if _, ok := g.users["alice"]; ok {
+		t.Error("hitungan username tidak dihapus setelah login berhasil")
+	}
+	// Tiga kegagalan ditambah satu percobaan berhasil: IP hanya dikurangi satu
+	if got := g.ips["10.0.0.1"].failures; got != 3 {
+		t.Errorf("failures IP = %d, want 3", got)
+	}
+}
+
+func TestLoginGuardRelease(t *testing.T) {
+	g := NewLoginGuard(testLoginProtection())
+	fail(t, g, "alice", "10.0.0.1")
+	fail(t, g, "alice", "10.0.0.1")
+
+	// Password benar tetapi menunggu faktor kedua: kegagalan sebelumnya tetap
+	if wait := g.Attempt("alice", "10.0.0.1"); wait != 0 {
+		t.Fatalf("Attempt = %v, want 0", wait)
+	}
+	g.Release("alice", "10.0.0.1")
+	if user, ip := g.users["alice"].failures, g.ips["10.0.0.1"].failures; user != 2 || ip != 2 {
+		t.Errorf("failures user %d ip %d setelah Release, want 2 2", user, ip)
+	}
+	g.Release("tidak-dikenal", "10.0.0.9")
+	if _, ok := g.users["tidak-dikenal"]; ok {
+		t.Error("Release membuat hitungan untuk username yang belum pernah mencoba")
+	}
+}
+
+func TestLoginGuardPurge(t *testing.T) {
+	cfg := testLoginProtection()
+	cfg.FreeAttempts = 100
+	cfg.UserMaxFailures = 1
+	g := NewLoginGuard(cfg)
+	fail(t, g, "alice", "10.0.0.1")
+	fail(t, g, "bob", "10.0.0.2")
+
+	// Hitungan alice lama tetapi masih dikunci; bob dan kedua IP sudah basi
+	old := time.Now().Add(-2 * cfg.FailureTTL)
+	g.users["alice"].lastAttempt = old
+	g.users["bob"].lastAttempt = old
+	g.users["bob"].lockedUntil = time.Time{}
+	g.ips["10.0.0.1"].lastAttempt = old
+	g.ips["10.0.0.2"].lastAttempt = old
+
+	if removed := g.Purge(); removed != 3 {
+		t.Errorf("Purge() = %d, want 3", removed)
+	}
+	if _, ok := g.users["alice"]; !ok {
+		t.Error("username yang masih dikunci ikut dibuang")
+	}
+
+	// Hitungan basi yang belum dibuang Purge tetap dianggap baru
+	g.ips["10.0.0.3"] = &loginState{failures: 99, lastAttempt: old}
+	if wait := g.Attempt("carol", "10.0.0.3"); wait != 0 {
+		t.Errorf("Attempt dengan hitungan basi = %v, want 0", wait)
+	}
+}
+
+func TestLoginGuardParallelAttempts(t *testing.T) {
+	g := NewLoginGuard(testLoginProtection())
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if g.Attempt("alice", "10.0.0.1") == 0 {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := allowed.Load(); got != 3 {
+		t.Errorf("%d percobaan paralel diizinkan, want FREE_ATTEMPTS (3)", got)
+	}
+}
@@ -0,0 +1,79 @@
+// pkg/auth/mfa.go
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// MFATokenType adalah header typ MFA challenge token. Verifier menolaknya
+// sebagai access token.
+const MFATokenType = "gateway-mfa+jwt"
+
+// Tujuan MFA challenge token.
+const (
+	MFAPurposeVerify = "verify" // Tukar dengan kode TOTP di /auth/mfa/verify
+	MFAPurposeEnroll = "enroll" // MFA wajib tetapi belum didaftarkan, hanya untuk /auth/mfa/enroll dan /confirm
+)
+
+// MFAChallengeContextKey adalah kunci gin.Context tempat middleware menyimpan
+// *MFAChallenge jika request diautentikasi dengan challenge token enrollment.
+const MFAChallengeContextKey = "mfaChallenge"
+
+// MFAChallenge adalah isi MFA challenge token yang diterbitkan /auth/login
+// setelah password benar. Subject berisi user ID.
+type MFAChallenge struct {
+	Purpose string `json:"purpose"`
+	Session bool   `json:"session,omitempty"` // Login meminta mode sesi cookie
+	jwt.RegisteredClaims
+}
+
+// SignMFAChallenge membuat MFA challenge token berumur ttl untuk pengguna.
+// session mencatat apakah login meminta mode sesi cookie.
+func (ks *KeySet) SignMFAChallenge(userID, purpose string, session bool, ttl time.Duration) (string, *MFAChallenge, error) {
+	jti, err := uuid.NewRandom()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	challenge := &MFAChallenge{
+		Purpose: purpose,
+		Session: session,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    TokenIssuer,
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{TokenIssuer},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        jti.String(),
+		},
+	}
+	token, err := ks.sign(challenge, MFATokenType)
+	return token, challenge, err
+}
+
+// ParseMFAChallenge memverifikasi MFA challenge token dengan tujuan purpose.
+func (ks *KeySet) ParseMFAChallenge(tokenString, purpose string) (*MFAChallenge, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(ks.ValidMethods()),
+		jwt.WithIssuer(TokenIssuer),
+		jwt.WithAudience(TokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	challenge := &MFAChallenge{}
+	token, err := parser.ParseWithClaims(tokenString, challenge, ks.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	if token.Header["typ"] != MFATokenType {
+		return nil, fmt.Errorf("%w: bukan MFA challenge token", jwt.ErrTokenInvalidClaims)
+	}
+	if challenge.Purpose != purpose || challenge.Subject == "" || challenge.ID == "" {
+		return nil, errors.New("MFA challenge token tidak berlaku untuk langkah ini")
+	}
+	return challenge, nil
+}
@@ -0,0 +1,308 @@
+// pkg/auth/oidc.go
+package auth
+
+import (
+	"api-gateway-go/pkg/config"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OIDCStateTokenType adalah header typ token state login OIDC. Verifier
+// menolaknya sebagai access token.
+const OIDCStateTokenType = "gateway-oidc-state+jwt"
+
+// ErrOIDCUnavailable dibungkus oleh error yang terjadi karena identity provider
+// tidak bisa dihubungi atau memberi respons yang tidak bisa dipakai, bukan
+// karena login ditolak.
+var ErrOIDCUnavailable = errors.New("identity provider tidak dapat dihubungi")
+
+// OIDCError adalah error dari token endpoint IdP (RFC 6749 bagian 5.2),
+// misalnya invalid_grant untuk authorization code yang sudah dipakai.
+type OIDCError struct {
+	Code        string
+	Description string
+}
+
+func (e *OIDCError) Error() string {
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	}
+	return e.Code
+}
+
+// OIDCState adalah isi cookie yang mengikat login OIDC ke browser yang
+// memulainya. Cookie HttpOnly ini menyimpan nonce dan PKCE code verifier
+// sehingga gateway tidak perlu menyimpan state di server.
+type OIDCState struct {
+	State    string `json:"state"`     // Nilai parameter state di URL
+	Nonce    string `json:"nonce"`     // Harus muncul di klaim nonce ID token
+	Verifier string `json:"verifier"`  // PKCE code verifier (RFC 7636)
+	ReturnTo string `json:"return_to"` // Tujuan redirect setelah login mode sesi
+	Session  bool   `json:"session,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// SignOIDCState menandatangani state login OIDC yang berlaku selama ttl.
+func (ks *KeySet) SignOIDCState(state *OIDCState, ttl time.Duration) (string, error) {
+	now := time.Now()
+	state.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    TokenIssuer,
+		Audience:  jwt.ClaimStrings{TokenIssuer},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+	return ks.sign(state, OIDCStateTokenType)
+}
+
+// ParseOIDCState memverifikasi cookie state dan mencocokkannya dengan
+// parameter state dari callback.
+func (ks *KeySet) ParseOIDCState(tokenString, state string) (*OIDCState, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(ks.ValidMethods()),
+		jwt.WithIssuer(TokenIssuer),
+		jwt.WithAudience(TokenIssuer),
+		jwt.WithExpirationRequired(),
+	)
+	claims := &OIDCState{}
+	token, err := parser.ParseWithClaims(tokenString, claims, ks.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	if token.Header["typ"] != OIDCStateTokenType {
+		return nil, fmt.Errorf("%w: bukan token state OIDC", jwt.ErrTokenInvalidClaims)
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(claims.State), []byte(state)) != 1 {
+		return nil, errors.New("parameter state tidak cocok dengan cookie")
+	}
+	if claims.Nonce == "" || claims.Verifier == "" {
+		return nil, errors.New("cookie state OIDC tidak lengkap")
+	}
+	return claims, nil
+}
+
+// NewOIDCNonce membuat nilai acak untuk parameter state, nonce, atau PKCE code
+// verifier (43 karakter, sesuai batas RFC 7636).
+func NewOIDCNonce() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// PKCEChallenge mengembalikan code challenge S256 untuk verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// OIDCIdentity adalah identitas pengguna dari ID token yang sudah diverifikasi.
+type OIDCIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string // Dari USERNAME_CLAIM; kosong jika IdP tidak mengirimnya
+	Email    string
+	Roles    []string // Role atau grup IdP dari ROLES_CLAIM, belum dipetakan
+	MFA      bool     // Klaim amr atau acr ID token cocok dengan MFA_AMR atau MFA_ACR
+}
+
+// oidcMetadata adalah bagian dokumen discovery yang dipakai relying party.
+type oidcMetadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgs           []string `json:"id_token_signing_alg_values_supported"`
+	ChallengeMethods      []string `json:"code_challenge_methods_supported"`
+}
+
+// OIDCProvider adalah klien relying party untuk satu identity provider:
+// membangun URL otorisasi, menukar authorization code, dan memverifikasi ID
+// token. Dokumen discovery diambil saat pertama kali dibutuhkan lalu disimpan.
+type OIDCProvider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu   sync.Mutex
+	meta *oidcMetadata
+	jwks *jwksCache
+}
+
+// NewOIDCProvider membuat OIDCProvider dari AUTH.OIDC.
+func NewOIDCProvider(cfg config.OIDCConfig) *OIDCProvider {
+	return &OIDCProvider{cfg: cfg, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// metadata mengembalikan dokumen discovery, mengambilnya jika belum ada.
+func (p *OIDCProvider) metadata(ctx context.Context) (*oidcMetadata, *jwksCache, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil {
+		return p.meta, p.jwks, nil
+	}
+
+	discoveryURL := p.cfg.DiscoveryDocumentURL()
+	var meta oidcMetadata
+	if err := fetchJSON(ctx, p.client, discoveryURL, &meta); err != nil {
+		return nil, nil, fmt.Errorf("%w: discovery %s: %v", ErrOIDCUnavailable, discoveryURL, err)
+	}
+	switch {
+	case meta.Issuer != p.cfg.Issuer:
+		return nil, nil, fmt.Errorf("%w: discovery %s: issuer %q tidak sama dengan %q", ErrOIDCUnavailable, discoveryURL, meta.Issuer, p.cfg.Issuer)
+	case meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "":
+		return nil, nil, fmt.Errorf("%w: discovery %s: authorization_endpoint, token_endpoint, atau jwks_uri kosong", ErrOIDCUnavailable, discoveryURL)
+	case len(meta.ChallengeMethods) > 0 && !slices.Contains(meta.ChallengeMethods, "S256"):
+		return nil, nil, fmt.Errorf("%w: IdP tidak mendukung PKCE S256", ErrOIDCUnavailable)
+	}
+	p.meta = &meta
+	p.jwks = newJWKSCache(p.cfg.Issuer, "", meta.JWKSURI)
+	return p.meta, p.jwks, nil
+}
+
+// AuthCodeURL mengembalikan URL authorization endpoint IdP untuk memulai
+// login dengan state, nonce, dan PKCE code challenge (S256).
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	meta, _, err := p.metadata(ctx)
+	if err != nil {
+		return "", err
+	}
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.RequestedScopes(), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {PKCEChallenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + q.Encode(), nil
+}
+
+// Exchange menukar authorization code dengan token di token endpoint IdP lalu
+// memverifikasi ID token-nya terhadap nonce.
+func (p *OIDCProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*OIDCIdentity, error) {
+	meta, jwks, err := p.metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {verifier},
+		"client_id":     {p.cfg.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		// client_secret_basic: client_id dan secret di-URL-encode (RFC 6749 2.3.1)
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: token endpoint: %v", ErrOIDCUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxJWKSBody)).Decode(&body); err != nil {
+		return nil, fmt.Errorf("%w: token endpoint: status %d: %v", ErrOIDCUnavailable, resp.StatusCode, err)
+	}
+	if body.Error != "" {
+		return nil, &OIDCError{Code: body.Error, Description: body.ErrorDescription}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: token endpoint: status %d", ErrOIDCUnavailable, resp.StatusCode)
+	}
+	if body.IDToken == "" {
+		return nil, errors.New("token endpoint tidak mengembalikan id_token")
+	}
+	return p.verifyIDToken(ctx, meta, jwks, body.IDToken, nonce)
+}
+
+// verifyIDToken memverifikasi signature dan klaim ID token (OIDC Core 3.1.3.7).
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, meta *oidcMetadata, jwks *jwksCache, raw, nonce string) (*OIDCIdentity, error) {
+	algs := meta.SigningAlgs
+	if len(algs) == 0 {
+		algs = []string{config.AlgRS256}
+	}
+	// "none" dan HMAC tidak pernah diterima untuk ID token dari IdP
+	algs = slices.DeleteFunc(slices.Clone(algs), func(alg string) bool {
+		return alg == "none" || strings.HasPrefix(alg, "HS")
+	})
+	parser := jwt.NewParser(
+		jwt.WithValidMethods(algs),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithLeeway(p.cfg.ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+	ext := &externalClaims{}
+	_, err := parser.ParseWithClaims(raw, ext, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		k, err := jwks.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if k.alg != "" && k.alg != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v for kid %q", token.Header["alg"], kid)
+		}
+		return k.key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if got, _ := ext.raw["nonce"].(string); subtle.ConstantTimeCompare([]byte(got), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce tidak cocok", jwt.ErrTokenInvalidClaims)
+	}
+	if azp, _ := ext.raw["azp"].(string); len(ext.Audience) > 1 && azp != p.cfg.ClientID {
+		return nil, fmt.Errorf("%w: azp %q bukan client gateway", jwt.ErrTokenInvalidClaims, azp)
+	}
+	if ext.Subject == "" {
+		return nil, fmt.Errorf("%w: klaim sub kosong", jwt.ErrTokenInvalidClaims)
+	}
+
+	username, _ := ext.raw[p.cfg.UsernameClaim].(string)
+	email, _ := ext.raw["email"].(string)
+	acr, _ := ext.raw["acr"].(string)
+	mfa := slices.ContainsFunc(stringList(ext.raw["amr"]), func(method string) bool {
+		return slices.Contains(p.cfg.MFAAMR, method)
+	}) || (acr != "" && slices.Contains(p.cfg.MFAACR, acr))
+	return &OIDCIdentity{
+		Issuer:   p.cfg.Issuer,
+		Subject:  ext.Subject,
+		Username: username,
+		Email:    email,
+		Roles:    stringList(ext.raw[p.cfg.RolesClaim]),
+		MFA:      mfa,
+	}, nil
+}
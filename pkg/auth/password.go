@@ -0,0 +1,214 @@
+// pkg/auth/password.go
+package auth
+
+import (
+	"api-gateway-go/pkg/config"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Algoritma hash password yang didukung untuk AUTH.PASSWORD_HASH.
+const (
+	HashArgon2id = "argon2id" // Default
+	HashBcrypt   = "bcrypt"
+)
+
+// Parameter argon2id sesuai rekomendasi OWASP (m=64 MiB, t=3, p=2).
+const (
+	argonMemory  = 64 * 1024
+	argonTime    = 3
+	argonThreads = 2
+	argonKeyLen  = 32
+	argonSaltLen = 16
+
+	// Batas parameter hash tersimpan yang mau diverifikasi. Hash dengan memori
+	// di atas argonMaxMemory (KiB) bisa membuat satu login menghabiskan RAM
+	// proses, sedangkan t=0 atau p=0 membuat argon2 panic.
+	argonMaxMemory = 256 * 1024
+	argonMinKeyLen = 16
+)
+
+// ErrUnknownHash dikembalikan jika format hash tersimpan tidak dikenali.
+var ErrUnknownHash = errors.New("format hash password tidak dikenal")
+
+// HashPassword membuat hash password dengan algoritma yang dipilih. Hasilnya
+// menyimpan algoritma dan parameternya sehingga VerifyPassword tetap bisa
+// memverifikasi hash lama setelah AUTH.PASSWORD_HASH diganti.
+func HashPassword(password, algorithm string) (string, error) {
+	switch algorithm {
+	case HashBcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		return string(hash), err
+	case "", HashArgon2id:
+		salt := make([]byte, argonSaltLen)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+			argon2.Version, argonMemory, argonTime, argonThreads,
+			base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key),
+		), nil
+	}
+	return "", fmt.Errorf("algoritma hash password %q tidak didukung", algorithm)
+}
+
+// VerifyPassword mencocokkan password dengan hash tersimpan (argon2id dalam
+// format PHC atau bcrypt).
+func VerifyPassword(encoded, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return verifyArgon2id(encoded, password)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return false, ErrUnknownHash
+}
+
+func verifyArgon2id(encoded, password string) (bool, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, ErrUnknownHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, ErrUnknownHash
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil ||
+		time < 1 || threads < 1 || memory > argonMaxMemory {
+		return false, ErrUnknownHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrUnknownHash
+	}
+	want, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(want) < argonMinKeyLen {
+		return false, ErrUnknownHash
+	}
+	got := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(want)))
+	return subtle.ConstantTimeCompare(got, want) == 1, nil
+}
+
+// dummyHashes berisi satu hash dummy per algoritma, dibuat saat pertama kali
+// dipakai. Verifikasi dummy dipakai saat username tidak ditemukan dan harus
+// memakai algoritma dan parameter yang sama dengan hash pengguna sungguhan,
+// karena bcrypt dan argon2id berbeda jauh waktunya.
+var dummyHashes = map[string]func() string{
+	HashArgon2id: newDummyHash(HashArgon2id),
+	HashBcrypt:   newDummyHash(HashBcrypt),
+}
+
+func newDummyHash(algorithm string) func() string {
+	return sync.OnceValue(func() string {
+		hash, _ := HashPassword("dummy-password-for-timing", algorithm)
+		return hash
+	})
+}
+
+// dummyHashFor mengembalikan hash dummy untuk algorithm; algoritma kosong
+// atau tidak dikenal memakai argon2id seperti HashPassword.
+func dummyHashFor(algorithm string) string {
+	dummy, ok := dummyHashes[algorithm]
+	if !ok {
+		dummy = dummyHashes[HashArgon2id]
+	}
+	return dummy()
+}
+
+// VerifyDummy menjalankan verifikasi terhadap hash dummy dan selalu gagal,
+// sehingga waktu respons tidak membocorkan keberadaan akun. algorithm adalah
+// AUTH.PASSWORD_HASH, algoritma hash password pengguna.
+func VerifyDummy(password, algorithm string) {
+	VerifyPassword(dummyHashFor(algorithm), password)
+}
+
+// ErrPasswordBusy dikembalikan PasswordLimiter jika slot verifikasi tidak
+// didapat dalam MAX_WAIT atau request dibatalkan selama menunggu.
+var ErrPasswordBusy = errors.New("terlalu banyak verifikasi password berjalan")
+
+// PasswordLimiter membatasi jumlah VerifyPassword dan VerifyDummy yang berjalan
+// bersamaan. Tanpa batas, banjir request login (termasuk dengan username
+// acak) membuat setiap request mengalokasikan 64 MiB untuk argon2id sampai
+// memori gateway habis. PasswordLimiter nil tidak membatasi apa pun.
+type PasswordLimiter struct {
+	slots   chan struct{}
+	maxWait time.Duration
+}
+
+// NewPasswordLimiter membuat PasswordLimiter dari cfg yang sudah lolos validasi.
+func NewPasswordLimiter(cfg config.PasswordVerifyConfig) *PasswordLimiter {
+	return &PasswordLimiter{slots: make(chan struct{}, cfg.MaxConcurrent), maxWait: cfg.MaxWait}
+}
+
+// acquire menunggu slot paling lama maxWait. Jika berhasil, pemanggil wajib
+// memanggil release.
+func (l *PasswordLimiter) acquire(ctx context.Context) error {
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if l.maxWait <= 0 {
+		return ErrPasswordBusy
+	}
+	timer := time.NewTimer(l.maxWait)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrPasswordBusy
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrPasswordBusy, ctx.Err())
+	}
+}
+
+func (l *PasswordLimiter) release() {
+	<-l.slots
+}
+
+// Verify menjalankan VerifyPassword setelah mendapat slot.
+func (l *PasswordLimiter) Verify(ctx context.Context, encoded, password string) (bool, error) {
+	if l == nil {
+		return VerifyPassword(encoded, password)
+	}
+	if err := l.acquire(ctx); err != nil {
+		return false, err
+	}
+	defer l.release()
+	return VerifyPassword(encoded, password)
+}
+
+// VerifyDummy menjalankan VerifyDummy setelah mendapat slot. Username yang
+// tidak terdaftar antre dengan cara yang sama, sehingga penolakan karena
+// penuh tidak membocorkan keberadaan akun.
+func (l *PasswordLimiter) VerifyDummy(ctx context.Context, password, algorithm string) error {
+	if l == nil {
+		VerifyDummy(password, algorithm)
+		return nil
+	}
+	if err := l.acquire(ctx); err != nil {
+		return err
+	}
+	defer l.release()
+	VerifyDummy(password, algorithm)
+	return nil
+}
@@ -0,0 +1,150 @@
+// pkg/auth/password_test.go
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"api-gateway-go/pkg/config"
+)
+
+func TestVerifyArgon2idParams(t *testing.T) {
+	hash, err := HashPassword("rahasia", HashArgon2id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(hash, "$")
+	withParams := func(params string) string {
+		return strings.Join([]string{"", "argon2id", parts[2], params, parts[4], parts[5]}, "$")
+	}
+	tests := []struct {
+		name    string
+		encoded string
+		wantOK  bool
+		wantErr error
+	}{
+		{"parameter default", hash, true, nil},
+		{"t=0", withParams("m=65536,t=0,p=2"), false, ErrUnknownHash},
+		{"p=0", withParams("m=65536,t=3,p=0"), false, ErrUnknownHash},
+		{"memori melebihi batas", withParams("m=4194304,t=3,p=2"), false, ErrUnknownHash},
+		{"p melebihi uint8", withParams("m=65536,t=3,p=300"), false, ErrUnknownHash},
+		{"hash kosong", strings.Join([]string{"", "argon2id", parts[2], parts[3], parts[4], ""}, "$"), false, ErrUnknownHash},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := VerifyPassword(tt.encoded, "rahasia")
+			if ok != tt.wantOK || !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifyPassword() = %v, %v, want %v, %v", ok, err, tt.wantOK, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordLimiter(t *testing.T) {
+	hash, err := HashPassword("rahasia", HashArgon2id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name     string
+		maxWait  time.Duration
+		occupied int // Slot yang sedang dipakai request lain
+		ctx      context.Context
+		freeIn   time.Duration // Satu slot dilepas setelah ini; 0 = tidak pernah
+		wantErr  error
+		wantOK   bool
+	}{
+		{name: "slot tersedia", maxWait: time.Second, ctx: context.Background(), wantOK: true},
+		{name: "penuh tanpa menunggu", occupied: 2, ctx: context.Background(), wantErr: ErrPasswordBusy},
+		{name: "penuh sampai MAX_WAIT habis", maxWait: 20 * time.Millisecond, occupied: 2, ctx: context.Background(), wantErr: ErrPasswordBusy},
+		{name: "slot dilepas selama menunggu", maxWait: time.Second, occupied: 2, ctx: context.Background(), freeIn: 20 * time.Millisecond, wantOK: true},
+		{name: "request dibatalkan selama menunggu", maxWait: time.Second, occupied: 2, ctx: cancelled, wantErr: ErrPasswordBusy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewPasswordLimiter(config.PasswordVerifyConfig{MaxConcurrent: 2, MaxWait: tt.maxWait})
+			for range tt.occupied {
+				l.slots <- struct{}{}
+			}
+			if tt.freeIn > 0 {
+				time.AfterFunc(tt.freeIn, l.release)
+			}
+
+			ok, err := l.Verify(tt.ctx, hash, "rahasia")
+			if !errors.Is(err, tt.wantErr) || ok != tt.wantOK {
+				t.Fatalf("Verify() = %v, %v, want %v, %v", ok, err, tt.wantOK, tt.wantErr)
+			}
+			if dummyErr := l.VerifyDummy(tt.ctx, "rahasia", HashArgon2id); tt.freeIn == 0 && !errors.Is(dummyErr, tt.wantErr) {
+				t.Errorf("VerifyDummy() = %v, want %v", dummyErr, tt.wantErr)
+			}
+			// Verifikasi yang selesai mengembalikan slotnya
+			want := tt.occupied
+			if tt.freeIn > 0 {
+				want--
+			}
+			if got := len(l.slots); got != want {
+				t.Errorf("%d slot terpakai setelah verifikasi, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestPasswordLimiterNil(t *testing.T) {
+	var l *PasswordLimiter
+	hash, err := HashPassword("rahasia", HashBcrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := l.Verify(context.Background(), hash, "salah"); ok || err != nil {
+		t.Errorf("Verify() = %v, %v, want false, nil", ok, err)
+	}
+	if err := l.VerifyDummy(context.Background(), "rahasia", HashBcrypt); err != nil {
+		t.Errorf("VerifyDummy() = %v", err)
+	}
+}
+
+func TestDummyHashFor(t *testing.T) {
+	tests := []struct {
+		algorithm  string
+		wantPrefix string
+	}{
+		{HashArgon2id, "$argon2id$"},
+		{HashBcrypt, "$2a$"},
+		{"", "$argon2id$"},
+		{"md5", "$argon2id$"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			hash := dummyHashFor(tt.algorithm)
+			if !strings.HasPrefix(hash, tt.wantPrefix) {
+				t.Fatalf("dummyHashFor(%q) = %q, want awalan %s", tt.algorithm, hash, tt.wantPrefix)
+			}
+			// Parameter sama dengan hash baru sehingga biaya verifikasinya sama
+			real, err := HashPassword("rahasia", tt.algorithm)
+			if err != nil {
+				real, _ = HashPassword("rahasia", HashArgon2id)
+			}
+			if hashParams(hash) != hashParams(real) {
+				t.Errorf("parameter dummy %q, want %q", hashParams(hash), hashParams(real))
+			}
+			if ok, err := VerifyPassword(hash, "rahasia"); ok || err != nil {
+				t.Errorf("VerifyPassword(dummy) = %v, %v, want false, nil", ok, err)
+			}
+		})
+	}
+}
+
+// hashParams mengambil bagian hash yang berisi algoritma dan parameter biaya.
+func hashParams(hash string) string {
+	parts := strings.Split(hash, "$")
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return strings.Join(parts[:4], "$")
+	}
+	return strings.Join(parts[:3], "$")
+}
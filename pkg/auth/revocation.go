@@ -0,0 +1,185 @@
+// pkg/auth/revocation.go
+package auth
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Jenis pencabutan yang disimpan RevocationStore.
+const (
+	RevokeKindToken = "jti"  // Satu access token, Key berisi jti
+	RevokeKindUser  = "user" // Semua access token pengguna yang terbit sebelum RevokedAt, Key berisi user ID
+)
+
+// Revocation adalah satu entri daftar pencabutan. Entri tidak berguna lagi
+// setelah ExpiresAt karena token yang dicabut sudah kedaluwarsa dengan sendirinya.
+type Revocation struct {
+	Kind      string
+	Key       string
+	RevokedAt time.Time
+	ExpiresAt time.Time
+}
+
+// RevocationPersister menyimpan daftar pencabutan agar tetap berlaku setelah
+// gateway di-restart.
+type RevocationPersister interface {
+	SaveRevocation(ctx context.Context, r Revocation) error
+	LoadRevocations(ctx context.Context, now time.Time) ([]Revocation, error)
+	DeleteExpiredRevocations(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RevocationStore adalah daftar access token yang dicabut sebelum kedaluwarsa.
+// Pemeriksaan hanya berupa lookup map di bawah RLock sehingga aman dipanggil
+// untuk setiap request.
+type RevocationStore struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time  // jti -> waktu entri boleh dibuang
+	users  map[string]Revocation // user ID -> pencabutan terakhir
+
+	persister RevocationPersister // nil berarti hanya di memori
+}
+
+// NewRevocationStore membuat store kosong. persister boleh nil.
+func NewRevocationStore(persister RevocationPersister) *RevocationStore {
+	return &RevocationStore{
+		tokens:    make(map[string]time.Time),
+		users:     make(map[string]Revocation),
+		persister: persister,
+	}
+}
+
+// Load mengisi store dengan entri tersimpan yang belum kedaluwarsa.
+func (s *RevocationStore) Load(ctx context.Context) (int, error) {
+	if s.persister == nil {
+		return 0, nil
+	}
+	entries, err := s.persister.LoadRevocations(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range entries {
+		s.add(r)
+	}
+	return len(entries), nil
+}
+
+// RevokeToken mencabut satu access token berdasarkan jti sampai expiresAt.
+func (s *RevocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return s.revoke(ctx, Revocation{Kind: RevokeKindToken, Key: jti, RevokedAt: time.Now(), ExpiresAt: expiresAt})
+}
+
+// RevokeUser mencabut semua access token pengguna yang terbit sampai saat ini.
+// maxTTL adalah masa berlaku access token terpanjang; setelah itu tidak ada lagi
+// token lama yang perlu ditolak.
+func (s *RevocationStore) RevokeUser(ctx context.Context, userID string, maxTTL time.Duration) error {
+	now := time.Now()
+	return s.revoke(ctx, Revocation{Kind: RevokeKindUser, Key: userID, RevokedAt: now, ExpiresAt: now.Add(maxTTL)})
+}
+
+func (s *RevocationStore) revoke(ctx context.Context, r Revocation) error {
+	s.add(r)
+	if s.persister == nil {
+		return nil
+	}
+	return s.persister.SaveRevocation(ctx, r)
+}
+
+func (s *RevocationStore) add(r Revocation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Kind {
+	case RevokeKindToken:
+		s.tokens[r.Key] = r.ExpiresAt
+	case RevokeKindUser:
+		// Simpan pencabutan terbaru saja; yang lebih baru mencakup yang lama
+		if prev, ok := s.users[r.Key]; !ok || r.RevokedAt.After(prev.RevokedAt) {
+			s.users[r.Key] = r
+		}
+	}
+}
+
+// IsRevoked melaporkan apakah access token dengan claims ini sudah dicabut.
+// Pencabutan per pengguna memakai presisi detik karena iat JWT dalam detik:
+// token yang terbit pada detik yang sama dengan pencabutan ikut ditolak.
+func (s *RevocationStore) IsRevoked(claims *Claims) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if claims.ID != "" {
+		if _, ok := s.tokens[claims.ID]; ok {
+			return true
+		}
+	}
+	if r, ok := s.users[claims.UserID]; ok {
+		if claims.IssuedAt == nil || claims.IssuedAt.Unix() <= r.RevokedAt.Unix() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTokenRevoked melaporkan apakah token dengan jti ini sudah dicabut, untuk
+// token sekali pakai yang bukan access token (misal MFA challenge).
+func (s *RevocationStore) IsTokenRevoked(jti string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.tokens[jti]
+	return ok
+}
+
+// Purge membuang entri yang sudah kedaluwarsa dari memori dan dari persister.
+func (s *RevocationStore) Purge(ctx context.Context) (int, error) {
+	now := time.Now()
+	removed := 0
+	s.mu.Lock()
+	for jti, exp := range s.tokens {
+		if now.After(exp) {
+			delete(s.tokens, jti)
+			removed++
+		}
+	}
+	for id, r := range s.users {
+		if now.After(r.ExpiresAt) {
+			delete(s.users, id)
+			removed++
+		}
+	}
+	s.mu.Unlock()
+
+	if s.persister != nil {
+		if _, err := s.persister.DeleteExpiredRevocations(ctx, now); err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// RunJanitor menjalankan Purge secara berkala sampai ctx selesai. Setelah itu
+// entri tersimpan dimuat ulang agar pencabutan yang ditulis proses lain (misal
+// "gateway users disable" atau instance gateway lain) ikut berlaku.
+func (s *RevocationStore) RunJanitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Purge(ctx); err != nil {
+				log.Printf("Gagal membersihkan daftar pencabutan token: %v", err)
+			}
+			if _, err := s.Load(ctx); err != nil {
+				log.Printf("Gagal memuat ulang daftar pencabutan token: %v", err)
+			}
+		}
+	}
+}
+
+// Len mengembalikan jumlah entri token dan pengguna yang sedang dicabut.
+func (s *RevocationStore) Len() (tokens, users int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tokens), len(s.users)
+}
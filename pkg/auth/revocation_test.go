@@ -0,0 +1,142 @@
+// pkg/auth/revocation_test.go
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// memoryPersister adalah RevocationPersister di memori untuk test.
+type memoryPersister struct {
+	saved []Revocation
+}
+
+func (p *memoryPersister) SaveRevocation(_ context.Context, r Revocation) error {
+	p.saved = append(p.saved, r)
+	return nil
+}
+
+func (p *memoryPersister) LoadRevocations(_ context.Context, now time.Time) ([]Revocation, error) {
+	var live []Revocation
+	for _, r := range p.saved {
+		if r.ExpiresAt.After(now) {
+			live = append(live, r)
+		}
+	}
+	return live, nil
+}
+
+func (p *memoryPersister) DeleteExpiredRevocations(_ context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func claimsAt(userID, jti string, iat time.Time) *Claims {
+	c := &Claims{UserID: userID, RegisteredClaims: jwt.RegisteredClaims{ID: jti}}
+	if !iat.IsZero() {
+		c.IssuedAt = jwt.NewNumericDate(iat)
+	}
+	return c
+}
+
+func TestRevocationStoreIsRevoked(t *testing.T) {
+	// Pencabutan di tengah detik agar batas presisi detik ikut teruji
+	revokedAt := time.Unix(1_700_000_000, 600_000_000)
+	store := NewRevocationStore(nil)
+	store.add(Revocation{Kind: RevokeKindUser, Key: "alice", RevokedAt: revokedAt, ExpiresAt: revokedAt.Add(time.Hour)})
+	// Pencabutan tepat di awal detik: token dengan iat yang sama tetap ditolak
+	store.add(Revocation{Kind: RevokeKindUser, Key: "carol", RevokedAt: revokedAt.Truncate(time.Second), ExpiresAt: revokedAt.Add(time.Hour)})
+	store.add(Revocation{Kind: RevokeKindToken, Key: "jti-revoked", RevokedAt: revokedAt, ExpiresAt: revokedAt.Add(time.Hour)})
+
+	tests := []struct {
+		name   string
+		claims *Claims
+		want   bool
+	}{
+		{"terbit sebelum pencabutan", claimsAt("alice", "a", revokedAt.Add(-time.Second)), true},
+		{"terbit di awal detik pencabutan", claimsAt("alice", "b", revokedAt.Truncate(time.Second)), true},
+		{"terbit di detik yang sama setelah pencabutan", claimsAt("alice", "c", revokedAt.Add(300*time.Millisecond)), true},
+		{"terbit di detik berikutnya", claimsAt("alice", "d", revokedAt.Truncate(time.Second).Add(time.Second)), false},
+		{"iat sama dengan pencabutan di awal detik", claimsAt("carol", "g", revokedAt.Truncate(time.Second)), true},
+		{"iat satu detik setelah pencabutan di awal detik", claimsAt("carol", "h", revokedAt.Truncate(time.Second).Add(time.Second)), false},
+		{"tanpa iat", claimsAt("alice", "e", time.Time{}), true},
+		{"pengguna lain", claimsAt("bob", "f", revokedAt.Add(-time.Hour)), false},
+		{"jti dicabut", claimsAt("bob", "jti-revoked", revokedAt.Add(time.Hour)), true},
+		{"jti kosong tidak cocok dengan apa pun", claimsAt("bob", "", revokedAt), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := store.IsRevoked(tt.claims); got != tt.want {
+				t.Errorf("IsRevoked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevocationStoreKeepsLatestUserRevocation(t *testing.T) {
+	base := time.Unix(1_700_000_000, 0)
+	tests := []struct {
+		name  string
+		order []time.Time // Urutan RevokedAt yang ditambahkan
+		want  time.Time
+	}{
+		{"lebih baru menggantikan", []time.Time{base, base.Add(time.Minute)}, base.Add(time.Minute)},
+		{"lebih lama diabaikan", []time.Time{base.Add(time.Minute), base}, base.Add(time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewRevocationStore(nil)
+			for _, at := range tt.order {
+				store.add(Revocation{Kind: RevokeKindUser, Key: "alice", RevokedAt: at, ExpiresAt: at.Add(time.Hour)})
+			}
+			if got := store.users["alice"].RevokedAt; !got.Equal(tt.want) {
+				t.Errorf("RevokedAt = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRevocationStoreLoadAndPurge(t *testing.T) {
+	ctx := context.Background()
+	persister := &memoryPersister{}
+	writer := NewRevocationStore(persister)
+	if err := writer.RevokeToken(ctx, "live", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.RevokeToken(ctx, "stale", time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.RevokeUser(ctx, "alice", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// Instance lain (atau CLI) yang memuat dari persister melihat entri yang masih berlaku
+	reader := NewRevocationStore(persister)
+	n, err := reader.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Load() = %d entri, want 2", n)
+	}
+	live, stale := reader.IsRevoked(claimsAt("bob", "live", time.Now())), reader.IsRevoked(claimsAt("bob", "stale", time.Now()))
+	if !live || stale {
+		t.Errorf("IsRevoked live=%v stale=%v, want true false", live, stale)
+	}
+	if !reader.IsRevoked(claimsAt("alice", "x", time.Now().Add(-time.Minute))) {
+		t.Error("token alice yang terbit sebelum pencabutan masih diterima setelah Load")
+	}
+
+	removed, err := writer.Purge(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("Purge() = %d, want 1", removed)
+	}
+	if tokens, users := writer.Len(); tokens != 1 || users != 1 {
+		t.Errorf("Len() = %d, %d, want 1, 1", tokens, users)
+	}
+}
@@ -0,0 +1,153 @@
+// pkg/auth/token.go
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// TokenIssuer adalah nilai klaim "iss" untuk token yang diterbitkan gateway.
+const TokenIssuer = "api-gateway"
+
+// ClaimsContextKey adalah kunci gin.Context tempat AuthMiddleware menyimpan *Claims.
+const ClaimsContextKey = "claims"
+
+// refreshTokenPrefix memudahkan mengenali refresh token di log atau secret scanner.
+const refreshTokenPrefix = "rt_"
+
+// Claims adalah struktur untuk data yang akan disimpan dalam token JWT
+type Claims struct {
+	UserID   string   `json:"user_id"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles,omitempty"`
+	Scope    string   `json:"scope,omitempty"`     // Daftar scope dipisah spasi (RFC 8693)
+	CSRF     string   `json:"csrf,omitempty"`      // Hash token CSRF untuk token yang dikirim sebagai cookie sesi
+	ClientID string   `json:"client_id,omitempty"` // OAuth client untuk token client_credentials (RFC 9068)
+	jwt.RegisteredClaims
+}
+
+// Machine melaporkan apakah token milik OAuth client (identitas mesin), bukan
+// pengguna.
+func (c *Claims) Machine() bool {
+	return c.ClientID != ""
+}
+
+// HasRole melaporkan apakah token memiliki role tertentu.
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// Scopes mengembalikan scope token sebagai slice.
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope melaporkan apakah token memiliki scope tertentu.
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes(), scope)
+}
+
+// Issuer menandatangani access token untuk pengguna.
+type Issuer struct {
+	keys      *KeySet
+	accessTTL time.Duration
+}
+
+// NewIssuer membuat Issuer dengan kunci aktif dari keys dan AUTH.ACCESS_TOKEN_TTL.
+func NewIssuer(keys *KeySet, accessTTL time.Duration) *Issuer {
+	return &Issuer{keys: keys, accessTTL: accessTTL}
+}
+
+// AccessTokenTTL mengembalikan masa berlaku access token.
+func (i *Issuer) AccessTokenTTL() time.Duration {
+	return i.accessTTL
+}
+
+// IssueAccessToken membuat access token JWT berumur pendek untuk pengguna.
+// roles diambil dari data pengguna saat token diterbitkan; perubahan role baru
+// terlihat di token berikutnya (login atau refresh).
+func (i *Issuer) IssueAccessToken(userID, username string, roles []string) (string, *Claims, error) {
+	return i.issue(&Claims{UserID: userID, Username: username, Roles: roles}, i.accessTTL)
+}
+
+// IssueSessionAccessToken seperti IssueAccessToken, untuk dikirim sebagai
+// cookie sesi. csrfHash (lihat HashCSRFToken) mengikat token CSRF ke token ini.
+func (i *Issuer) IssueSessionAccessToken(userID, username string, roles []string, csrfHash string) (string, *Claims, error) {
+	return i.issue(&Claims{UserID: userID, Username: username, Roles: roles, CSRF: csrfHash}, i.accessTTL)
+}
+
+// IssueClientToken membuat access token untuk OAuth client (grant
+// client_credentials). id adalah ID client di database sehingga token bisa
+// dicabut seperti token pengguna; clientID ditaruh di klaim client_id dan
+// username. ttl menggantikan ACCESS_TOKEN_TTL.
+func (i *Issuer) IssueClientToken(id, clientID string, roles []string, scope string, ttl time.Duration) (string, *Claims, error) {
+	return i.issue(&Claims{UserID: id, Username: clientID, Roles: roles, Scope: scope, ClientID: clientID}, ttl)
+}
+
+// issue melengkapi klaim standar claims lalu menandatanganinya.
+func (i *Issuer) issue(claims *Claims, ttl time.Duration) (string, *Claims, error) {
+	jti, err := uuid.NewV7()
+	if err != nil {
+		return "", nil, err
+	}
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		Issuer:    TokenIssuer,   // Nama penerbit token
+		Subject:   claims.UserID, // Subjek token (seringkali ID pengguna)
+		ID:        jti.String(),  // jti, dipakai untuk mencabut satu token saat logout
+	}
+
+	// Tanda tangani dengan kunci aktif (atau HS256 jika tidak ada kunci asimetris)
+	signed, err := i.keys.Sign(claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, claims, nil
+}
+
+// NewRefreshToken membuat refresh token opaque acak. Yang disimpan di server
+// hanya hash-nya (lihat HashRefreshToken), token aslinya hanya dikirim ke klien.
+func NewRefreshToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = refreshTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// IsRefreshToken melaporkan apakah token berformat refresh token gateway.
+func IsRefreshToken(token string) bool {
+	return strings.HasPrefix(token, refreshTokenPrefix)
+}
+
+// HashRefreshToken mengembalikan hash refresh token untuk lookup.
+func HashRefreshToken(token string) string {
+	return hashOpaqueSecret(token)
+}
+
+// hashOpaqueSecret mengembalikan hash SHA-256 (hex) dari secret buatan gateway
+// (refresh token, API key, client secret). Secret tersebut berisi 256 bit acak
+// sehingga hash cepat tanpa salt sudah cukup, berbeda dengan password.
+func hashOpaqueSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// opaqueSecretMatches membandingkan secret dengan hash tersimpan dalam waktu
+// konstan.
+func opaqueSecretMatches(secret, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(hashOpaqueSecret(secret)), []byte(hash)) == 1
+}
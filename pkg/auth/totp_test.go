@@ -0,0 +1,72 @@
+// pkg/auth/totp_test.go
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret adalah secret SHA-1 dari vektor uji RFC 6238 dalam base32.
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestVerifyTOTP(t *testing.T) {
+	at := func(sec int64) time.Time { return time.Unix(sec, 0) }
+	// Langkah waktu untuk t=1111111109 (kode 081804)
+	step := int64(1111111109 / totpPeriod)
+
+	tests := []struct {
+		name     string
+		code     string
+		t        time.Time
+		skew     int
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"vektor RFC t=59", "287082", at(59), 0, 0, 1, true},
+		{"vektor RFC t=1111111109", "081804", at(1111111109), 0, 0, step, true},
+		{"vektor RFC t=1234567890", "005924", at(1234567890), 0, 0, 1234567890 / totpPeriod, true},
+		{"kode salah", "000000", at(1111111109), 1, 0, 0, false},
+		{"panjang kode salah", "81804", at(1111111109), 1, 0, 0, false},
+		{"langkah sebelumnya tanpa skew", "081804", at(1111111109 + totpPeriod), 0, 0, 0, false},
+		{"langkah sebelumnya dalam skew", "081804", at(1111111109 + totpPeriod), 1, 0, step, true},
+		{"langkah berikutnya dalam skew", "081804", at(1111111109 - totpPeriod), 1, 0, step, true},
+		{"di luar skew", "081804", at(1111111109 + 2*totpPeriod), 1, 0, 0, false},
+		{"replay langkah yang sama", "081804", at(1111111109), 1, step, 0, false},
+		{"replay langkah lebih lama", "081804", at(1111111109 + totpPeriod), 1, step + 1, 0, false},
+		{"langkah sebelumnya masih belum terpakai", "081804", at(1111111109), 1, step - 1, step, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := VerifyTOTP(rfc6238Secret, tt.code, tt.t, tt.skew, tt.lastStep)
+			if ok != tt.wantOK || gotStep != tt.wantStep {
+				t.Errorf("VerifyTOTP() = %d, %v, want %d, %v", gotStep, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestVerifyTOTPSecretCase(t *testing.T) {
+	// Secret yang diketik manual sering berhuruf kecil
+	lower := []byte(rfc6238Secret)
+	for i, b := range lower {
+		if b >= 'A' && b <= 'Z' {
+			lower[i] = b + 'a' - 'A'
+		}
+	}
+	if _, ok := VerifyTOTP(string(lower), "287082", time.Unix(59, 0), 0, 0); !ok {
+		t.Error("secret huruf kecil ditolak")
+	}
+	if _, ok := VerifyTOTP("bukan-base32!", "287082", time.Unix(59, 0), 0, 0); ok {
+		t.Error("secret tidak valid diterima")
+	}
+}
+
+func TestHashRecoveryCodeNormalizes(t *testing.T) {
+	want := HashRecoveryCode("abcde-12345")
+	for _, code := range []string{"ABCDE-12345", "abcde12345", " abcde 12345 "} {
+		if got := HashRecoveryCode(code); got != want {
+			t.Errorf("HashRecoveryCode(%q) berbeda dari bentuk kanonik", code)
+		}
+	}
+}
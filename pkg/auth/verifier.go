@@ -0,0 +1,180 @@
+// pkg/auth/verifier.go
+package auth
+
+import (
+	"api-gateway-go/pkg/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// externalIssuer memverifikasi token dari satu identity provider eksternal.
+type externalIssuer struct {
+	cfg    config.TrustedIssuerConfig
+	parser *jwt.Parser
+	jwks   *jwksCache
+}
+
+// externalClaims menyimpan klaim standar beserta semua klaim mentah, karena
+// nama klaim username (dan klaim lain) berbeda-beda antar IdP.
+type externalClaims struct {
+	jwt.RegisteredClaims
+	raw map[string]interface{}
+}
+
+func (c *externalClaims) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, &c.RegisteredClaims); err != nil {
+		return err
+	}
+	return json.Unmarshal(b, &c.raw)
+}
+
+// Verifier memverifikasi access token buatan gateway (dengan KeySet) dan token
+// dari issuer eksternal di AUTH.TRUSTED_ISSUERS (dengan JWKS masing-masing).
+// Issuer dipilih berdasarkan klaim "iss".
+type Verifier struct {
+	keys        *KeySet
+	localParser *jwt.Parser
+	issuers     map[string]*externalIssuer
+}
+
+// NewVerifier memuat kunci lokal dan menyiapkan issuer eksternal dari cfg.
+func NewVerifier(cfg config.Config) (*Verifier, error) {
+	keys, err := LoadKeySet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	v := &Verifier{
+		keys:        keys,
+		localParser: jwt.NewParser(jwt.WithValidMethods(keys.ValidMethods())),
+		issuers:     make(map[string]*externalIssuer),
+	}
+	for _, ic := range cfg.Auth.TrustedIssuers {
+		ic = ic.WithDefaults()
+		v.issuers[ic.Issuer] = &externalIssuer{
+			cfg: ic,
+			parser: jwt.NewParser(
+				jwt.WithValidMethods(ic.Algorithms),
+				jwt.WithIssuer(ic.Issuer),
+				jwt.WithLeeway(ic.ClockSkew),
+				jwt.WithExpirationRequired(),
+			),
+			jwks: newJWKSCache(ic.Issuer, ic.DiscoveryURL, ic.JWKSURL),
+		}
+	}
+	return v, nil
+}
+
+// Keys mengembalikan kunci lokal untuk menandatangani token.
+func (v *Verifier) Keys() *KeySet {
+	return v.keys
+}
+
+// InheritState memakai ulang cache JWKS dari verifier generasi sebelumnya
+// untuk issuer yang sumber JWKS-nya tidak berubah, agar reload konfigurasi
+// tidak memaksa fetch ulang.
+func (v *Verifier) InheritState(prev *Verifier) {
+	if prev == nil {
+		return
+	}
+	for iss, ei := range v.issuers {
+		old, ok := prev.issuers[iss]
+		if ok && old.cfg.DiscoveryURL == ei.cfg.DiscoveryURL && old.cfg.JWKSURL == ei.cfg.JWKSURL {
+			ei.jwks = old.jwks
+		}
+	}
+}
+
+// Start menjalankan refresh JWKS di background untuk setiap issuer eksternal.
+func (v *Verifier) Start() (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	for _, ei := range v.issuers {
+		go ei.jwks.run(ctx, ei.cfg.RefreshInterval)
+	}
+	return cancel
+}
+
+// Verify memverifikasi token dan mengembalikan claims-nya. Error yang
+// dikembalikan membungkus error jwt (misal jwt.ErrTokenExpired).
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*Claims, error) {
+	// Baca "iss" tanpa verifikasi hanya untuk memilih kunci; signature tetap
+	// diverifikasi di bawah dengan kunci issuer tersebut.
+	var peek jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, &peek); err != nil {
+		return nil, err
+	}
+	if ei, ok := v.issuers[peek.Issuer]; ok {
+		return ei.verify(ctx, tokenString)
+	}
+
+	claims := &Claims{}
+	token, err := v.localParser.ParseWithClaims(tokenString, claims, v.keys.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	// Identity assertion dan MFA challenge ditandatangani kunci yang sama tetapi
+	// bukan access token
+	if typ, _ := token.Header["typ"].(string); typ != "" && typ != "JWT" {
+		return nil, fmt.Errorf("%w: token bertipe %s bukan access token", jwt.ErrTokenInvalidClaims, typ)
+	}
+	return claims, nil
+}
+
+func (ei *externalIssuer) verify(ctx context.Context, tokenString string) (*Claims, error) {
+	ext := &externalClaims{}
+	_, err := ei.parser.ParseWithClaims(tokenString, ext, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		k, err := ei.jwks.key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if k.alg != "" && k.alg != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %v for kid %q", token.Header["alg"], kid)
+		}
+		return k.key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(ei.cfg.Audiences, func(aud string) bool { return slices.Contains(ext.Audience, aud) }) {
+		return nil, fmt.Errorf("%w: %v", jwt.ErrTokenInvalidAudience, ext.Audience)
+	}
+
+	if ext.Subject == "" {
+		return nil, fmt.Errorf("%w: klaim sub kosong", jwt.ErrTokenInvalidClaims)
+	}
+
+	username, _ := ext.raw[ei.cfg.UsernameClaim].(string)
+	if username == "" {
+		username = ext.Subject
+	}
+	return &Claims{
+		UserID:           ext.Subject,
+		Username:         username,
+		Roles:            stringList(ext.raw[ei.cfg.RolesClaim]),
+		Scope:            strings.Join(append(stringList(ext.raw["scope"]), stringList(ext.raw["scp"])...), " "),
+		RegisteredClaims: ext.RegisteredClaims,
+	}, nil
+}
+
+// stringList membaca klaim yang bisa berupa array string atau string dipisah
+// spasi (bentuk "scope" di OAuth2, sedangkan "scp" dan "roles" biasanya array).
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -2,16 +2,374 @@
 package config
 
 import (
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
 // Config menyimpan semua konfigurasi aplikasi.
 type Config struct {
-	ServerPort       string            `mapstructure:"SERVER_PORT"`
-	AppEnv           string            `mapstructure:"APP_ENV"`
-	AuthSecret       string            `mapstructure:"AUTH_SECRET"`
-	ServiceEndpoints map[string]string `mapstructure:"SERVICE_ENDPOINTS"`
-	RateLimit        RateLimitConfig   `mapstructure:"RATE_LIMIT"`
+	ServerPort       string                   `mapstructure:"SERVER_PORT"`
+	AppEnv           string                   `mapstructure:"APP_ENV"`
+	AuthSecret       string                   `mapstructure:"AUTH_SECRET"`
+	Auth             AuthConfig               `mapstructure:"AUTH"`
+	Database         DatabaseConfig           `mapstructure:"DATABASE"`
+	TrustedProxies   []string                 `mapstructure:"TRUSTED_PROXIES"`   // IP/CIDR proxy yang header forwarding-nya dipercaya
+	ServiceEndpoints map[string]string        `mapstructure:"SERVICE_ENDPOINTS"` // Shorthand untuk service dengan satu instance
+	Services         map[string]ServiceConfig `mapstructure:"SERVICES"`
+	Routes           []RouteConfig            `mapstructure:"ROUTES"`
+	RateLimit        RateLimitConfig          `mapstructure:"RATE_LIMIT"`
+	RetryBudget      RetryBudgetConfig        `mapstructure:"RETRY_BUDGET"`
+	RequestID        RequestIDConfig          `mapstructure:"REQUEST_ID"`
+	Server           ServerConfig             `mapstructure:"SERVER"`
+	Transport        TransportConfig          `mapstructure:"UPSTREAM_TRANSPORT"`
+	Admin            AdminConfig              `mapstructure:"ADMIN"`
+	CORS             CORSConfig               `mapstructure:"CORS"`
+
+	// IdentityHeaders adalah header identitas default untuk semua rute; rute
+	// bisa menggantinya dengan ROUTES[].IDENTITY_HEADERS.
+	IdentityHeaders IdentityConfig `mapstructure:"IDENTITY_HEADERS"`
+}
+
+// AuthConfig mengatur autentikasi pengguna.
+type AuthConfig struct {
+	PasswordHash    string           `mapstructure:"PASSWORD_HASH"`     // argon2id (default) atau bcrypt untuk hash baru
+	AccessTokenTTL  time.Duration    `mapstructure:"ACCESS_TOKEN_TTL"`  // Masa berlaku access token JWT
+	RefreshTokenTTL time.Duration    `mapstructure:"REFRESH_TOKEN_TTL"` // Masa berlaku refresh token sejak diterbitkan
+	Revocation      RevocationConfig `mapstructure:"REVOCATION"`
+	Signing         SigningConfig    `mapstructure:"SIGNING"`
+	APIKeys         APIKeyConfig     `mapstructure:"API_KEYS"`
+	MFA             MFAConfig        `mapstructure:"MFA"`
+	Session         SessionConfig    `mapstructure:"SESSION"`
+
+	// Introspection mengatur siapa yang boleh memanggil /auth/introspect.
+	Introspection IntrospectionConfig `mapstructure:"INTROSPECTION"`
+
+	// ClientCredentials mengatur grant client_credentials di /oauth/token.
+	ClientCredentials ClientCredentialsConfig `mapstructure:"CLIENT_CREDENTIALS"`
+
+	// OIDC mengatur login lewat identity provider perusahaan di /auth/oidc.
+	OIDC OIDCConfig `mapstructure:"OIDC"`
+
+	// LoginProtection membatasi tebakan password di /auth/login.
+	LoginProtection LoginProtectionConfig `mapstructure:"LOGIN_PROTECTION"`
+
+	// PasswordVerify membatasi verifikasi password yang berjalan bersamaan.
+	PasswordVerify PasswordVerifyConfig `mapstructure:"PASSWORD_VERIFY"`
+
+	// TrustedIssuers adalah identity provider eksternal (OIDC) yang token-nya
+	// diterima AuthMiddleware selain token buatan gateway sendiri.
+	TrustedIssuers []TrustedIssuerConfig `mapstructure:"TRUSTED_ISSUERS"`
+}
+
+// TrustedIssuerConfig adalah satu identity provider eksternal. Salah satu dari
+// DISCOVERY_URL atau JWKS_URL wajib diisi.
+type TrustedIssuerConfig struct {
+	Issuer          string        `mapstructure:"ISSUER"`           // Harus sama persis dengan klaim "iss"
+	DiscoveryURL    string        `mapstructure:"DISCOVERY_URL"`    // .well-known/openid-configuration, untuk menemukan jwks_uri
+	JWKSURL         string        `mapstructure:"JWKS_URL"`         // Dipakai langsung jika diisi
+	Audiences       []string      `mapstructure:"AUDIENCES"`        // Token harus ditujukan ke salah satu audience ini
+	Algorithms      []string      `mapstructure:"ALGORITHMS"`       // Default [RS256]
+	ClockSkew       time.Duration `mapstructure:"CLOCK_SKEW"`       // Toleransi selisih jam untuk exp/nbf/iat, default 30s
+	RefreshInterval time.Duration `mapstructure:"REFRESH_INTERVAL"` // Interval refresh JWKS di background, default 15m
+	UsernameClaim   string        `mapstructure:"USERNAME_CLAIM"`   // Klaim untuk username, default preferred_username
+	RolesClaim      string        `mapstructure:"ROLES_CLAIM"`      // Klaim berisi daftar role, default roles
+}
+
+// WithDefaults mengisi nilai kosong dengan default.
+func (t TrustedIssuerConfig) WithDefaults() TrustedIssuerConfig {
+	if len(t.Algorithms) == 0 {
+		t.Algorithms = []string{AlgRS256}
+	}
+	if t.ClockSkew == 0 {
+		t.ClockSkew = 30 * time.Second
+	}
+	if t.RefreshInterval <= 0 {
+		t.RefreshInterval = 15 * time.Minute
+	}
+	if t.UsernameClaim == "" {
+		t.UsernameClaim = "preferred_username"
+	}
+	if t.RolesClaim == "" {
+		t.RolesClaim = "roles"
+	}
+	return t
+}
+
+// APIKeyConfig mengatur dari mana API key consumer dibaca. Key dan consumer
+// sendiri disimpan di database dan dikelola lewat CLI atau admin API.
+type APIKeyConfig struct {
+	Header     string `mapstructure:"HEADER"`      // Default X-API-Key
+	QueryParam string `mapstructure:"QUERY_PARAM"` // Kosong berarti API key tidak diterima dari query string
+}
+
+// SigningConfig mengatur kunci penandatangan access token. Jika KEYS kosong,
+// token ditandatangani HS256 dengan AUTH_SECRET.
+type SigningConfig struct {
+	ActiveKID   string             `mapstructure:"ACTIVE_KID"`   // kid kunci yang dipakai menandatangani token baru
+	Keys        []SigningKeyConfig `mapstructure:"KEYS"`         // Semua kunci yang diterima saat verifikasi
+	AcceptHS256 bool               `mapstructure:"ACCEPT_HS256"` // Tetap terima token HS256 lama selama migrasi
+}
+
+// SigningKeyConfig adalah satu kunci asimetris dari file PEM.
+type SigningKeyConfig struct {
+	KID            string `mapstructure:"KID"`
+	Algorithm      string `mapstructure:"ALGORITHM"`        // RS256, ES256, atau EdDSA
+	PrivateKeyFile string `mapstructure:"PRIVATE_KEY_FILE"` // Wajib untuk kunci aktif
+	PublicKeyFile  string `mapstructure:"PUBLIC_KEY_FILE"`  // Untuk kunci yang hanya dipakai verifikasi
+}
+
+// Algoritma penandatangan yang didukung untuk AUTH.SIGNING.KEYS.
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// RevocationConfig mengatur daftar pencabutan access token (butuh restart).
+type RevocationConfig struct {
+	Persist         bool          `mapstructure:"PERSIST"`          // Simpan ke database agar bertahan setelah restart
+	CleanupInterval time.Duration `mapstructure:"CLEANUP_INTERVAL"` // Interval membuang entri yang sudah kedaluwarsa
+}
+
+// CORSConfig mengatur origin browser yang boleh memanggil gateway.
+type CORSConfig struct {
+	// AllowedOrigins, jika diisi, membatasi CORS ke origin ini dan mengizinkan
+	// credentials (cookie). Kosong berarti semua origin tanpa credentials.
+	AllowedOrigins []string `mapstructure:"ALLOWED_ORIGINS"`
+}
+
+// SessionConfig mengatur mode sesi browser: token disimpan di cookie HttpOnly
+// alih-alih dikembalikan di body, dan request yang diautentikasi dengan cookie
+// wajib membawa token CSRF di CSRF_HEADER untuk method yang mengubah data.
+type SessionConfig struct {
+	Enabled           bool   `mapstructure:"ENABLED"`
+	CookieName        string `mapstructure:"COOKIE_NAME"`         // Cookie access token, default gw_session
+	RefreshCookieName string `mapstructure:"REFRESH_COOKIE_NAME"` // Cookie refresh token (Path /auth), default gw_refresh
+	CSRFCookieName    string `mapstructure:"CSRF_COOKIE_NAME"`    // Cookie token CSRF yang bisa dibaca JavaScript, default gw_csrf
+	CSRFHeader        string `mapstructure:"CSRF_HEADER"`         // Default X-CSRF-Token
+	Domain            string `mapstructure:"DOMAIN"`              // Kosong = hanya host gateway
+	Secure            bool   `mapstructure:"SECURE"`              // Default true; matikan hanya untuk pengembangan lewat http
+	SameSite          string `mapstructure:"SAME_SITE"`           // Lax (default), Strict, atau None
+}
+
+// SameSiteMode mengembalikan SAME_SITE sebagai http.SameSite.
+func (s SessionConfig) SameSiteMode() http.SameSite {
+	switch strings.ToLower(s.SameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	}
+	return http.SameSiteLaxMode
+}
+
+// CookieNames mengembalikan nama semua cookie sesi, atau nil jika mode sesi
+// dimatikan.
+func (s SessionConfig) CookieNames() []string {
+	if !s.Enabled {
+		return nil
+	}
+	return []string{s.CookieName, s.RefreshCookieName, s.CSRFCookieName}
+}
+
+// IntrospectionConfig mengatur endpoint introspeksi token (RFC 7662). Pemanggil
+// hanya consumer API key atau OAuth client dengan access token
+// client_credentials; token pengguna dan cookie sesi ditolak.
+type IntrospectionConfig struct {
+	Enabled bool     `mapstructure:"ENABLED"`
+	Roles   []string `mapstructure:"ROLES"` // Consumer atau client wajib punya salah satu role ini; kosong = semua consumer dan client
+}
+
+// ClientCredentialsConfig mengatur grant OAuth2 client_credentials untuk
+// panggilan antar service. Client didaftarkan dengan "gateway clients ..." atau
+// admin API.
+type ClientCredentialsConfig struct {
+	Enabled     bool          `mapstructure:"ENABLED"`
+	TokenTTL    time.Duration `mapstructure:"TOKEN_TTL"`     // Masa berlaku token jika client tidak mengatur TTL sendiri
+	MaxTokenTTL time.Duration `mapstructure:"MAX_TOKEN_TTL"` // Batas atas TTL token client; pencabutan token client diingat selama ini
+}
+
+// OIDCConfig mengatur login OpenID Connect (authorization code flow dengan
+// PKCE). Gateway bertindak sebagai relying party: identitas dari IdP dipetakan
+// ke pengguna lokal, lalu gateway menerbitkan sesi atau token miliknya sendiri.
+type OIDCConfig struct {
+	Enabled           bool              `mapstructure:"ENABLED"`
+	Issuer            string            `mapstructure:"ISSUER"`              // Harus sama persis dengan klaim "iss" ID token
+	DiscoveryURL      string            `mapstructure:"DISCOVERY_URL"`       // Default ISSUER + /.well-known/openid-configuration
+	ClientID          string            `mapstructure:"CLIENT_ID"`           // Client gateway yang terdaftar di IdP
+	ClientSecret      string            `mapstructure:"CLIENT_SECRET"`       // Kosong untuk public client (hanya PKCE)
+	RedirectURL       string            `mapstructure:"REDIRECT_URL"`        // URL /auth/oidc/callback yang terdaftar di IdP
+	Scopes            []string          `mapstructure:"SCOPES"`              // Default [openid, profile, email]; openid selalu diminta
+	UsernameClaim     string            `mapstructure:"USERNAME_CLAIM"`      // Default preferred_username
+	RolesClaim        string            `mapstructure:"ROLES_CLAIM"`         // Klaim berisi role/grup IdP, default roles
+	RoleMapping       []OIDCRoleMapping `mapstructure:"ROLE_MAPPING"`        // Role IdP yang tidak dipetakan diabaikan
+	DefaultRoles      []string          `mapstructure:"DEFAULT_ROLES"`       // Role lokal untuk semua pengguna OIDC
+	SyncRoles         bool              `mapstructure:"SYNC_ROLES"`          // Timpa role lokal setiap login (default true)
+	AutoCreateUsers   bool              `mapstructure:"AUTO_CREATE_USERS"`   // Buat pengguna lokal saat login pertama (default true)
+	LinkExistingUsers bool              `mapstructure:"LINK_EXISTING_USERS"` // Tautkan ke pengguna lokal dengan username sama (default false)
+	PostLoginRedirect string            `mapstructure:"POST_LOGIN_REDIRECT"` // Tujuan setelah login mode sesi, default /
+	StateTTL          time.Duration     `mapstructure:"STATE_TTL"`           // Batas waktu menyelesaikan login di IdP, default 10m
+	ClockSkew         time.Duration     `mapstructure:"CLOCK_SKEW"`          // Toleransi exp/iat ID token, default 30s
+	MFAAMR            []string          `mapstructure:"MFA_AMR"`             // Nilai klaim amr yang berarti IdP memverifikasi MFA, default [mfa]
+	MFAACR            []string          `mapstructure:"MFA_ACR"`             // Nilai klaim acr yang berarti IdP memverifikasi MFA, default kosong
+}
+
+// OIDCRoleMapping memetakan satu role atau grup IdP ke role lokal.
+type OIDCRoleMapping struct {
+	IdPRole string   `mapstructure:"IDP_ROLE"`
+	Roles   []string `mapstructure:"ROLES"`
+}
+
+// DiscoveryDocumentURL mengembalikan URL dokumen discovery IdP.
+func (o OIDCConfig) DiscoveryDocumentURL() string {
+	if o.DiscoveryURL != "" {
+		return o.DiscoveryURL
+	}
+	return strings.TrimRight(o.Issuer, "/") + "/.well-known/openid-configuration"
+}
+
+// RequestedScopes mengembalikan SCOPES dengan openid di depan.
+func (o OIDCConfig) RequestedScopes() []string {
+	scopes := []string{"openid"}
+	for _, s := range o.Scopes {
+		if s != "openid" {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// MapRoles mengubah role IdP menjadi role lokal: DEFAULT_ROLES ditambah hasil
+// ROLE_MAPPING, tanpa duplikat.
+func (o OIDCConfig) MapRoles(idpRoles []string) []string {
+	roles := []string{}
+	add := func(list []string) {
+		for _, r := range list {
+			if !slices.Contains(roles, r) {
+				roles = append(roles, r)
+			}
+		}
+	}
+	add(o.DefaultRoles)
+	for _, m := range o.RoleMapping {
+		if slices.Contains(idpRoles, m.IdPRole) {
+			add(m.Roles)
+		}
+	}
+	return roles
+}
+
+// MFAConfig mengatur TOTP multi-factor authentication. Pengguna bisa
+// mendaftarkan MFA sendiri; untuk pengguna dengan salah satu REQUIRED_ROLES,
+// login tidak menghasilkan token sebelum MFA didaftarkan dan diverifikasi.
+type MFAConfig struct {
+	Issuer        string        `mapstructure:"ISSUER"`         // Nama yang tampil di aplikasi authenticator
+	RequiredRoles []string      `mapstructure:"REQUIRED_ROLES"` // Role yang wajib memakai MFA
+	ChallengeTTL  time.Duration `mapstructure:"CHALLENGE_TTL"`  // Masa berlaku mfa_token dari /auth/login
+	Skew          int           `mapstructure:"SKEW"`           // Toleransi langkah waktu (30 detik) ke depan/belakang
+	RecoveryCodes int           `mapstructure:"RECOVERY_CODES"` // Jumlah recovery code yang dibuat
+}
+
+// Required melaporkan apakah pengguna dengan roles wajib memakai MFA.
+func (m MFAConfig) Required(roles []string) bool {
+	for _, r := range roles {
+		if slices.Contains(m.RequiredRoles, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoginProtectionConfig mengatur perlindungan brute-force login (butuh
+// restart). Kegagalan dihitung per username (termasuk username yang tidak
+// terdaftar) dan per IP; setelah FREE_ATTEMPTS (per IP: IP_FREE_ATTEMPTS)
+// kegagalan, percobaan berikutnya harus menunggu BACKOFF_BASE yang berlipat dua tiap kegagalan hingga
+// BACKOFF_MAX. Username atau IP dikunci selama LOCKOUT_DURATION setelah
+// mencapai batas kegagalannya.
+type LoginProtectionConfig struct {
+	Enabled         bool          `mapstructure:"ENABLED"`
+	FreeAttempts    int           `mapstructure:"FREE_ATTEMPTS"`     // Kegagalan per username sebelum backoff berlaku
+	IPFreeAttempts  int           `mapstructure:"IP_FREE_ATTEMPTS"`  // Kegagalan per IP sebelum backoff berlaku
+	BackoffBase     time.Duration `mapstructure:"BACKOFF_BASE"`      // Jeda setelah kegagalan pertama yang kena backoff
+	BackoffMax      time.Duration `mapstructure:"BACKOFF_MAX"`       // Batas atas jeda backoff
+	UserMaxFailures int           `mapstructure:"USER_MAX_FAILURES"` // Kegagalan per username sebelum dikunci
+	IPMaxFailures   int           `mapstructure:"IP_MAX_FAILURES"`   // Kegagalan per IP sebelum dikunci
+	LockoutDuration time.Duration `mapstructure:"LOCKOUT_DURATION"`  // Lama penguncian
+	FailureTTL      time.Duration `mapstructure:"FAILURE_TTL"`       // Hitungan direset jika tidak ada kegagalan selama ini
+}
+
+// PasswordVerifyConfig membatasi jumlah verifikasi password yang berjalan
+// bersamaan (butuh restart). Satu verifikasi argon2id memakai 64 MiB memori,
+// jadi pemakaian memori puncak kira-kira MAX_CONCURRENT x 64 MiB. Login yang
+// tidak mendapat slot dalam MAX_WAIT ditolak dengan 503.
+type PasswordVerifyConfig struct {
+	MaxConcurrent int           `mapstructure:"MAX_CONCURRENT"` // Default 8
+	MaxWait       time.Duration `mapstructure:"MAX_WAIT"`       // Lama menunggu slot, default 1s; 0 = tidak menunggu
+}
+
+// Driver database yang didukung untuk DATABASE.DRIVER.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// DatabaseConfig mengatur koneksi database penyimpan pengguna. Perubahan pada
+// bagian ini baru berlaku setelah restart.
+type DatabaseConfig struct {
+	Driver          string        `mapstructure:"DRIVER"` // Kosong = ditebak dari DSN (postgres://, mysql://), selain itu sqlite
+	DSN             string        `mapstructure:"DSN"`    // Untuk sqlite berupa path file
+	MaxOpenConns    int           `mapstructure:"MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `mapstructure:"MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `mapstructure:"CONN_MAX_LIFETIME"`
+}
+
+// DriverName mengembalikan driver yang dipakai, menebak dari DSN jika DRIVER kosong.
+func (d DatabaseConfig) DriverName() string {
+	if d.Driver != "" {
+		return strings.ToLower(d.Driver)
+	}
+	switch {
+	case strings.HasPrefix(d.DSN, "postgres://"), strings.HasPrefix(d.DSN, "postgresql://"):
+		return DriverPostgres
+	case strings.HasPrefix(d.DSN, "mysql://"):
+		return DriverMySQL
+	}
+	return DriverSQLite
+}
+
+// RequestIDConfig mengatur request ID yang dipakai untuk mengkorelasikan log
+// gateway, log upstream, dan response ke klien.
+type RequestIDConfig struct {
+	Header        string `mapstructure:"HEADER"`         // Default X-Request-ID
+	Format        string `mapstructure:"FORMAT"`         // uuidv7 (default), uuidv4, atau hex
+	TrustIncoming bool   `mapstructure:"TRUST_INCOMING"` // Pakai ID dari klien jika formatnya aman
+}
+
+// ServerConfig mengatur timeout http.Server gateway. Perubahan pada bagian ini
+// baru berlaku setelah restart. Nilai 0 berarti tanpa batas.
+type ServerConfig struct {
+	ReadHeaderTimeout time.Duration `mapstructure:"READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `mapstructure:"READ_TIMEOUT"`
+	WriteTimeout      time.Duration `mapstructure:"WRITE_TIMEOUT"` // Sebaiknya lebih besar dari TIMEOUTS.TOTAL rute mana pun
+	IdleTimeout       time.Duration `mapstructure:"IDLE_TIMEOUT"`
+}
+
+// TransportConfig mengatur koneksi dari gateway ke upstream: timeout default
+// untuk semua rute dan pengaturan connection pool.
+type TransportConfig struct {
+	ConnectTimeout        time.Duration `mapstructure:"CONNECT_TIMEOUT"`
+	TLSHandshakeTimeout   time.Duration `mapstructure:"TLS_HANDSHAKE_TIMEOUT"`
+	ResponseHeaderTimeout time.Duration `mapstructure:"RESPONSE_HEADER_TIMEOUT"`
+	TotalTimeout          time.Duration `mapstructure:"TOTAL_TIMEOUT"` // Batas seluruh request termasuk body response
+	MaxIdleConns          int           `mapstructure:"MAX_IDLE_CONNS"`
+	MaxIdleConnsPerHost   int           `mapstructure:"MAX_IDLE_CONNS_PER_HOST"`
+	IdleConnTimeout       time.Duration `mapstructure:"IDLE_CONN_TIMEOUT"`
 }
 
 type RateLimitConfig struct {
@@ -20,25 +378,525 @@ type RateLimitConfig struct {
 	WindowSec int  `mapstructure:"WINDOW_SEC"`
 }
 
+// AdminConfig mengatur server admin terpisah (status reload, dll.).
+// Perubahan pada bagian ini baru berlaku setelah restart.
+type AdminConfig struct {
+	Enabled bool   `mapstructure:"ENABLED"`
+	Addr    string `mapstructure:"ADDR"`  // Sebaiknya hanya listen di loopback/jaringan internal
+	Token   string `mapstructure:"TOKEN"` // Bearer token untuk mengakses endpoint admin, wajib jika Enabled
+}
+
+// Strategi load balancing yang didukung untuk SERVICES.<nama>.STRATEGY.
+const (
+	StrategyRoundRobin       = "round_robin"
+	StrategyWeighted         = "weighted"
+	StrategyLeastConnections = "least_connections"
+	StrategyPowerOfTwo       = "p2c" // Random power-of-two-choices
+	StrategyConsistentHash   = "consistent_hash"
+)
+
+// ServiceConfig mendefinisikan service upstream dengan satu atau lebih instance.
+type ServiceConfig struct {
+	Strategy string         `mapstructure:"STRATEGY"` // Default round_robin
+	HashKey  string         `mapstructure:"HASH_KEY"` // "client_ip" atau "header:<Nama-Header>" untuk consistent_hash
+	Targets  []TargetConfig `mapstructure:"TARGETS"`
+
+	HealthCheck    HealthCheckConfig    `mapstructure:"HEALTH_CHECK"`
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"CIRCUIT_BREAKER"`
+}
+
+// CircuitBreakerConfig mengatur circuit breaker untuk service dan, jika service
+// punya lebih dari satu instance, untuk setiap instance. Circuit terbuka jika
+// terjadi CONSECUTIVE_FAILURES kegagalan berturut-turut atau jika rasio error
+// dalam WINDOW mencapai ERROR_RATE (dengan minimal MIN_REQUESTS request).
+type CircuitBreakerConfig struct {
+	Enabled             bool          `mapstructure:"ENABLED"`
+	ConsecutiveFailures int           `mapstructure:"CONSECUTIVE_FAILURES"` // Default 5
+	ErrorRate           float64       `mapstructure:"ERROR_RATE"`           // 0-1, 0 berarti tidak dipakai
+	MinRequests         int           `mapstructure:"MIN_REQUESTS"`         // Default 20
+	Window              time.Duration `mapstructure:"WINDOW"`               // Default 30s
+	OpenTimeout         time.Duration `mapstructure:"OPEN_TIMEOUT"`         // Lama circuit terbuka sebelum half-open, default 30s
+	HalfOpenRequests    int           `mapstructure:"HALF_OPEN_REQUESTS"`   // Request percobaan saat half-open, default 1
+}
+
+// WithDefaults mengisi nilai kosong dengan default.
+func (b CircuitBreakerConfig) WithDefaults() CircuitBreakerConfig {
+	if b.ConsecutiveFailures <= 0 {
+		b.ConsecutiveFailures = 5
+	}
+	if b.MinRequests <= 0 {
+		b.MinRequests = 20
+	}
+	if b.Window <= 0 {
+		b.Window = 30 * time.Second
+	}
+	if b.OpenTimeout <= 0 {
+		b.OpenTimeout = 30 * time.Second
+	}
+	if b.HalfOpenRequests <= 0 {
+		b.HalfOpenRequests = 1
+	}
+	return b
+}
+
+// HealthCheckConfig mengatur probe aktif ke setiap instance sebuah service.
+// Instance yang gagal UNHEALTHY_THRESHOLD kali berturut-turut dikeluarkan dari
+// rotasi sampai berhasil HEALTHY_THRESHOLD kali berturut-turut.
+type HealthCheckConfig struct {
+	Enabled            bool          `mapstructure:"ENABLED"`
+	Path               string        `mapstructure:"PATH"`     // Path absolut dari host instance, misal /health
+	Interval           time.Duration `mapstructure:"INTERVAL"` // Default 10s
+	Timeout            time.Duration `mapstructure:"TIMEOUT"`  // Default 2s
+	HealthyThreshold   int           `mapstructure:"HEALTHY_THRESHOLD"`
+	UnhealthyThreshold int           `mapstructure:"UNHEALTHY_THRESHOLD"`
+	ExpectedStatus     []int         `mapstructure:"EXPECTED_STATUS"` // Kosong berarti semua status 2xx
+}
+
+// WithDefaults mengisi nilai kosong dengan default.
+func (h HealthCheckConfig) WithDefaults() HealthCheckConfig {
+	if h.Path == "" {
+		h.Path = "/health"
+	}
+	if h.Interval <= 0 {
+		h.Interval = 10 * time.Second
+	}
+	if h.Timeout <= 0 {
+		h.Timeout = 2 * time.Second
+	}
+	if h.HealthyThreshold <= 0 {
+		h.HealthyThreshold = 2
+	}
+	if h.UnhealthyThreshold <= 0 {
+		h.UnhealthyThreshold = 3
+	}
+	return h
+}
+
+// TargetConfig adalah satu instance dari sebuah service.
+type TargetConfig struct {
+	URL    string `mapstructure:"URL"`
+	Weight int    `mapstructure:"WEIGHT"` // Default 1
+}
+
+// Service mengembalikan konfigurasi service berdasarkan nama. Entri di SERVICES
+// diutamakan; entri SERVICE_ENDPOINTS diperlakukan sebagai service satu instance.
+func (c Config) Service(name string) (ServiceConfig, bool) {
+	name = strings.ToLower(name) // Viper menyimpan kunci map dalam huruf kecil
+	if svc, ok := c.Services[name]; ok {
+		return svc, true
+	}
+	if target, ok := c.ServiceEndpoints[name]; ok && target != "" {
+		return ServiceConfig{
+			Strategy: StrategyRoundRobin,
+			Targets:  []TargetConfig{{URL: target, Weight: 1}},
+		}, true
+	}
+	return ServiceConfig{}, false
+}
+
+// RouteConfig mendefinisikan satu grup proxy: semua request di bawah PathPrefix
+// diteruskan ke service Upstream (kunci di SERVICES atau SERVICE_ENDPOINTS).
+type RouteConfig struct {
+	PathPrefix  string   `mapstructure:"PATH_PREFIX"`
+	Upstream    string   `mapstructure:"UPSTREAM"`
+	Methods     []string `mapstructure:"METHODS"`      // Kosong berarti semua method standar
+	AuthMethods []string `mapstructure:"AUTH_METHODS"` // Method yang butuh JWT, "*" untuk semua
+	StripPrefix bool     `mapstructure:"STRIP_PREFIX"` // Hapus PathPrefix sebelum diteruskan ke upstream
+
+	// AuthSchemes adalah kredensial yang diterima untuk AUTH_METHODS: "jwt",
+	// "api_key", atau keduanya. Default hanya JWT.
+	AuthSchemes []string `mapstructure:"AUTH_SCHEMES"`
+
+	Retry    RetryConfig    `mapstructure:"RETRY"`
+	Timeouts TimeoutsConfig `mapstructure:"TIMEOUTS"`
+
+	// IdentityHeaders, jika diisi, menggantikan IDENTITY_HEADERS global untuk rute ini.
+	IdentityHeaders *IdentityConfig `mapstructure:"IDENTITY_HEADERS"`
+
+	// Authorization adalah syarat role/scope tambahan untuk method yang butuh
+	// JWT. Semua aturan yang cocok dengan method request harus terpenuhi.
+	Authorization []AuthzRuleConfig `mapstructure:"AUTHORIZATION"`
+
+	// ExtAuthz, jika diisi, meminta keputusan ke policy service eksternal
+	// sebelum request diteruskan (setelah AUTHORIZATION).
+	ExtAuthz *ExtAuthzConfig `mapstructure:"EXT_AUTHZ"`
+}
+
+// ExtAuthzConfig mengatur callout otorisasi eksternal untuk satu rute. Gateway
+// mengirim metadata request dan klaim pemanggil sebagai JSON ke URL, lalu
+// meneruskan, menolak, atau menambahkan header sesuai jawabannya.
+type ExtAuthzConfig struct {
+	URL            string              `mapstructure:"URL"`             // Endpoint policy service (POST JSON)
+	Methods        []string            `mapstructure:"METHODS"`         // Method yang diperiksa; kosong = semua method rute
+	Timeout        time.Duration       `mapstructure:"TIMEOUT"`         // Batas waktu satu callout, default 500ms
+	FailOpen       bool                `mapstructure:"FAIL_OPEN"`       // Izinkan request jika policy service gagal; default false (tolak)
+	IncludeHeaders []string            `mapstructure:"INCLUDE_HEADERS"` // Header request yang dikirim; kosong = semua kecuali kredensial
+	Cache          ExtAuthzCacheConfig `mapstructure:"CACHE"`
+}
+
+// ExtAuthzCacheConfig mengatur cache keputusan ext_authz. Keputusan izinkan
+// dan tolak disimpan selama TTL; kegagalan callout tidak pernah disimpan.
+type ExtAuthzCacheConfig struct {
+	TTL        time.Duration `mapstructure:"TTL"`         // 0 = tanpa cache
+	Key        []string      `mapstructure:"KEY"`         // Atribut kunci cache, default [method, path, user_id]
+	MaxEntries int           `mapstructure:"MAX_ENTRIES"` // Default 10000
+}
+
+// Atribut yang bisa dipakai di EXT_AUTHZ.CACHE.KEY, selain "header:<Nama>" dan
+// "query:<nama>".
+const (
+	ExtAuthzKeyMethod   = "method"
+	ExtAuthzKeyPath     = "path"
+	ExtAuthzKeyUserID   = "user_id"
+	ExtAuthzKeyRoles    = "roles"
+	ExtAuthzKeyScope    = "scope"
+	ExtAuthzKeyClientID = "client_id"
+	ExtAuthzKeyClientIP = "client_ip"
+)
+
+// WithDefaults mengisi nilai kosong dengan default.
+func (e ExtAuthzConfig) WithDefaults() ExtAuthzConfig {
+	if e.Timeout <= 0 {
+		e.Timeout = 500 * time.Millisecond
+	}
+	if len(e.Cache.Key) == 0 {
+		e.Cache.Key = []string{ExtAuthzKeyMethod, ExtAuthzKeyPath, ExtAuthzKeyUserID}
+	}
+	if e.Cache.MaxEntries <= 0 {
+		e.Cache.MaxEntries = 10000
+	}
+	return e
+}
+
+// AppliesTo melaporkan apakah callout dijalankan untuk method tertentu.
+func (e ExtAuthzConfig) AppliesTo(method string) bool {
+	if len(e.Methods) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(e.Methods, func(m string) bool { return m == "*" || strings.EqualFold(m, method) })
+}
+
+// AuthzRuleConfig mensyaratkan role dan/atau scope untuk method tertentu.
+// Token harus memiliki minimal satu dari ROLES dan semua SCOPES.
+type AuthzRuleConfig struct {
+	Methods []string `mapstructure:"METHODS"` // "*" untuk semua method yang butuh JWT
+	Roles   []string `mapstructure:"ROLES"`   // Salah satu role ini
+	Scopes  []string `mapstructure:"SCOPES"`  // Semua scope ini
+}
+
+// AppliesTo melaporkan apakah aturan berlaku untuk method tertentu.
+func (a AuthzRuleConfig) AppliesTo(method string) bool {
+	for _, m := range a.Methods {
+		if m == "*" || strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// IdentityConfig mengatur header identitas pemanggil yang dikirim ke upstream
+// setelah autentikasi berhasil. Header yang sama dari klien selalu dihapus.
+type IdentityConfig struct {
+	Headers      map[string]string `mapstructure:"HEADERS"`       // Nama klaim -> nama header; nil = IdentityDefaultHeaders
+	SignedHeader string            `mapstructure:"SIGNED_HEADER"` // Header berisi JWT bertanda tangan gateway dengan semua klaim
+	SignedTTL    time.Duration     `mapstructure:"SIGNED_TTL"`    // Masa berlaku JWT di SIGNED_HEADER, default 60s
+}
+
+// Klaim yang bisa dipetakan ke header di IDENTITY_HEADERS.HEADERS.
+const (
+	IdentityClaimUserID   = "user_id"   // ID pengguna atau consumer API key
+	IdentityClaimSubject  = "sub"       // Sama dengan user_id
+	IdentityClaimUsername = "username"  // Username atau nama consumer
+	IdentityClaimRoles    = "roles"     // Dipisah koma
+	IdentityClaimScope    = "scope"     // Dipisah spasi
+	IdentityClaimIssuer   = "iss"       // Issuer token (kosong untuk API key)
+	IdentityClaimTokenID  = "jti"       // ID token (kosong untuk API key)
+	IdentityClaimClientID = "client_id" // OAuth client (kosong untuk pengguna dan API key)
+)
+
+// IdentityDefaultHeaders dipakai jika HEADERS tidak diisi sama sekali. Klaim
+// dengan nama header kosong tidak dikirim; karena Viper mengabaikan map kosong,
+// isi misalnya HEADERS: {user_id: ""} untuk tidak mengirim header per klaim.
+var IdentityDefaultHeaders = map[string]string{
+	IdentityClaimUserID:   "X-User-ID",
+	IdentityClaimUsername: "X-Username",
+	IdentityClaimRoles:    "X-User-Roles",
+}
+
+// WithDefaults mengisi nilai kosong dengan default.
+func (i IdentityConfig) WithDefaults() IdentityConfig {
+	if i.Headers == nil {
+		i.Headers = IdentityDefaultHeaders
+	}
+	if i.SignedTTL <= 0 {
+		i.SignedTTL = time.Minute
+	}
+	return i
+}
+
+// HeaderNames mengembalikan semua header yang diisi gateway, termasuk SIGNED_HEADER.
+func (i IdentityConfig) HeaderNames() []string {
+	var names []string
+	for _, h := range i.WithDefaults().Headers {
+		if h != "" {
+			names = append(names, http.CanonicalHeaderKey(h))
+		}
+	}
+	if i.SignedHeader != "" {
+		names = append(names, http.CanonicalHeaderKey(i.SignedHeader))
+	}
+	return names
+}
+
+// IdentityFor mengembalikan pengaturan header identitas untuk rute r.
+func (c Config) IdentityFor(r RouteConfig) IdentityConfig {
+	if r.IdentityHeaders != nil {
+		return r.IdentityHeaders.WithDefaults()
+	}
+	return c.IdentityHeaders.WithDefaults()
+}
+
+// IdentityHeaderNames mengembalikan gabungan semua header identitas dari
+// IDENTITY_HEADERS global dan setiap rute. Header ini dihapus dari setiap
+// request klien, termasuk di rute tanpa autentikasi, agar upstream tidak
+// menerima identitas palsu.
+func (c Config) IdentityHeaderNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	add := func(ic IdentityConfig) {
+		for _, h := range ic.HeaderNames() {
+			if !seen[h] {
+				seen[h] = true
+				names = append(names, h)
+			}
+		}
+	}
+	add(c.IdentityHeaders)
+	for _, r := range c.Routes {
+		if r.IdentityHeaders != nil {
+			add(*r.IdentityHeaders)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TimeoutsConfig mengatur timeout upstream untuk satu rute. Nilai 0 berarti
+// memakai nilai default dari UPSTREAM_TRANSPORT.
+type TimeoutsConfig struct {
+	Connect        time.Duration `mapstructure:"CONNECT"`         // Membuka koneksi TCP ke instance
+	ResponseHeader time.Duration `mapstructure:"RESPONSE_HEADER"` // Menunggu header response setelah request terkirim
+	Total          time.Duration `mapstructure:"TOTAL"`           // Seluruh request, termasuk semua retry dan body response
+}
+
+// WithDefaults mengisi nilai yang kosong dari pengaturan UPSTREAM_TRANSPORT.
+func (t TimeoutsConfig) WithDefaults(tr TransportConfig) TimeoutsConfig {
+	if t.Connect <= 0 {
+		t.Connect = tr.ConnectTimeout
+	}
+	if t.ResponseHeader <= 0 {
+		t.ResponseHeader = tr.ResponseHeaderTimeout
+	}
+	if t.Total <= 0 {
+		t.Total = tr.TotalTimeout
+	}
+	return t
+}
+
+// Jenis error koneksi yang bisa di-retry untuk RETRY.RETRY_ON_ERRORS.
+const (
+	RetryOnConnect = "connect" // Gagal membuka koneksi ke instance
+	RetryOnReset   = "reset"   // Koneksi terputus sebelum response diterima
+	RetryOnTimeout = "timeout" // PER_TRY_TIMEOUT terlampaui
+)
+
+// RetryConfig mengatur retry otomatis untuk satu rute. Retry hanya dilakukan
+// untuk method idempoten atau request yang membawa header Idempotency-Key,
+// dan jika service punya lebih dari satu instance, percobaan berikutnya
+// diarahkan ke instance lain.
+type RetryConfig struct {
+	MaxAttempts     int           `mapstructure:"MAX_ATTEMPTS"`     // Termasuk percobaan pertama; <= 1 berarti tanpa retry
+	PerTryTimeout   time.Duration `mapstructure:"PER_TRY_TIMEOUT"`  // Batas waktu sampai header response diterima, 0 = tanpa batas
+	Backoff         time.Duration `mapstructure:"BACKOFF"`          // Backoff dasar, default 50ms
+	MaxBackoff      time.Duration `mapstructure:"MAX_BACKOFF"`      // Default 1s
+	RetryableStatus []int         `mapstructure:"RETRYABLE_STATUS"` // Default [502, 503, 504]
+	RetryOnErrors   []string      `mapstructure:"RETRY_ON_ERRORS"`  // connect, reset, timeout; default semua
+	MaxBodyBytes    int64         `mapstructure:"MAX_BODY_BYTES"`   // Body lebih besar tidak di-retry, default 1 MiB
+}
+
+// MinWindow adalah WINDOW terpendek untuk RETRY_BUDGET dan CIRCUIT_BREAKER.
+// Rolling window dibagi 10 bucket; bucket di bawah 1ms tidak berguna dan
+// bucket 0ns membuat pembagian dengan nol.
+const MinWindow = 10 * time.Millisecond
+
+// RetryBudgetConfig membatasi jumlah retry secara global agar retry tidak
+// memperparah beban saat upstream bermasalah: dalam WINDOW, jumlah retry tidak
+// boleh melebihi RATIO x jumlah request ditambah MIN_RETRIES.
+type RetryBudgetConfig struct {
+	Ratio      float64       `mapstructure:"RATIO"`
+	MinRetries int           `mapstructure:"MIN_RETRIES"`
+	Window     time.Duration `mapstructure:"WINDOW"`
+}
+
+// DefaultMethods adalah method yang diteruskan jika METHODS tidak diisi.
+var DefaultMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// AllowedMethods mengembalikan method yang diteruskan oleh rute ini.
+func (r RouteConfig) AllowedMethods() []string {
+	if len(r.Methods) == 0 {
+		return DefaultMethods
+	}
+	methods := make([]string, 0, len(r.Methods))
+	for _, m := range r.Methods {
+		methods = append(methods, strings.ToUpper(m))
+	}
+	return methods
+}
+
+// RequiresAuth melaporkan apakah method tertentu pada rute ini butuh token JWT.
+func (r RouteConfig) RequiresAuth(method string) bool {
+	for _, m := range r.AuthMethods {
+		if m == "*" || strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// Skema autentikasi untuk ROUTES[].AUTH_SCHEMES.
+const (
+	AuthSchemeJWT    = "jwt"
+	AuthSchemeAPIKey = "api_key"
+)
+
+// AcceptedAuthSchemes mengembalikan skema autentikasi yang diterima rute ini.
+func (r RouteConfig) AcceptedAuthSchemes() []string {
+	if len(r.AuthSchemes) == 0 {
+		return []string{AuthSchemeJWT}
+	}
+	schemes := make([]string, 0, len(r.AuthSchemes))
+	for _, s := range r.AuthSchemes {
+		schemes = append(schemes, strings.ToLower(s))
+	}
+	return schemes
+}
+
+// AuthorizationFor mengembalikan aturan AUTHORIZATION yang berlaku untuk method.
+func (r RouteConfig) AuthorizationFor(method string) []AuthzRuleConfig {
+	var rules []AuthzRuleConfig
+	for _, rule := range r.Authorization {
+		if rule.AppliesTo(method) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
 // LoadConfig membaca konfigurasi dari file atau variabel environment.
+// Setiap pemanggilan memakai instance Viper baru sehingga aman dipanggil ulang
+// saat reload tanpa membawa sisa state dari pembacaan sebelumnya.
 func LoadConfig(path string) (config Config, err error) {
-	viper.AddConfigPath(path)     // Path ke direktori tempat file config berada (root proyek)
-	viper.SetConfigName("config") // Nama file config (tanpa ekstensi)
-	viper.SetConfigType("yaml")   // Tipe file config
+	v := viper.New()
+	v.AddConfigPath(path)     // Path ke direktori tempat file config berada (root proyek)
+	v.SetConfigName("config") // Nama file config (tanpa ekstensi)
+	v.SetConfigType("yaml")   // Tipe file config
 
-	viper.AutomaticEnv() // Baca variabel environment yang cocok
+	v.AutomaticEnv() // Baca variabel environment yang cocok
 
 	// Set default values
-	viper.SetDefault("SERVER_PORT", "8080")
-	viper.SetDefault("APP_ENV", "development")
-	viper.SetDefault("AUTH_SECRET", "your-default-secret-key") // Ganti ini di produksi
-	viper.SetDefault("RATE_LIMIT.ENABLED", true)
-	viper.SetDefault("RATE_LIMIT.REQUESTS", 100)  // 100 requests
-	viper.SetDefault("RATE_LIMIT.WINDOW_SEC", 60) // per 60 detik (1 menit)
-	viper.SetDefault("SERVICE_ENDPOINTS.user_service", "http://localhost:8081")
-	viper.SetDefault("SERVICE_ENDPOINTS.product_service", "http://localhost:8082")
-
-	err = viper.ReadInConfig()
+	v.SetDefault("SERVER_PORT", "8080")
+	v.SetDefault("APP_ENV", "development")
+	v.SetDefault("AUTH_SECRET", "your-default-secret-key") // Ganti ini di produksi
+	v.SetDefault("RATE_LIMIT.ENABLED", true)
+	v.SetDefault("RATE_LIMIT.REQUESTS", 100)  // 100 requests
+	v.SetDefault("RATE_LIMIT.WINDOW_SEC", 60) // per 60 detik (1 menit)
+	v.SetDefault("RETRY_BUDGET.RATIO", 0.2)
+	v.SetDefault("RETRY_BUDGET.MIN_RETRIES", 10)
+	v.SetDefault("RETRY_BUDGET.WINDOW", "10s")
+	v.SetDefault("AUTH.PASSWORD_HASH", "argon2id")
+	v.SetDefault("AUTH.ACCESS_TOKEN_TTL", "15m")
+	v.SetDefault("AUTH.REFRESH_TOKEN_TTL", "720h")
+	v.SetDefault("AUTH.REVOCATION.PERSIST", true)
+	v.SetDefault("AUTH.REVOCATION.CLEANUP_INTERVAL", "1m")
+	v.SetDefault("AUTH.API_KEYS.HEADER", "X-API-Key")
+	v.SetDefault("AUTH.INTROSPECTION.ENABLED", true)
+	v.SetDefault("AUTH.INTROSPECTION.ROLES", []string{"introspect"})
+	v.SetDefault("AUTH.CLIENT_CREDENTIALS.ENABLED", true)
+	v.SetDefault("AUTH.CLIENT_CREDENTIALS.TOKEN_TTL", "1h")
+	v.SetDefault("AUTH.CLIENT_CREDENTIALS.MAX_TOKEN_TTL", "24h")
+	v.SetDefault("AUTH.OIDC.ENABLED", false)
+	v.SetDefault("AUTH.OIDC.SCOPES", []string{"openid", "profile", "email"})
+	v.SetDefault("AUTH.OIDC.USERNAME_CLAIM", "preferred_username")
+	v.SetDefault("AUTH.OIDC.ROLES_CLAIM", "roles")
+	v.SetDefault("AUTH.OIDC.SYNC_ROLES", true)
+	v.SetDefault("AUTH.OIDC.AUTO_CREATE_USERS", true)
+	v.SetDefault("AUTH.OIDC.LINK_EXISTING_USERS", false)
+	v.SetDefault("AUTH.OIDC.POST_LOGIN_REDIRECT", "/")
+	v.SetDefault("AUTH.OIDC.STATE_TTL", "10m")
+	v.SetDefault("AUTH.OIDC.CLOCK_SKEW", "30s")
+	v.SetDefault("AUTH.OIDC.MFA_AMR", []string{"mfa"})
+	v.SetDefault("AUTH.MFA.ISSUER", "API Gateway")
+	v.SetDefault("AUTH.MFA.CHALLENGE_TTL", "5m")
+	v.SetDefault("AUTH.MFA.SKEW", 1)
+	v.SetDefault("AUTH.MFA.RECOVERY_CODES", 10)
+	v.SetDefault("AUTH.SESSION.ENABLED", false)
+	v.SetDefault("AUTH.SESSION.COOKIE_NAME", "gw_session")
+	v.SetDefault("AUTH.SESSION.REFRESH_COOKIE_NAME", "gw_refresh")
+	v.SetDefault("AUTH.SESSION.CSRF_COOKIE_NAME", "gw_csrf")
+	v.SetDefault("AUTH.SESSION.CSRF_HEADER", "X-CSRF-Token")
+	v.SetDefault("AUTH.SESSION.SECURE", true)
+	v.SetDefault("AUTH.SESSION.SAME_SITE", "Lax")
+	v.SetDefault("AUTH.LOGIN_PROTECTION.ENABLED", true)
+	v.SetDefault("AUTH.LOGIN_PROTECTION.FREE_ATTEMPTS", 3)
+	v.SetDefault("AUTH.LOGIN_PROTECTION.IP_FREE_ATTEMPTS", 10)
+	v.SetDefault("AUTH.LOGIN_PROTECTION.BACKOFF_BASE", "1s")
+	v.SetDefault("AUTH.LOGIN_PROTECTION.BACKOFF_MAX", "1m")
+	v.SetDefault("AUTH.LOGIN_PROTECTION.USER_MAX_FAILURES", 10)
+	v.SetDefault("AUTH.LOGIN_PROTECTION.IP_MAX_FAILURES", 50)
+	v.SetDefault("AUTH.LOGIN_PROTECTION.LOCKOUT_DURATION", "15m")
+	v.SetDefault("AUTH.LOGIN_PROTECTION.FAILURE_TTL", "1h")
+	v.SetDefault("AUTH.PASSWORD_VERIFY.MAX_CONCURRENT", 8)
+	v.SetDefault("AUTH.PASSWORD_VERIFY.MAX_WAIT", "1s")
+	v.SetDefault("DATABASE.DSN", "gateway.db")
+	v.SetDefault("DATABASE.MAX_OPEN_CONNS", 10)
+	v.SetDefault("DATABASE.MAX_IDLE_CONNS", 5)
+	v.SetDefault("DATABASE.CONN_MAX_LIFETIME", "30m")
+	v.SetDefault("REQUEST_ID.HEADER", "X-Request-ID")
+	v.SetDefault("REQUEST_ID.FORMAT", "uuidv7")
+	v.SetDefault("REQUEST_ID.TRUST_INCOMING", true)
+	v.SetDefault("SERVER.READ_HEADER_TIMEOUT", "10s")
+	v.SetDefault("SERVER.READ_TIMEOUT", "60s")
+	v.SetDefault("SERVER.WRITE_TIMEOUT", "90s")
+	v.SetDefault("SERVER.IDLE_TIMEOUT", "120s")
+	v.SetDefault("UPSTREAM_TRANSPORT.CONNECT_TIMEOUT", "5s")
+	v.SetDefault("UPSTREAM_TRANSPORT.TLS_HANDSHAKE_TIMEOUT", "5s")
+	v.SetDefault("UPSTREAM_TRANSPORT.RESPONSE_HEADER_TIMEOUT", "30s")
+	v.SetDefault("UPSTREAM_TRANSPORT.TOTAL_TIMEOUT", "60s")
+	v.SetDefault("UPSTREAM_TRANSPORT.MAX_IDLE_CONNS", 100)
+	v.SetDefault("UPSTREAM_TRANSPORT.MAX_IDLE_CONNS_PER_HOST", 32)
+	v.SetDefault("UPSTREAM_TRANSPORT.IDLE_CONN_TIMEOUT", "90s")
+	v.SetDefault("ADMIN.ENABLED", false) // Butuh ADMIN.TOKEN
+	v.SetDefault("ADMIN.ADDR", "127.0.0.1:9090")
+	v.SetDefault("SERVICE_ENDPOINTS.user_service", "http://localhost:8081/api/users")
+	v.SetDefault("SERVICE_ENDPOINTS.product_service", "http://localhost:8082/api/products")
+	// Rute default sama dengan perilaku gateway sebelum ROUTES bisa dikonfigurasi
+	v.SetDefault("ROUTES", []map[string]interface{}{
+		{
+			"PATH_PREFIX":  "/api/v1/users",
+			"UPSTREAM":     "user_service",
+			"AUTH_METHODS": []string{"*"},
+			"STRIP_PREFIX": true,
+		},
+		{
+			"PATH_PREFIX":  "/api/v1/products",
+			"UPSTREAM":     "product_service",
+			"METHODS":      []string{"GET", "POST", "PUT", "DELETE"},
+			"AUTH_METHODS": []string{"POST", "PUT", "DELETE"},
+			"STRIP_PREFIX": true,
+		},
+	})
+
+	err = v.ReadInConfig()
 	if err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			// Config file ditemukan tapi ada error lain saat parsing
@@ -47,6 +905,11 @@ func LoadConfig(path string) (config Config, err error) {
 		// Config file tidak ditemukan, tidak apa-apa, akan menggunakan default atau env var
 	}
 
-	err = viper.Unmarshal(&config)
+	err = v.Unmarshal(&config)
+	if err != nil {
+		return
+	}
+
+	err = config.Validate()
 	return
 }
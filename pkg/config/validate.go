@@ -0,0 +1,625 @@
+// pkg/config/validate.go
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// reservedPrefixes adalah path yang sudah dipakai oleh rute bawaan gateway
+// sehingga tidak boleh diambil alih oleh entri ROUTES.
+var reservedPrefixes = []string{"/auth", "/oauth", "/api/public"}
+
+var validMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodOptions: true,
+}
+
+// ValidationError berisi semua masalah yang ditemukan saat memvalidasi
+// konfigurasi, sehingga operator bisa memperbaiki semuanya sekaligus.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "konfigurasi tidak valid:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate memeriksa konfigurasi dan menormalkan nilai yang perlu dinormalkan
+// (misalnya trailing slash pada PATH_PREFIX).
+func (c *Config) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	c.validateServices(addf)
+
+	for _, p := range c.TrustedProxies {
+		if _, err := ParseTrustedProxy(p); err != nil {
+			addf("TRUSTED_PROXIES: %q bukan alamat IP atau CIDR yang valid", p)
+		}
+	}
+
+	if c.RetryBudget.Ratio < 0 || c.RetryBudget.Window < MinWindow {
+		addf("RETRY_BUDGET: RATIO tidak boleh negatif dan WINDOW minimal %s", MinWindow)
+	}
+
+	switch c.Auth.PasswordHash {
+	case "", "argon2id", "bcrypt":
+	default:
+		addf("AUTH: PASSWORD_HASH %q tidak dikenal (argon2id, bcrypt)", c.Auth.PasswordHash)
+	}
+	if c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL <= 0 {
+		addf("AUTH: ACCESS_TOKEN_TTL dan REFRESH_TOKEN_TTL harus lebih dari 0")
+	} else if c.Auth.RefreshTokenTTL < c.Auth.AccessTokenTTL {
+		addf("AUTH: REFRESH_TOKEN_TTL tidak boleh lebih pendek dari ACCESS_TOKEN_TTL")
+	}
+	if c.Auth.Revocation.CleanupInterval <= 0 {
+		addf("AUTH.REVOCATION: CLEANUP_INTERVAL harus lebih dari 0")
+	}
+	if c.Auth.APIKeys.Header == "" && c.Auth.APIKeys.QueryParam == "" {
+		addf("AUTH.API_KEYS: isi HEADER dan/atau QUERY_PARAM")
+	}
+	if m := c.Auth.MFA; m.Issuer == "" || strings.Contains(m.Issuer, ":") {
+		addf("AUTH.MFA: ISSUER wajib diisi dan tidak boleh mengandung ':'")
+	} else if m.ChallengeTTL <= 0 || m.Skew < 0 || m.Skew > 10 || m.RecoveryCodes <= 0 {
+		addf("AUTH.MFA: CHALLENGE_TTL dan RECOVERY_CODES harus lebih dari 0, SKEW antara 0 dan 10")
+	}
+	if cc := c.Auth.ClientCredentials; cc.Enabled && (cc.TokenTTL <= 0 || cc.MaxTokenTTL < cc.TokenTTL) {
+		addf("AUTH.CLIENT_CREDENTIALS: TOKEN_TTL harus lebih dari 0 dan tidak lebih dari MAX_TOKEN_TTL")
+	}
+	c.validateSession(addf)
+	c.validateOIDC(addf)
+	if lp := c.Auth.LoginProtection; lp.Enabled {
+		if lp.FreeAttempts < 0 || lp.IPFreeAttempts < 0 || lp.UserMaxFailures <= 0 || lp.IPMaxFailures <= 0 {
+			addf("AUTH.LOGIN_PROTECTION: USER_MAX_FAILURES dan IP_MAX_FAILURES harus lebih dari 0, FREE_ATTEMPTS dan IP_FREE_ATTEMPTS tidak boleh negatif")
+		}
+		if lp.BackoffBase <= 0 || lp.BackoffMax < lp.BackoffBase {
+			addf("AUTH.LOGIN_PROTECTION: BACKOFF_BASE harus lebih dari 0 dan tidak melebihi BACKOFF_MAX")
+		}
+		if lp.LockoutDuration <= 0 || lp.FailureTTL <= 0 {
+			addf("AUTH.LOGIN_PROTECTION: LOCKOUT_DURATION dan FAILURE_TTL harus lebih dari 0")
+		}
+	}
+	if pv := c.Auth.PasswordVerify; pv.MaxConcurrent <= 0 || pv.MaxWait < 0 {
+		addf("AUTH.PASSWORD_VERIFY: MAX_CONCURRENT harus lebih dari 0 dan MAX_WAIT tidak boleh negatif")
+	}
+	if c.Admin.Enabled && c.Admin.Token == "" {
+		addf("ADMIN: TOKEN wajib diisi jika ENABLED, endpoint admin bisa mencabut token dan mengubah pengguna")
+	}
+	c.validateSigning(addf)
+	c.validateTrustedIssuers(addf)
+	switch c.Database.DriverName() {
+	case DriverSQLite, DriverPostgres, DriverMySQL:
+	default:
+		addf("DATABASE: DRIVER %q tidak didukung (sqlite, postgres, mysql)", c.Database.Driver)
+	}
+	if c.Database.DSN == "" {
+		addf("DATABASE: DSN wajib diisi")
+	}
+
+	switch c.RequestID.Format {
+	case "", "uuidv7", "uuidv4", "hex":
+	default:
+		addf("REQUEST_ID: FORMAT %q tidak dikenal (uuidv7, uuidv4, hex)", c.RequestID.Format)
+	}
+	if c.RequestID.Header != "" && !validHeaderName(c.RequestID.Header) {
+		addf("REQUEST_ID: HEADER %q bukan nama header yang valid", c.RequestID.Header)
+	}
+
+	validateIdentity("IDENTITY_HEADERS", c.IdentityHeaders, c.RequestID.Header, addf)
+
+	if c.Transport.ConnectTimeout < 0 || c.Transport.ResponseHeaderTimeout < 0 || c.Transport.TotalTimeout < 0 {
+		addf("UPSTREAM_TRANSPORT: timeout tidak boleh negatif")
+	}
+	if c.Transport.MaxIdleConns < 0 || c.Transport.MaxIdleConnsPerHost < 0 {
+		addf("UPSTREAM_TRANSPORT: MAX_IDLE_CONNS dan MAX_IDLE_CONNS_PER_HOST tidak boleh negatif")
+	}
+
+	if len(c.Routes) == 0 {
+		addf("ROUTES: minimal satu rute harus dikonfigurasi")
+	}
+
+	for i := range c.Routes {
+		r := &c.Routes[i]
+		label := fmt.Sprintf("ROUTES[%d]", i)
+		if r.PathPrefix != "" {
+			label = fmt.Sprintf("ROUTES[%d] (%s)", i, r.PathPrefix)
+		}
+
+		// Path prefix
+		r.PathPrefix = strings.TrimRight(r.PathPrefix, "/")
+		switch {
+		case r.PathPrefix == "":
+			addf("%s: PATH_PREFIX wajib diisi dan tidak boleh \"/\"", label)
+		case !strings.HasPrefix(r.PathPrefix, "/"):
+			addf("%s: PATH_PREFIX harus diawali \"/\"", label)
+		case strings.ContainsAny(r.PathPrefix, ":*?#"):
+			addf("%s: PATH_PREFIX tidak boleh mengandung parameter atau wildcard", label)
+		}
+
+		// Upstream. Service di SERVICES sudah divalidasi oleh validateServices,
+		// entri SERVICE_ENDPOINTS hanya divalidasi jika dipakai oleh rute.
+		if r.Upstream == "" {
+			addf("%s: UPSTREAM wajib diisi", label)
+		} else if svc, ok := c.Service(r.Upstream); !ok {
+			addf("%s: UPSTREAM %q tidak ada di SERVICES atau SERVICE_ENDPOINTS", label, r.Upstream)
+		} else if _, inServices := c.Services[strings.ToLower(r.Upstream)]; !inServices && !validTargetURL(svc.Targets[0].URL) {
+			addf("%s: URL untuk UPSTREAM %q tidak valid: %q", label, r.Upstream, svc.Targets[0].URL)
+		}
+
+		// Methods
+		allowed := make(map[string]bool)
+		for _, m := range r.AllowedMethods() {
+			if !validMethods[m] {
+				addf("%s: method %q tidak didukung", label, m)
+			}
+			allowed[m] = true
+		}
+		for _, m := range r.AuthMethods {
+			if m != "*" && !allowed[strings.ToUpper(m)] {
+				addf("%s: AUTH_METHODS berisi %q yang tidak ada di METHODS", label, m)
+			}
+		}
+		for _, scheme := range r.AcceptedAuthSchemes() {
+			if scheme != AuthSchemeJWT && scheme != AuthSchemeAPIKey {
+				addf("%s: AUTH_SCHEMES %q tidak dikenal (jwt atau api_key)", label, scheme)
+			}
+		}
+		if r.IdentityHeaders != nil {
+			validateIdentity(label+".IDENTITY_HEADERS", *r.IdentityHeaders, c.RequestID.Header, addf)
+		}
+		if r.ExtAuthz != nil {
+			validateExtAuthz(label+".EXT_AUTHZ", *r.ExtAuthz, allowed, addf)
+		}
+		for j, rule := range r.Authorization {
+			ruleLabel := fmt.Sprintf("%s.AUTHORIZATION[%d]", label, j)
+			if len(rule.Methods) == 0 {
+				addf("%s: METHODS wajib diisi", ruleLabel)
+			}
+			if len(rule.Roles) == 0 && len(rule.Scopes) == 0 {
+				addf("%s: isi ROLES dan/atau SCOPES", ruleLabel)
+			}
+			for _, m := range rule.Methods {
+				if m != "*" && !r.RequiresAuth(m) {
+					addf("%s: method %q tidak ada di AUTH_METHODS sehingga token tidak diperiksa", ruleLabel, m)
+				}
+			}
+		}
+
+		// Retry
+		for _, code := range r.Retry.RetryableStatus {
+			if code < 400 || code > 599 {
+				addf("%s: RETRY.RETRYABLE_STATUS %d harus status 4xx/5xx", label, code)
+			}
+		}
+		for _, e := range r.Retry.RetryOnErrors {
+			if e != RetryOnConnect && e != RetryOnReset && e != RetryOnTimeout {
+				addf("%s: RETRY.RETRY_ON_ERRORS %q tidak dikenal", label, e)
+			}
+		}
+
+		// Timeout
+		if r.Timeouts.Connect < 0 || r.Timeouts.ResponseHeader < 0 || r.Timeouts.Total < 0 {
+			addf("%s: TIMEOUTS tidak boleh negatif", label)
+		}
+		timeouts := r.Timeouts.WithDefaults(c.Transport)
+		if c.Server.WriteTimeout > 0 && timeouts.Total > c.Server.WriteTimeout {
+			addf("%s: TIMEOUTS.TOTAL (%v) melebihi SERVER.WRITE_TIMEOUT (%v)", label, timeouts.Total, c.Server.WriteTimeout)
+		}
+		if r.Retry.PerTryTimeout > 0 && timeouts.Total > 0 && r.Retry.PerTryTimeout > timeouts.Total {
+			addf("%s: RETRY.PER_TRY_TIMEOUT melebihi TIMEOUTS.TOTAL", label)
+		}
+
+		// Konflik dengan rute bawaan dan rute lain. Gin tidak mengizinkan
+		// catch-all yang saling bertumpuk, jadi prefix bersarang juga konflik.
+		if !strings.HasPrefix(r.PathPrefix, "/") {
+			continue
+		}
+		for _, reserved := range reservedPrefixes {
+			if pathOverlaps(r.PathPrefix, reserved) {
+				addf("%s: bertabrakan dengan rute bawaan %s", label, reserved)
+			}
+		}
+		for j := 0; j < i; j++ {
+			prev := c.Routes[j].PathPrefix
+			if prev == r.PathPrefix {
+				addf("%s: PATH_PREFIX duplikat dengan ROUTES[%d]", label, j)
+			} else if strings.HasPrefix(prev, "/") && pathOverlaps(prev, r.PathPrefix) {
+				addf("%s: bertumpuk dengan ROUTES[%d] (%s)", label, j, prev)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// validateExtAuthz memeriksa EXT_AUTHZ satu rute.
+func validateExtAuthz(label string, e ExtAuthzConfig, allowed map[string]bool, addf func(format string, args ...interface{})) {
+	if !validTargetURL(e.URL) {
+		addf("%s: URL tidak valid: %q", label, e.URL)
+	}
+	for _, m := range e.Methods {
+		if m != "*" && !allowed[strings.ToUpper(m)] {
+			addf("%s: METHODS berisi %q yang tidak ada di METHODS rute", label, m)
+		}
+	}
+	if e.Timeout < 0 || e.Cache.TTL < 0 || e.Cache.MaxEntries < 0 {
+		addf("%s: TIMEOUT, CACHE.TTL, dan CACHE.MAX_ENTRIES tidak boleh negatif", label)
+	}
+	for _, h := range e.IncludeHeaders {
+		if !validHeaderName(h) {
+			addf("%s: INCLUDE_HEADERS %q bukan nama header yang valid", label, h)
+		}
+	}
+	for _, attr := range e.Cache.Key {
+		switch {
+		case attr == ExtAuthzKeyMethod, attr == ExtAuthzKeyPath, attr == ExtAuthzKeyUserID, attr == ExtAuthzKeyRoles,
+			attr == ExtAuthzKeyScope, attr == ExtAuthzKeyClientID, attr == ExtAuthzKeyClientIP:
+		case strings.HasPrefix(attr, "header:") && validHeaderName(strings.TrimPrefix(attr, "header:")):
+		case strings.HasPrefix(attr, "query:") && len(attr) > len("query:"):
+		default:
+			addf("%s: CACHE.KEY berisi atribut tidak dikenal %q", label, attr)
+		}
+	}
+}
+
+// validateServices memeriksa setiap entri SERVICES. Nama yang juga ada di
+// SERVICE_ENDPOINTS diperbolehkan; entri SERVICES yang dipakai.
+func (c *Config) validateServices(addf func(format string, args ...interface{})) {
+	names := make([]string, 0, len(c.Services))
+	for name := range c.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		svc := c.Services[name]
+		label := fmt.Sprintf("SERVICES.%s", name)
+		switch svc.Strategy {
+		case "", StrategyRoundRobin, StrategyWeighted, StrategyLeastConnections, StrategyPowerOfTwo:
+		case StrategyConsistentHash:
+			if svc.HashKey != "client_ip" && !strings.HasPrefix(svc.HashKey, "header:") {
+				addf("%s: HASH_KEY harus \"client_ip\" atau \"header:<Nama-Header>\"", label)
+			}
+		default:
+			addf("%s: STRATEGY %q tidak dikenal", label, svc.Strategy)
+		}
+
+		if len(svc.Targets) == 0 {
+			addf("%s: TARGETS minimal berisi satu instance", label)
+		}
+		if hc := svc.HealthCheck; hc.Enabled {
+			if hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+				addf("%s.HEALTH_CHECK: PATH harus diawali \"/\"", label)
+			}
+			if hc := hc.WithDefaults(); hc.Timeout >= hc.Interval {
+				addf("%s.HEALTH_CHECK: TIMEOUT harus lebih kecil dari INTERVAL", label)
+			}
+			for _, code := range hc.ExpectedStatus {
+				if code < 100 || code > 599 {
+					addf("%s.HEALTH_CHECK: EXPECTED_STATUS %d tidak valid", label, code)
+				}
+			}
+		}
+
+		if cb := svc.CircuitBreaker; cb.Enabled {
+			if cb.ErrorRate < 0 || cb.ErrorRate > 1 {
+				addf("%s.CIRCUIT_BREAKER: ERROR_RATE harus antara 0 dan 1", label)
+			}
+			if cb.Window != 0 && cb.Window < MinWindow {
+				addf("%s.CIRCUIT_BREAKER: WINDOW minimal %s (kosong = 30s)", label, MinWindow)
+			}
+		}
+
+		for i, t := range svc.Targets {
+			if !validTargetURL(t.URL) {
+				addf("%s.TARGETS[%d]: URL tidak valid: %q", label, i, t.URL)
+			}
+			if t.Weight < 0 {
+				addf("%s.TARGETS[%d]: WEIGHT tidak boleh negatif", label, i)
+			}
+		}
+	}
+}
+
+// ParseTrustedProxy mengurai satu entri TRUSTED_PROXIES. Alamat IP tanpa
+// panjang prefix dianggap sebagai satu host (/32 atau /128).
+func ParseTrustedProxy(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// identityClaims adalah klaim yang bisa dipetakan di IDENTITY_HEADERS.HEADERS.
+var identityClaims = map[string]bool{
+	IdentityClaimUserID:   true,
+	IdentityClaimSubject:  true,
+	IdentityClaimUsername: true,
+	IdentityClaimRoles:    true,
+	IdentityClaimScope:    true,
+	IdentityClaimIssuer:   true,
+	IdentityClaimTokenID:  true,
+	IdentityClaimClientID: true,
+}
+
+// reservedIdentityHeaders tidak boleh dipakai sebagai header identitas karena
+// sudah punya arti sendiri bagi HTTP atau gateway.
+var reservedIdentityHeaders = map[string]bool{
+	"Authorization":     true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Cookie":            true,
+	"Host":              true,
+	"Transfer-Encoding": true,
+	"X-Forwarded-For":   true,
+	"X-Forwarded-Host":  true,
+	"X-Forwarded-Proto": true,
+}
+
+// validateIdentity memeriksa satu blok IDENTITY_HEADERS.
+func validateIdentity(label string, ic IdentityConfig, requestIDHeader string, addf func(format string, args ...interface{})) {
+	check := func(field, header string) {
+		canonical := http.CanonicalHeaderKey(header)
+		switch {
+		case !validHeaderName(header):
+			addf("%s: %s %q bukan nama header yang valid", label, field, header)
+		case reservedIdentityHeaders[canonical] || canonical == http.CanonicalHeaderKey(requestIDHeader):
+			addf("%s: %s tidak boleh memakai header %s", label, field, canonical)
+		}
+	}
+	seen := make(map[string]string)
+	for claim, header := range ic.Headers {
+		if !identityClaims[claim] {
+			addf("%s: klaim %q tidak dikenal (user_id, sub, username, roles, scope, iss, jti)", label, claim)
+		}
+		if header == "" {
+			continue
+		}
+		check("HEADERS."+claim, header)
+		if prev, ok := seen[http.CanonicalHeaderKey(header)]; ok {
+			addf("%s: header %s dipakai untuk klaim %s dan %s", label, header, prev, claim)
+		}
+		seen[http.CanonicalHeaderKey(header)] = claim
+	}
+	if ic.SignedHeader != "" {
+		check("SIGNED_HEADER", ic.SignedHeader)
+		if claim, ok := seen[http.CanonicalHeaderKey(ic.SignedHeader)]; ok {
+			addf("%s: SIGNED_HEADER sama dengan header untuk klaim %s", label, claim)
+		}
+	}
+	if ic.SignedTTL < 0 {
+		addf("%s: SIGNED_TTL tidak boleh negatif", label)
+	}
+}
+
+// validateSession memeriksa AUTH.SESSION dan CORS. Mode sesi memakai cookie
+// sehingga CORS wajib dibatasi ke origin tertentu.
+func (c *Config) validateSession(addf func(format string, args ...interface{})) {
+	for _, origin := range c.CORS.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if origin == "*" || err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") || (u.Path != "" && u.Path != "/") {
+			addf("CORS: ALLOWED_ORIGINS %q harus berupa origin seperti https://app.example.com", origin)
+		}
+	}
+
+	s := c.Auth.Session
+	if !s.Enabled {
+		return
+	}
+	if len(c.CORS.AllowedOrigins) == 0 {
+		addf("AUTH.SESSION: CORS.ALLOWED_ORIGINS wajib diisi saat mode sesi aktif")
+	}
+	names := map[string]bool{}
+	for _, name := range s.CookieNames() {
+		if !validHeaderName(name) {
+			addf("AUTH.SESSION: nama cookie %q tidak valid", name)
+		}
+		if names[name] {
+			addf("AUTH.SESSION: nama cookie %q dipakai lebih dari sekali", name)
+		}
+		names[name] = true
+		if strings.HasPrefix(name, "__Host-") && (!s.Secure || s.Domain != "") {
+			addf("AUTH.SESSION: cookie %s dengan awalan __Host- butuh SECURE true dan DOMAIN kosong", name)
+		}
+	}
+	if !validHeaderName(s.CSRFHeader) {
+		addf("AUTH.SESSION: CSRF_HEADER %q bukan nama header yang valid", s.CSRFHeader)
+	}
+	switch strings.ToLower(s.SameSite) {
+	case "lax", "strict":
+	case "none":
+		if !s.Secure {
+			addf("AUTH.SESSION: SAME_SITE None butuh SECURE true")
+		}
+	default:
+		addf("AUTH.SESSION: SAME_SITE %q tidak dikenal (Lax, Strict, None)", s.SameSite)
+	}
+}
+
+// validateOIDC memeriksa AUTH.OIDC jika login OIDC diaktifkan.
+func (c *Config) validateOIDC(addf func(format string, args ...interface{})) {
+	o := c.Auth.OIDC
+	if !o.Enabled {
+		return
+	}
+	switch {
+	case o.Issuer == "" || !validTargetURL(o.Issuer):
+		addf("AUTH.OIDC: ISSUER wajib berupa URL http(s)")
+	case o.Issuer == "api-gateway":
+		addf("AUTH.OIDC: ISSUER %q dipakai untuk token buatan gateway sendiri", o.Issuer)
+	}
+	if o.DiscoveryURL != "" && !validTargetURL(o.DiscoveryURL) {
+		addf("AUTH.OIDC: DISCOVERY_URL tidak valid: %q", o.DiscoveryURL)
+	}
+	if o.ClientID == "" {
+		addf("AUTH.OIDC: CLIENT_ID wajib diisi")
+	}
+	if u, err := url.Parse(o.RedirectURL); o.RedirectURL == "" || err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		addf("AUTH.OIDC: REDIRECT_URL wajib berupa URL absolut ke /auth/oidc/callback")
+	} else if u.Path != "/auth/oidc/callback" {
+		addf("AUTH.OIDC: REDIRECT_URL harus mengarah ke /auth/oidc/callback, bukan %q", u.Path)
+	}
+	if o.UsernameClaim == "" {
+		addf("AUTH.OIDC: USERNAME_CLAIM wajib diisi")
+	}
+	for i, m := range o.RoleMapping {
+		if m.IdPRole == "" || len(m.Roles) == 0 {
+			addf("AUTH.OIDC.ROLE_MAPPING[%d]: IDP_ROLE dan ROLES wajib diisi", i)
+		}
+	}
+	// Redirect setelah login dibaca dari konfigurasi, tetapi tetap harus path
+	// lokal atau origin frontend yang dikenal
+	if r := o.PostLoginRedirect; !AllowedRedirect(r, c.CORS.AllowedOrigins) {
+		addf("AUTH.OIDC: POST_LOGIN_REDIRECT %q harus path lokal atau URL di CORS.ALLOWED_ORIGINS", r)
+	}
+	if o.StateTTL <= 0 || o.ClockSkew < 0 {
+		addf("AUTH.OIDC: STATE_TTL harus lebih dari 0 dan CLOCK_SKEW tidak boleh negatif")
+	}
+}
+
+// AllowedRedirect melaporkan apakah target aman dipakai sebagai tujuan
+// redirect setelah login: path lokal dengan tepat satu "/" di awal, atau URL
+// absolut yang origin-nya ada di origins. Karakter kontrol dan backslash
+// selalu ditolak karena browser membuang atau menganggapnya "/", sehingga
+// "/\t/evil.example" atau "/\evil.example" menjadi URL ke host lain.
+func AllowedRedirect(target string, origins []string) bool {
+	if target == "" || strings.ContainsFunc(target, func(r rune) bool { return r < 0x20 || r == 0x7f || r == '\\' }) {
+		return false
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.User != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		return strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//")
+	}
+	return u.Host != "" && slices.Contains(origins, u.Scheme+"://"+u.Host)
+}
+
+func validHeaderName(name string) bool {
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+func validTargetURL(target string) bool {
+	u, err := url.Parse(target)
+	return err == nil && u.Host != "" && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// pathOverlaps melaporkan apakah salah satu path adalah segmen awal dari path lain.
+func pathOverlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// validateSigning memeriksa AUTH.SIGNING. Isi file PEM baru dibaca saat rute
+// dibangun (auth.LoadKeySet).
+func (c *Config) validateSigning(addf func(format string, args ...interface{})) {
+	sc := c.Auth.Signing
+	if len(sc.Keys) == 0 {
+		if sc.ActiveKID != "" {
+			addf("AUTH.SIGNING: ACTIVE_KID %q diisi tetapi KEYS kosong", sc.ActiveKID)
+		}
+		return
+	}
+	seen := make(map[string]bool)
+	for i, k := range sc.Keys {
+		label := fmt.Sprintf("AUTH.SIGNING.KEYS[%d]", i)
+		if k.KID == "" {
+			addf("%s: KID wajib diisi", label)
+		} else if seen[k.KID] {
+			addf("%s: KID %q duplikat", label, k.KID)
+		}
+		seen[k.KID] = true
+		switch k.Algorithm {
+		case AlgRS256, AlgES256, AlgEdDSA:
+		default:
+			addf("%s: ALGORITHM %q tidak didukung (RS256, ES256, EdDSA)", label, k.Algorithm)
+		}
+		if k.PrivateKeyFile == "" && k.PublicKeyFile == "" {
+			addf("%s: PRIVATE_KEY_FILE atau PUBLIC_KEY_FILE wajib diisi", label)
+		}
+	}
+	if sc.ActiveKID == "" {
+		addf("AUTH.SIGNING: ACTIVE_KID wajib diisi jika KEYS tidak kosong")
+		return
+	}
+	for _, k := range sc.Keys {
+		if k.KID == sc.ActiveKID {
+			if k.PrivateKeyFile == "" {
+				addf("AUTH.SIGNING: kunci aktif %q tidak memiliki PRIVATE_KEY_FILE", sc.ActiveKID)
+			}
+			return
+		}
+	}
+	addf("AUTH.SIGNING: ACTIVE_KID %q tidak ada di KEYS", sc.ActiveKID)
+}
+
+// externalAlgorithms adalah algoritma yang boleh dipakai issuer eksternal.
+// HS* tidak didukung karena butuh secret bersama, dan "none" tidak pernah diterima.
+var externalAlgorithms = map[string]bool{
+	"RS256": true, "RS384": true, "RS512": true,
+	"PS256": true, "PS384": true, "PS512": true,
+	"ES256": true, "ES384": true, "ES512": true,
+	"EdDSA": true,
+}
+
+func (c *Config) validateTrustedIssuers(addf func(format string, args ...interface{})) {
+	seen := make(map[string]bool)
+	for i, ti := range c.Auth.TrustedIssuers {
+		label := fmt.Sprintf("AUTH.TRUSTED_ISSUERS[%d]", i)
+		switch {
+		case ti.Issuer == "":
+			addf("%s: ISSUER wajib diisi", label)
+		case ti.Issuer == "api-gateway":
+			addf("%s: ISSUER %q dipakai untuk token buatan gateway sendiri", label, ti.Issuer)
+		case seen[ti.Issuer]:
+			addf("%s: ISSUER %q duplikat", label, ti.Issuer)
+		}
+		seen[ti.Issuer] = true
+		if (ti.DiscoveryURL == "") == (ti.JWKSURL == "") {
+			addf("%s: isi salah satu dari DISCOVERY_URL atau JWKS_URL", label)
+		}
+		for _, u := range []string{ti.DiscoveryURL, ti.JWKSURL} {
+			if u != "" && !validTargetURL(u) {
+				addf("%s: URL tidak valid: %q", label, u)
+			}
+		}
+		if len(ti.Audiences) == 0 {
+			addf("%s: AUDIENCES wajib diisi agar token untuk aplikasi lain tidak diterima", label)
+		}
+		for _, alg := range ti.Algorithms {
+			if !externalAlgorithms[alg] {
+				addf("%s: ALGORITHMS berisi %q yang tidak didukung", label, alg)
+			}
+		}
+		if ti.ClockSkew < 0 || ti.RefreshInterval < 0 {
+			addf("%s: CLOCK_SKEW dan REFRESH_INTERVAL tidak boleh negatif", label)
+		}
+	}
+}
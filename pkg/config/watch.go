@@ -0,0 +1,71 @@
+// pkg/config/watch.go
+package config
+
+import (
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce memberi jeda agar rangkaian event dari satu kali simpan
+// (editor biasanya menulis, rename, lalu chmod) hanya memicu satu reload.
+const watchDebounce = 500 * time.Millisecond
+
+// WatchConfig memantau file config.* di direktori path dan memanggil onChange
+// setiap kali file tersebut berubah. Yang dipantau adalah direktorinya, bukan
+// file-nya, supaya tetap berfungsi saat editor mengganti file lewat rename.
+// Panggil fungsi stop untuk berhenti.
+func WatchConfig(path string, onChange func()) (stop func(), err error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(path); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		var timer *time.Timer
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !isConfigFile(event.Name) || event.Op == fsnotify.Chmod {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(watchDebounce, onChange)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("Error saat memantau file konfigurasi: %v", err)
+			case <-done:
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		watcher.Close()
+	}, nil
+}
+
+func isConfigFile(name string) bool {
+	base := filepath.Base(name)
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) == "config" && (ext == ".yml" || ext == ".yaml")
+}
@@ -0,0 +1,238 @@
+// pkg/database/api_key.go
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrConsumerNotFound dikembalikan jika nama consumer tidak terdaftar.
+	ErrConsumerNotFound = errors.New("consumer tidak ditemukan")
+	// ErrConsumerExists dikembalikan saat membuat consumer dengan nama yang sudah ada.
+	ErrConsumerExists = errors.New("nama consumer sudah terdaftar")
+	// ErrAPIKeyNotFound dikembalikan jika prefix API key tidak dikenal.
+	ErrAPIKeyNotFound = errors.New("API key tidak ditemukan")
+)
+
+// Consumer adalah klien mesin (batch job, integrasi partner) yang
+// mengautentikasi dengan API key, bukan username dan password. Setiap consumer
+// punya role dan rate limit sendiri.
+type Consumer struct {
+	ID                 string   `gorm:"primaryKey;size:36"`
+	Name               string   `gorm:"uniqueIndex;size:128;not null"`
+	Roles              []string `gorm:"serializer:json"`
+	RateLimitRequests  int      `gorm:"not null;default:0"` // 0 berarti hanya RATE_LIMIT global
+	RateLimitWindowSec int      `gorm:"not null;default:0"`
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+// BeforeCreate mengisi ID dengan UUIDv7 jika belum ada.
+func (c *Consumer) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		c.ID = id.String()
+	}
+	return nil
+}
+
+// APIKey adalah satu API key milik consumer. Hanya hash key yang disimpan;
+// Prefix (awal key, bukan rahasia) dipakai untuk lookup dan ditampilkan ke admin.
+type APIKey struct {
+	ID         string   `gorm:"primaryKey;size:36"`
+	ConsumerID string   `gorm:"index;size:36;not null"`
+	Consumer   Consumer `gorm:"constraint:OnDelete:CASCADE"`
+	Prefix     string   `gorm:"uniqueIndex;size:32;not null"`
+	KeyHash    string   `gorm:"size:64;not null"`
+	Name       string   `gorm:"size:128"` // Label bebas, misal "batch-nightly"
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// BeforeCreate mengisi ID dengan UUIDv7 jika belum ada.
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		k.ID = id.String()
+	}
+	return nil
+}
+
+// Expired melaporkan apakah key sudah melewati ExpiresAt pada waktu t.
+func (k *APIKey) Expired(t time.Time) bool {
+	return k.ExpiresAt != nil && !t.Before(*k.ExpiresAt)
+}
+
+// Status merangkum keadaan key: active, expired, atau revoked.
+func (k *APIKey) Status(t time.Time) string {
+	switch {
+	case k.RevokedAt != nil:
+		return "revoked"
+	case k.Expired(t):
+		return "expired"
+	default:
+		return "active"
+	}
+}
+
+// APIKeyRepository menyimpan consumer dan API key-nya.
+type APIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository membuat repository API key di atas koneksi GORM.
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db}
+}
+
+// normalizeConsumerName membuat nama consumer tidak peka huruf besar/kecil.
+func normalizeConsumerName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// CreateConsumer menyimpan consumer baru.
+func (r *APIKeyRepository) CreateConsumer(ctx context.Context, consumer *Consumer) error {
+	consumer.Name = normalizeConsumerName(consumer.Name)
+	if _, err := r.FindConsumer(ctx, consumer.Name); err == nil {
+		return ErrConsumerExists
+	} else if !errors.Is(err, ErrConsumerNotFound) {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(consumer).Error
+}
+
+// FindConsumer mencari consumer berdasarkan nama.
+func (r *APIKeyRepository) FindConsumer(ctx context.Context, name string) (*Consumer, error) {
+	var consumer Consumer
+	err := r.db.WithContext(ctx).Where("name = ?", normalizeConsumerName(name)).First(&consumer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrConsumerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &consumer, nil
+}
+
+// ListConsumers mengembalikan semua consumer urut berdasarkan nama.
+func (r *APIKeyRepository) ListConsumers(ctx context.Context) ([]Consumer, error) {
+	var consumers []Consumer
+	err := r.db.WithContext(ctx).Order("name").Find(&consumers).Error
+	return consumers, err
+}
+
+// UpdateConsumer mengganti role dan rate limit consumer.
+func (r *APIKeyRepository) UpdateConsumer(ctx context.Context, name string, roles []string, requests, windowSec int) error {
+	// Select agar nilai nol (tanpa role, tanpa rate limit) ikut disimpan
+	res := r.db.WithContext(ctx).Model(&Consumer{}).Where("name = ?", normalizeConsumerName(name)).
+		Select("roles", "rate_limit_requests", "rate_limit_window_sec").
+		Updates(Consumer{Roles: roles, RateLimitRequests: requests, RateLimitWindowSec: windowSec})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrConsumerNotFound
+	}
+	return nil
+}
+
+// DeleteConsumer menghapus consumer beserta semua API key-nya.
+func (r *APIKeyRepository) DeleteConsumer(ctx context.Context, name string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var consumer Consumer
+		if err := tx.Where("name = ?", normalizeConsumerName(name)).First(&consumer).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrConsumerNotFound
+			}
+			return err
+		}
+		if err := tx.Where("consumer_id = ?", consumer.ID).Delete(&APIKey{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&consumer).Error
+	})
+}
+
+// CreateKey menyimpan API key baru. Prefix dan KeyHash harus sudah diisi
+// (lihat auth.NewAPIKey).
+func (r *APIKeyRepository) CreateKey(ctx context.Context, key *APIKey) error {
+	return r.db.WithContext(ctx).Omit("Consumer").Create(key).Error
+}
+
+// FindKey mencari API key berdasarkan prefix beserta consumer-nya.
+func (r *APIKeyRepository) FindKey(ctx context.Context, prefix string) (*APIKey, error) {
+	var key APIKey
+	err := r.db.WithContext(ctx).Joins("Consumer").Where("prefix = ?", prefix).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListKeys mengembalikan API key milik consumerID, atau semua key jika
+// consumerID kosong, urut dari yang terbaru.
+func (r *APIKeyRepository) ListKeys(ctx context.Context, consumerID string) ([]APIKey, error) {
+	var keys []APIKey
+	q := r.db.WithContext(ctx).Joins("Consumer")
+	if consumerID != "" {
+		q = q.Where("consumer_id = ?", consumerID)
+	}
+	err := q.Order("api_keys.created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// RevokeKey mencabut API key. Key yang sudah dicabut tidak diubah.
+func (r *APIKeyRepository) RevokeKey(ctx context.Context, prefix string) error {
+	return r.updateKey(ctx, prefix, r.db.WithContext(ctx).Model(&APIKey{}).
+		Where("prefix = ? AND revoked_at IS NULL", prefix).
+		Update("revoked_at", now()))
+}
+
+// SetKeyExpiry mengganti waktu kedaluwarsa API key, misalnya untuk
+// mengakhiri masa transisi setelah key pengganti dibuat.
+func (r *APIKeyRepository) SetKeyExpiry(ctx context.Context, prefix string, expiresAt time.Time) error {
+	return r.updateKey(ctx, prefix, r.db.WithContext(ctx).Model(&APIKey{}).
+		Where("prefix = ?", prefix).
+		Update("expires_at", expiresAt.UTC()))
+}
+
+// updateKey menerjemahkan hasil update nol baris menjadi ErrAPIKeyNotFound
+// jika prefix memang tidak ada.
+func (r *APIKeyRepository) updateKey(ctx context.Context, prefix string, res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&APIKey{}).Where("prefix = ?", prefix).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// TouchKey mencatat waktu terakhir API key dipakai.
+func (r *APIKeyRepository) TouchKey(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
@@ -0,0 +1,89 @@
+// pkg/database/database.go
+package database
+
+import (
+	"api-gateway-go/pkg/config"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// InitDB membuka koneksi database sesuai konfigurasi DATABASE lalu menjalankan
+// migrasi skema. SQLite dipakai jika DRIVER tidak diisi dan DSN tidak berawalan
+// postgres:// atau mysql://.
+func InitDB(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	driver := cfg.DriverName()
+	switch driver {
+	case config.DriverSQLite:
+		// Aktifkan foreign key dan tunggu lock alih-alih langsung gagal
+		dsn := cfg.DSN
+		if !strings.Contains(dsn, "?") {
+			dsn += "?_foreign_keys=on&_busy_timeout=5000"
+		}
+		dialector = sqlite.Open(dsn)
+	case config.DriverPostgres:
+		dialector = postgres.Open(cfg.DSN)
+	case config.DriverMySQL:
+		// Driver MySQL memakai format DSN user:pass@tcp(host)/db, bukan URL
+		dsn := strings.TrimPrefix(cfg.DSN, "mysql://")
+		if !strings.Contains(dsn, "parseTime") {
+			sep := "?"
+			if strings.Contains(dsn, "?") {
+				sep = "&"
+			}
+			dsn += sep + "parseTime=true"
+		}
+		dialector = mysql.Open(dsn)
+	default:
+		return nil, fmt.Errorf("driver database %q tidak didukung", cfg.Driver)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		// "record not found" adalah hasil normal (misal username salah), bukan error
+		Logger: logger.New(log.Default(), logger.Config{
+			SlowThreshold:             200 * time.Millisecond,
+			LogLevel:                  logger.Warn,
+			IgnoreRecordNotFoundError: true,
+		}),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gagal membuka database %s: %w", driver, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	if driver == config.DriverSQLite {
+		// SQLite hanya mengizinkan satu penulis; satu koneksi mencegah "database is locked"
+		sqlDB.SetMaxOpenConns(1)
+	} else {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if err := Migrate(db); err != nil {
+		return nil, fmt.Errorf("gagal menjalankan migrasi database: %w", err)
+	}
+	log.Printf("Database %s siap", driver)
+	return db, nil
+}
+
+// Migrate membuat atau memperbarui tabel untuk semua model.
+func Migrate(db *gorm.DB) error {
+	return db.AutoMigrate(&User{}, &RefreshToken{}, &RevokedToken{}, &Consumer{}, &APIKey{}, &MFARecoveryCode{}, &OAuthClient{}, &UserIdentity{})
+}
+
+// now dipakai untuk timestamp agar seragam dalam UTC di semua driver.
+func now() time.Time {
+	return time.Now().UTC()
+}
@@ -0,0 +1,26 @@
+// pkg/database/database_test.go
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"api-gateway-go/pkg/config"
+
+	"gorm.io/gorm"
+)
+
+// openTestDB membuka database SQLite baru di direktori sementara test.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := InitDB(config.DatabaseConfig{Driver: config.DriverSQLite, DSN: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
@@ -0,0 +1,93 @@
+// pkg/database/identity.go
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserIdentity menautkan akun lokal ke identitas di identity provider
+// eksternal (login OIDC). Satu pasangan issuer dan subject hanya boleh
+// tertaut ke satu pengguna.
+type UserIdentity struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      string `gorm:"index;size:36;not null"`
+	Issuer      string `gorm:"uniqueIndex:idx_user_identity_subject;size:255;not null"`
+	Subject     string `gorm:"uniqueIndex:idx_user_identity_subject;size:255;not null"`
+	LastLoginAt *time.Time
+	CreatedAt   time.Time
+}
+
+// FindByIdentity mencari pengguna yang tertaut ke subject di issuer.
+func (r *UserRepository) FindByIdentity(ctx context.Context, issuer, subject string) (*User, error) {
+	var identity UserIdentity
+	err := r.db.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.FindByID(ctx, identity.UserID)
+}
+
+// CreateExternal menyimpan pengguna baru yang login lewat identity provider
+// beserta tautan identitasnya. Pengguna ini tidak punya password lokal.
+func (r *UserRepository) CreateExternal(ctx context.Context, user *User, issuer, subject string) error {
+	user.Username = normalizeUsername(user.Username)
+	user.PasswordHash = ""
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&User{}).Where("username = ?", user.Username).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrUserExists
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Create(&UserIdentity{UserID: user.ID, Issuer: issuer, Subject: subject}).Error
+	})
+}
+
+// LinkIdentity menautkan pengguna yang sudah ada ke subject di issuer.
+func (r *UserRepository) LinkIdentity(ctx context.Context, userID, issuer, subject string) error {
+	err := r.db.WithContext(ctx).Create(&UserIdentity{UserID: userID, Issuer: issuer, Subject: subject}).Error
+	if err != nil && strings.Contains(strings.ToLower(err.Error()), "unique") {
+		return ErrUserExists
+	}
+	return err
+}
+
+// HasIdentity melaporkan apakah pengguna sudah tertaut ke issuer, sehingga
+// akun lokal dengan username sama tidak diambil alih oleh subject lain.
+func (r *UserRepository) HasIdentity(ctx context.Context, userID, issuer string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&UserIdentity{}).Where("user_id = ? AND issuer = ?", userID, issuer).Count(&count).Error
+	return count > 0, err
+}
+
+// SetRoles mengganti role pengguna, misalnya dengan role dari identity
+// provider setiap kali pengguna login.
+func (r *UserRepository) SetRoles(ctx context.Context, id string, roles []string) error {
+	res := r.db.WithContext(ctx).Model(&User{ID: id}).Select("roles").Updates(&User{Roles: roles})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// RecordIdentityLogin mencatat waktu login terakhir lewat identitas eksternal.
+func (r *UserRepository) RecordIdentityLogin(ctx context.Context, issuer, subject string) error {
+	return r.db.WithContext(ctx).Model(&UserIdentity{}).
+		Where("issuer = ? AND subject = ?", issuer, subject).
+		Update("last_login_at", now()).Error
+}
@@ -0,0 +1,113 @@
+// pkg/database/mfa.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MFARecoveryCode adalah satu recovery code sekali pakai. Hanya hash-nya yang
+// disimpan.
+type MFARecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"index;size:36;not null"`
+	CodeHash  string `gorm:"uniqueIndex;size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// StartMFAEnrollment menyimpan secret TOTP baru yang belum aktif. MFA yang
+// sudah aktif tidak diubah; pemanggil harus menonaktifkannya lebih dulu.
+func (r *UserRepository) StartMFAEnrollment(ctx context.Context, id, secret string) error {
+	res := r.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND mfa_enabled = ?", id, false).
+		Updates(map[string]interface{}{"mfa_secret": secret, "mfa_last_step": 0})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// EnableMFA mengaktifkan MFA setelah kode pertama terverifikasi, mencatat
+// langkah waktunya, dan mengganti semua recovery code dengan hashes.
+func (r *UserRepository) EnableMFA(ctx context.Context, id string, step int64, hashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&User{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"mfa_enabled": true, "mfa_last_step": step})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return replaceRecoveryCodes(tx, id, hashes)
+	})
+}
+
+// ReplaceRecoveryCodes mengganti semua recovery code pengguna.
+func (r *UserRepository) ReplaceRecoveryCodes(ctx context.Context, id string, hashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, id, hashes)
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID string, hashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+	codes := make([]MFARecoveryCode, len(hashes))
+	for i, h := range hashes {
+		codes[i] = MFARecoveryCode{UserID: userID, CodeHash: h}
+	}
+	return tx.Create(&codes).Error
+}
+
+// DisableMFA menonaktifkan MFA dan menghapus secret serta recovery code.
+func (r *UserRepository) DisableMFA(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&User{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"mfa_enabled": false, "mfa_secret": "", "mfa_last_step": 0})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrUserNotFound
+		}
+		return tx.Where("user_id = ?", id).Delete(&MFARecoveryCode{}).Error
+	})
+}
+
+// AdvanceMFAStep menyimpan langkah waktu TOTP yang baru dipakai. Update hanya
+// berhasil jika step lebih baru dari yang tersimpan, sehingga dua request
+// paralel dengan kode yang sama tidak bisa sama-sama lolos.
+func (r *UserRepository) AdvanceMFAStep(ctx context.Context, id string, step int64) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&User{}).
+		Where("id = ? AND mfa_last_step < ?", id, step).
+		Update("mfa_last_step", step)
+	return res.RowsAffected == 1, res.Error
+}
+
+// UseRecoveryCode menandai recovery code sebagai terpakai. Mengembalikan
+// false jika kode tidak dikenal atau sudah dipakai.
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, id, hash string) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", id, hash).
+		Update("used_at", now())
+	return res.RowsAffected == 1, res.Error
+}
+
+// CountRecoveryCodes mengembalikan jumlah recovery code yang belum dipakai.
+func (r *UserRepository) CountRecoveryCodes(ctx context.Context, id string) (int64, error) {
+	var n int64
+	err := r.db.WithContext(ctx).Model(&MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", id).Count(&n).Error
+	return n, err
+}
@@ -0,0 +1,122 @@
+// pkg/database/mfa_test.go
+package database
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// seedMFAUser membuat pengguna dengan MFA aktif pada langkah waktu step.
+func seedMFAUser(t *testing.T, repo *UserRepository, step int64, hashes []string) *User {
+	t.Helper()
+	ctx := context.Background()
+	user := &User{Username: "alice", PasswordHash: "-"}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := repo.StartMFAEnrollment(ctx, user.ID, "JBSWY3DPEHPK3PXP"); err != nil {
+		t.Fatalf("StartMFAEnrollment: %v", err)
+	}
+	if err := repo.EnableMFA(ctx, user.ID, step, hashes); err != nil {
+		t.Fatalf("EnableMFA: %v", err)
+	}
+	return user
+}
+
+func TestAdvanceMFAStep(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []int64 // Dipanggil berurutan
+		want  []bool
+	}{
+		{"langkah baru diterima", []int64{101}, []bool{true}},
+		{"langkah sama ditolak", []int64{101, 101}, []bool{true, false}},
+		{"langkah saat enrollment ditolak", []int64{100}, []bool{false}},
+		{"langkah lebih lama ditolak", []int64{103, 102}, []bool{true, false}},
+		{"langkah terus maju", []int64{101, 102, 103}, []bool{true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewUserRepository(openTestDB(t))
+			user := seedMFAUser(t, repo, 100, nil)
+			for i, step := range tt.steps {
+				ok, err := repo.AdvanceMFAStep(ctx, user.ID, step)
+				if err != nil {
+					t.Fatalf("AdvanceMFAStep(%d): %v", step, err)
+				}
+				if ok != tt.want[i] {
+					t.Errorf("AdvanceMFAStep(%d) = %v, want %v", step, ok, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAdvanceMFAStepConcurrent(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(openTestDB(t))
+	user := seedMFAUser(t, repo, 100, nil)
+
+	// Kode yang sama dikirim paralel: hanya satu yang boleh lolos
+	const workers = 8
+	results := make([]bool, workers)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			ok, err := repo.AdvanceMFAStep(ctx, user.ID, 101)
+			if err != nil {
+				t.Errorf("worker %d: %v", i, err)
+			}
+			results[i] = ok
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for _, ok := range results {
+		if ok {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d request lolos, want tepat 1", succeeded)
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository(openTestDB(t))
+	user := seedMFAUser(t, repo, 100, []string{"hash-a", "hash-b"})
+
+	steps := []struct {
+		name   string
+		userID string
+		hash   string
+		want   bool
+	}{
+		{"kode valid", user.ID, "hash-a", true},
+		{"kode yang sama dipakai ulang", user.ID, "hash-a", false},
+		{"kode tidak dikenal", user.ID, "hash-x", false},
+		{"kode milik pengguna lain", "user-lain", "hash-b", false},
+		{"kode kedua masih berlaku", user.ID, "hash-b", true},
+	}
+	for _, step := range steps {
+		ok, err := repo.UseRecoveryCode(ctx, step.userID, step.hash)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if ok != step.want {
+			t.Errorf("%s: UseRecoveryCode() = %v, want %v", step.name, ok, step.want)
+		}
+	}
+	if n, err := repo.CountRecoveryCodes(ctx, user.ID); err != nil || n != 0 {
+		t.Errorf("CountRecoveryCodes() = %d, %v, want 0", n, err)
+	}
+}
@@ -0,0 +1,145 @@
+// pkg/database/oauth_client.go
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrClientNotFound dikembalikan jika client_id tidak terdaftar.
+	ErrClientNotFound = errors.New("OAuth client tidak ditemukan")
+	// ErrClientExists dikembalikan saat mendaftarkan client_id yang sudah ada.
+	ErrClientExists = errors.New("client_id sudah terdaftar")
+)
+
+// OAuthClient adalah service internal yang meminta access token lewat grant
+// client_credentials di /oauth/token. Hanya hash secret yang disimpan.
+type OAuthClient struct {
+	ID              string   `gorm:"primaryKey;size:36"`
+	ClientID        string   `gorm:"uniqueIndex;size:128;not null"`
+	SecretHash      string   `gorm:"size:64;not null"`
+	Scopes          []string `gorm:"serializer:json"` // Scope yang boleh diminta client
+	Roles           []string `gorm:"serializer:json"`
+	TokenTTLSeconds int      `gorm:"not null;default:0"` // 0 berarti AUTH.CLIENT_CREDENTIALS.TOKEN_TTL
+	Disabled        bool     `gorm:"not null;default:false"`
+	LastUsedAt      *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+// BeforeCreate mengisi ID dengan UUIDv7 jika belum ada.
+func (c *OAuthClient) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		c.ID = id.String()
+	}
+	return nil
+}
+
+// TokenTTL mengembalikan masa berlaku token client, atau fallback jika client
+// tidak mengatur TTL sendiri.
+func (c *OAuthClient) TokenTTL(fallback time.Duration) time.Duration {
+	if c.TokenTTLSeconds > 0 {
+		return time.Duration(c.TokenTTLSeconds) * time.Second
+	}
+	return fallback
+}
+
+// OAuthClientRepository menyimpan OAuth client.
+type OAuthClientRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthClientRepository membuat repository OAuth client di atas koneksi GORM.
+func NewOAuthClientRepository(db *gorm.DB) *OAuthClientRepository {
+	return &OAuthClientRepository{db: db}
+}
+
+// normalizeClientID membuat client_id tidak peka huruf besar/kecil.
+func normalizeClientID(clientID string) string {
+	return strings.ToLower(strings.TrimSpace(clientID))
+}
+
+// Create menyimpan client baru. SecretHash harus sudah diisi (lihat
+// auth.NewClientSecret).
+func (r *OAuthClientRepository) Create(ctx context.Context, client *OAuthClient) error {
+	client.ClientID = normalizeClientID(client.ClientID)
+	if _, err := r.Find(ctx, client.ClientID); err == nil {
+		return ErrClientExists
+	} else if !errors.Is(err, ErrClientNotFound) {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+// Find mencari client berdasarkan client_id.
+func (r *OAuthClientRepository) Find(ctx context.Context, clientID string) (*OAuthClient, error) {
+	var client OAuthClient
+	err := r.db.WithContext(ctx).Where("client_id = ?", normalizeClientID(clientID)).First(&client).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrClientNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// List mengembalikan semua client urut berdasarkan client_id.
+func (r *OAuthClientRepository) List(ctx context.Context) ([]OAuthClient, error) {
+	var clients []OAuthClient
+	err := r.db.WithContext(ctx).Order("client_id").Find(&clients).Error
+	return clients, err
+}
+
+// Update mengganti scope, role, TTL token, dan status client.
+func (r *OAuthClientRepository) Update(ctx context.Context, client *OAuthClient) error {
+	// Select agar nilai nol (tanpa scope, TTL default, aktif) ikut disimpan
+	return r.update(ctx, client.ClientID, r.db.WithContext(ctx).Model(&OAuthClient{}).
+		Where("client_id = ?", normalizeClientID(client.ClientID)).
+		Select("scopes", "roles", "token_ttl_seconds", "disabled").
+		Updates(client))
+}
+
+// SetSecretHash mengganti secret client. Secret lama langsung tidak berlaku.
+func (r *OAuthClientRepository) SetSecretHash(ctx context.Context, clientID, hash string) error {
+	return r.update(ctx, clientID, r.db.WithContext(ctx).Model(&OAuthClient{}).
+		Where("client_id = ?", normalizeClientID(clientID)).
+		Update("secret_hash", hash))
+}
+
+// Delete menghapus client.
+func (r *OAuthClientRepository) Delete(ctx context.Context, clientID string) error {
+	return r.update(ctx, clientID, r.db.WithContext(ctx).
+		Where("client_id = ?", normalizeClientID(clientID)).
+		Delete(&OAuthClient{}))
+}
+
+// Touch mencatat waktu terakhir client meminta token.
+func (r *OAuthClientRepository) Touch(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&OAuthClient{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
+
+// update menerjemahkan hasil update nol baris menjadi ErrClientNotFound.
+func (r *OAuthClientRepository) update(ctx context.Context, clientID string, res *gorm.DB) error {
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+	// Update dengan nilai yang sama tetap 0 baris di beberapa driver
+	if _, err := r.Find(ctx, clientID); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+// pkg/database/refresh_token.go
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRefreshTokenInvalid dikembalikan jika refresh token tidak dikenal,
+	// kedaluwarsa, atau sudah dicabut.
+	ErrRefreshTokenInvalid = errors.New("refresh token tidak valid")
+	// ErrRefreshTokenReused dikembalikan jika refresh token yang sudah dirotasi
+	// dipakai lagi. Seluruh family token sudah dicabut saat error ini muncul.
+	ErrRefreshTokenReused = errors.New("refresh token sudah pernah dipakai")
+)
+
+// RefreshToken adalah refresh token opaque yang tersimpan di server. Setiap
+// login memulai family baru; setiap rotasi menandai token lama sebagai terpakai
+// dan membuat token baru di family yang sama.
+type RefreshToken struct {
+	ID         string `gorm:"primaryKey;size:36"`
+	FamilyID   string `gorm:"index;size:36;not null"`
+	UserID     string `gorm:"index;size:36;not null"`
+	TokenHash  string `gorm:"uniqueIndex;size:64;not null"`
+	ExpiresAt  time.Time
+	UsedAt     *time.Time
+	RevokedAt  *time.Time
+	ReplacedBy string `gorm:"size:36"`
+	CreatedAt  time.Time
+}
+
+// BeforeCreate mengisi ID dan FamilyID jika belum ada.
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		t.ID = id.String()
+	}
+	if t.FamilyID == "" {
+		t.FamilyID = t.ID
+	}
+	return nil
+}
+
+// RefreshTokenRepository menyimpan refresh token dan menjalankan rotasi.
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRefreshTokenRepository membuat repository refresh token.
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// Create menyimpan refresh token baru (awal family baru jika FamilyID kosong).
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// Rotate menukar refresh token dengan hash presentedHash menjadi next, dalam
+// family yang sama. Token lama ditandai terpakai secara atomik sehingga dua
+// request yang memakai token yang sama bersamaan tidak bisa sama-sama berhasil;
+// yang kalah diperlakukan sebagai reuse dan seluruh family dicabut.
+func (r *RefreshTokenRepository) Rotate(ctx context.Context, presentedHash string, next *RefreshToken) (*RefreshToken, error) {
+	var current RefreshToken
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ?", presentedHash).First(&current).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRefreshTokenInvalid
+			}
+			return err
+		}
+		if current.RevokedAt != nil {
+			return ErrRefreshTokenInvalid
+		}
+		if current.UsedAt != nil {
+			return ErrRefreshTokenReused
+		}
+		if now().After(current.ExpiresAt) {
+			return ErrRefreshTokenInvalid
+		}
+
+		next.FamilyID = current.FamilyID
+		next.UserID = current.UserID
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", current.ID).
+			Updates(map[string]interface{}{"used_at": now(), "replaced_by": next.ID})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected != 1 {
+			return ErrRefreshTokenReused
+		}
+		return nil
+	})
+	if errors.Is(err, ErrRefreshTokenReused) {
+		// Dicabut di luar transaksi yang gagal agar pencabutan tidak ikut di-rollback
+		if revokeErr := r.RevokeFamily(ctx, current.FamilyID); revokeErr != nil {
+			return &current, revokeErr
+		}
+	}
+	if err != nil {
+		return &current, err
+	}
+	return &current, nil
+}
+
+// FindByHash mengambil refresh token dengan hash tertentu, apa pun statusnya.
+// Mengembalikan ErrRefreshTokenInvalid jika tidak ada.
+func (r *RefreshTokenRepository) FindByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	var token RefreshToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrRefreshTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Active melaporkan apakah refresh token masih bisa dipakai pada waktu t.
+func (t *RefreshToken) Active(at time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && at.Before(t.ExpiresAt)
+}
+
+// RevokeFamily mencabut semua token yang belum dicabut dalam satu family.
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now()).Error
+}
+
+// RevokeFamilyOf mencabut family dari refresh token dengan hash tertentu,
+// asalkan token tersebut milik userID. Token yang tidak dikenal diabaikan.
+func (r *RefreshTokenRepository) RevokeFamilyOf(ctx context.Context, tokenHash, userID string) error {
+	var token RefreshToken
+	err := r.db.WithContext(ctx).Where("token_hash = ? AND user_id = ?", tokenHash, userID).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return r.RevokeFamily(ctx, token.FamilyID)
+}
+
+// RevokeUser mencabut semua refresh token milik pengguna.
+func (r *RefreshTokenRepository) RevokeUser(ctx context.Context, userID string) (int64, error) {
+	res := r.db.WithContext(ctx).Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now())
+	return res.RowsAffected, res.Error
+}
+
+// DeleteExpired menghapus token yang sudah kedaluwarsa sebelum waktu tertentu.
+func (r *RefreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at < ?", before.UTC()).Delete(&RefreshToken{})
+	return res.RowsAffected, res.Error
+}
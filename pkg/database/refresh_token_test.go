@@ -0,0 +1,182 @@
+// pkg/database/refresh_token_test.go
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// seedToken menyimpan refresh token dengan hash dan masa berlaku tertentu.
+func seedToken(t *testing.T, repo *RefreshTokenRepository, hash string, expiresIn time.Duration) *RefreshToken {
+	t.Helper()
+	token := &RefreshToken{UserID: "user-1", TokenHash: hash, ExpiresAt: time.Now().Add(expiresIn).UTC()}
+	if err := repo.Create(context.Background(), token); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return token
+}
+
+// loadToken membaca refresh token apa pun statusnya langsung dari database.
+func loadToken(t *testing.T, repo *RefreshTokenRepository, hash string) *RefreshToken {
+	t.Helper()
+	var token RefreshToken
+	if err := repo.db.Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		t.Fatalf("membaca token %s: %v", hash, err)
+	}
+	return &token
+}
+
+func TestRotate(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, repo *RefreshTokenRepository) string // Mengembalikan hash yang dipakai
+		wantErr error
+	}{
+		{
+			name: "token aktif dirotasi",
+			setup: func(t *testing.T, repo *RefreshTokenRepository) string {
+				return seedToken(t, repo, "h-active", time.Hour).TokenHash
+			},
+		},
+		{
+			name: "token tidak dikenal",
+			setup: func(t *testing.T, repo *RefreshTokenRepository) string {
+				return "h-unknown"
+			},
+			wantErr: ErrRefreshTokenInvalid,
+		},
+		{
+			name: "token kedaluwarsa",
+			setup: func(t *testing.T, repo *RefreshTokenRepository) string {
+				return seedToken(t, repo, "h-expired", -time.Minute).TokenHash
+			},
+			wantErr: ErrRefreshTokenInvalid,
+		},
+		{
+			name: "token dicabut",
+			setup: func(t *testing.T, repo *RefreshTokenRepository) string {
+				token := seedToken(t, repo, "h-revoked", time.Hour)
+				if err := repo.RevokeFamily(context.Background(), token.FamilyID); err != nil {
+					t.Fatalf("RevokeFamily: %v", err)
+				}
+				return token.TokenHash
+			},
+			wantErr: ErrRefreshTokenInvalid,
+		},
+		{
+			name: "token yang sudah dirotasi dipakai ulang",
+			setup: func(t *testing.T, repo *RefreshTokenRepository) string {
+				token := seedToken(t, repo, "h-used", time.Hour)
+				next := &RefreshToken{TokenHash: "h-used-next", ExpiresAt: time.Now().Add(time.Hour).UTC()}
+				if _, err := repo.Rotate(context.Background(), token.TokenHash, next); err != nil {
+					t.Fatalf("rotasi pertama: %v", err)
+				}
+				return token.TokenHash
+			},
+			wantErr: ErrRefreshTokenReused,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := NewRefreshTokenRepository(openTestDB(t))
+			hash := tt.setup(t, repo)
+			next := &RefreshToken{TokenHash: hash + "-rotated", ExpiresAt: time.Now().Add(time.Hour).UTC()}
+
+			current, err := repo.Rotate(ctx, hash, next)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Rotate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if next.FamilyID != current.FamilyID || next.UserID != current.UserID {
+				t.Errorf("token baru family %s user %s, want family %s user %s", next.FamilyID, next.UserID, current.FamilyID, current.UserID)
+			}
+			old := loadToken(t, repo, hash)
+			if old.UsedAt == nil || old.ReplacedBy != next.ID {
+				t.Errorf("token lama UsedAt=%v ReplacedBy=%q, want terpakai dan diganti %s", old.UsedAt, old.ReplacedBy, next.ID)
+			}
+		})
+	}
+}
+
+func TestRotateReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRefreshTokenRepository(openTestDB(t))
+	first := seedToken(t, repo, "h-1", time.Hour)
+
+	second := &RefreshToken{TokenHash: "h-2", ExpiresAt: time.Now().Add(time.Hour).UTC()}
+	if _, err := repo.Rotate(ctx, "h-1", second); err != nil {
+		t.Fatalf("rotasi h-1: %v", err)
+	}
+	third := &RefreshToken{TokenHash: "h-3", ExpiresAt: time.Now().Add(time.Hour).UTC()}
+	if _, err := repo.Rotate(ctx, "h-2", third); err != nil {
+		t.Fatalf("rotasi h-2: %v", err)
+	}
+
+	// Penyerang memakai token pertama yang sudah dirotasi
+	if _, err := repo.Rotate(ctx, "h-1", &RefreshToken{TokenHash: "h-x", ExpiresAt: time.Now().Add(time.Hour).UTC()}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reuse error = %v, want ErrRefreshTokenReused", err)
+	}
+
+	for _, hash := range []string{"h-1", "h-2", "h-3"} {
+		token := loadToken(t, repo, hash)
+		if token.FamilyID != first.FamilyID {
+			t.Errorf("%s family %s, want %s", hash, token.FamilyID, first.FamilyID)
+		}
+		if token.RevokedAt == nil {
+			t.Errorf("%s belum dicabut setelah reuse", hash)
+		}
+	}
+	// Token terbaru pengguna sah juga tidak bisa dipakai lagi
+	if _, err := repo.Rotate(ctx, "h-3", &RefreshToken{TokenHash: "h-4", ExpiresAt: time.Now().Add(time.Hour).UTC()}); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("rotasi h-3 setelah reuse error = %v, want ErrRefreshTokenInvalid", err)
+	}
+}
+
+func TestRotateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRefreshTokenRepository(openTestDB(t))
+	seedToken(t, repo, "h-race", time.Hour)
+
+	const workers = 8
+	errs := make([]error, workers)
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			next := &RefreshToken{TokenHash: "h-race-" + string(rune('a'+i)), ExpiresAt: time.Now().Add(time.Hour).UTC()}
+			_, errs[i] = repo.Rotate(ctx, "h-race", next)
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded, reused := 0, 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrRefreshTokenReused):
+			reused++
+		case errors.Is(err, ErrRefreshTokenInvalid):
+			// Family sudah dicabut oleh pemakai ulang sebelumnya
+		default:
+			t.Errorf("worker %d: error tak terduga %v", i, err)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d rotasi berhasil, want tepat 1", succeeded)
+	}
+	if reused == 0 {
+		t.Errorf("tidak ada rotasi yang dianggap reuse")
+	}
+}
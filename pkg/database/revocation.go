@@ -0,0 +1,58 @@
+// pkg/database/revocation.go
+package database
+
+import (
+	"context"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RevokedToken adalah entri daftar pencabutan access token yang tersimpan.
+type RevokedToken struct {
+	Kind      string `gorm:"primaryKey;size:16"`
+	Subject   string `gorm:"primaryKey;size:64"` // jti atau user ID
+	RevokedAt time.Time
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// RevocationRepository menyimpan daftar pencabutan untuk auth.RevocationStore.
+type RevocationRepository struct {
+	db *gorm.DB
+}
+
+// NewRevocationRepository membuat repository daftar pencabutan.
+func NewRevocationRepository(db *gorm.DB) *RevocationRepository {
+	return &RevocationRepository{db: db}
+}
+
+// SaveRevocation menyimpan atau memperbarui satu entri pencabutan.
+func (r *RevocationRepository) SaveRevocation(ctx context.Context, rev auth.Revocation) error {
+	row := RevokedToken{Kind: rev.Kind, Subject: rev.Key, RevokedAt: rev.RevokedAt.UTC(), ExpiresAt: rev.ExpiresAt.UTC()}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "subject"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_at", "expires_at"}),
+	}).Create(&row).Error
+}
+
+// LoadRevocations membaca semua entri yang belum kedaluwarsa.
+func (r *RevocationRepository) LoadRevocations(ctx context.Context, now time.Time) ([]auth.Revocation, error) {
+	var rows []RevokedToken
+	if err := r.db.WithContext(ctx).Where("expires_at > ?", now.UTC()).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	revs := make([]auth.Revocation, 0, len(rows))
+	for _, row := range rows {
+		revs = append(revs, auth.Revocation{Kind: row.Kind, Key: row.Subject, RevokedAt: row.RevokedAt, ExpiresAt: row.ExpiresAt})
+	}
+	return revs, nil
+}
+
+// DeleteExpiredRevocations menghapus entri yang kedaluwarsa sebelum waktu tertentu.
+func (r *RevocationRepository) DeleteExpiredRevocations(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at <= ?", before.UTC()).Delete(&RevokedToken{})
+	return res.RowsAffected, res.Error
+}
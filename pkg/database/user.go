@@ -0,0 +1,144 @@
+// pkg/database/user.go
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUserNotFound dikembalikan jika username tidak terdaftar.
+	ErrUserNotFound = errors.New("pengguna tidak ditemukan")
+	// ErrUserExists dikembalikan saat membuat pengguna dengan username yang sudah ada.
+	ErrUserExists = errors.New("username sudah terdaftar")
+)
+
+// User adalah akun yang bisa login ke gateway.
+type User struct {
+	ID           string   `gorm:"primaryKey;size:36"`
+	Username     string   `gorm:"uniqueIndex;size:128;not null"`
+	PasswordHash string   `gorm:"size:255;not null"`
+	Roles        []string `gorm:"serializer:json"`
+	Disabled     bool     `gorm:"not null;default:false"`
+	LastLoginAt  *time.Time
+
+	// MFASecret berisi secret TOTP (base32). Selama enrollment belum
+	// dikonfirmasi secret sudah terisi tetapi MFAEnabled masih false.
+	MFASecret   string `gorm:"column:mfa_secret;size:64"`
+	MFAEnabled  bool   `gorm:"column:mfa_enabled;not null;default:false"`
+	MFALastStep int64  `gorm:"column:mfa_last_step;not null;default:0"` // Langkah waktu TOTP terakhir yang dipakai
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// BeforeCreate mengisi ID dengan UUIDv7 jika belum ada.
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == "" {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return err
+		}
+		u.ID = id.String()
+	}
+	return nil
+}
+
+// HasRole melaporkan apakah pengguna memiliki role tertentu.
+func (u *User) HasRole(role string) bool {
+	for _, r := range u.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// UserRepository menyimpan dan membaca akun pengguna.
+type UserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository membuat repository pengguna di atas koneksi GORM.
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// normalizeUsername membuat username tidak peka huruf besar/kecil.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// Create menyimpan pengguna baru. PasswordHash harus sudah berisi hash.
+func (r *UserRepository) Create(ctx context.Context, user *User) error {
+	user.Username = normalizeUsername(user.Username)
+	if _, err := r.FindByUsername(ctx, user.Username); err == nil {
+		return ErrUserExists
+	} else if !errors.Is(err, ErrUserNotFound) {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(user).Error
+}
+
+// FindByUsername mencari pengguna berdasarkan username.
+func (r *UserRepository) FindByUsername(ctx context.Context, username string) (*User, error) {
+	var user User
+	err := r.db.WithContext(ctx).Where("username = ?", normalizeUsername(username)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// FindByID mencari pengguna berdasarkan ID.
+func (r *UserRepository) FindByID(ctx context.Context, id string) (*User, error) {
+	var user User
+	err := r.db.WithContext(ctx).Where("id = ?", id).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// List mengembalikan semua pengguna urut berdasarkan username.
+func (r *UserRepository) List(ctx context.Context) ([]User, error) {
+	var users []User
+	err := r.db.WithContext(ctx).Order("username").Find(&users).Error
+	return users, err
+}
+
+// SetPasswordHash mengganti hash password pengguna.
+func (r *UserRepository) SetPasswordHash(ctx context.Context, username, hash string) error {
+	return r.update(ctx, username, map[string]interface{}{"password_hash": hash})
+}
+
+// SetDisabled menonaktifkan atau mengaktifkan kembali pengguna.
+func (r *UserRepository) SetDisabled(ctx context.Context, username string, disabled bool) error {
+	return r.update(ctx, username, map[string]interface{}{"disabled": disabled})
+}
+
+// RecordLogin mencatat waktu login terakhir.
+func (r *UserRepository) RecordLogin(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("last_login_at", now()).Error
+}
+
+func (r *UserRepository) update(ctx context.Context, username string, fields map[string]interface{}) error {
+	res := r.db.WithContext(ctx).Model(&User{}).Where("username = ?", normalizeUsername(username)).Updates(fields)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
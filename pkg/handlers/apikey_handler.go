@@ -0,0 +1,352 @@
+// pkg/handlers/apikey_handler.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConsumerRequest adalah body untuk membuat atau mengubah consumer.
+type ConsumerRequest struct {
+	Name      string         `json:"name"`
+	Roles     []string       `json:"roles"`
+	RateLimit RateLimitInput `json:"rate_limit"`
+}
+
+// RateLimitInput adalah rate limit consumer; nol berarti hanya RATE_LIMIT global.
+type RateLimitInput struct {
+	Requests  int `json:"requests"`
+	WindowSec int `json:"window_sec"`
+}
+
+// CreateAPIKeyRequest adalah body opsional untuk membuat API key.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`       // Label bebas
+	ExpiresIn string     `json:"expires_in"` // Durasi Go, misal "720h"
+	ExpiresAt *time.Time `json:"expires_at"` // Alternatif ExpiresIn
+}
+
+// ExpireAPIKeyRequest adalah body opsional untuk mengatur kedaluwarsa API key.
+// Jika kosong, key langsung kedaluwarsa.
+type ExpireAPIKeyRequest struct {
+	ExpiresIn string     `json:"expires_in"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ConsumerView adalah representasi consumer di admin API.
+type ConsumerView struct {
+	ID        string         `json:"id"`
+	Name      string         `json:"name"`
+	Roles     []string       `json:"roles"`
+	RateLimit RateLimitInput `json:"rate_limit"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// APIKeyView adalah representasi API key di admin API. Key lengkap tidak
+// pernah ditampilkan, hanya prefix-nya.
+type APIKeyView struct {
+	ID         string     `json:"id"`
+	Prefix     string     `json:"prefix"`
+	Consumer   string     `json:"consumer"`
+	Name       string     `json:"name,omitempty"`
+	Status     string     `json:"status"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewConsumerView mengubah consumer database menjadi ConsumerView.
+func NewConsumerView(c database.Consumer) ConsumerView {
+	roles := c.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	return ConsumerView{
+		ID:        c.ID,
+		Name:      c.Name,
+		Roles:     roles,
+		RateLimit: RateLimitInput{Requests: c.RateLimitRequests, WindowSec: c.RateLimitWindowSec},
+		CreatedAt: c.CreatedAt,
+	}
+}
+
+// NewAPIKeyView mengubah API key database menjadi APIKeyView.
+func NewAPIKeyView(k database.APIKey) APIKeyView {
+	return APIKeyView{
+		ID:         k.ID,
+		Prefix:     k.Prefix,
+		Consumer:   k.Consumer.Name,
+		Name:       k.Name,
+		Status:     k.Status(time.Now()),
+		ExpiresAt:  k.ExpiresAt,
+		RevokedAt:  k.RevokedAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// IssueAPIKey membuat API key baru untuk consumer dan mengembalikan key
+// lengkap, yang hanya bisa dilihat sekali ini. Dipakai juga oleh CLI.
+func IssueAPIKey(ctx context.Context, keys *database.APIKeyRepository, consumer *database.Consumer, name string, expiresAt *time.Time) (string, *database.APIKey, error) {
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+	record := &database.APIKey{
+		ConsumerID: consumer.ID,
+		Prefix:     prefix,
+		KeyHash:    hash,
+		Name:       name,
+		ExpiresAt:  expiresAt,
+	}
+	if err := keys.CreateKey(ctx, record); err != nil {
+		return "", nil, err
+	}
+	record.Consumer = *consumer
+	return key, record, nil
+}
+
+// ResolveExpiry menentukan waktu kedaluwarsa dari durasi (expiresIn) atau
+// waktu absolut (expiresAt). Mengembalikan nil jika keduanya kosong.
+func ResolveExpiry(expiresIn string, expiresAt *time.Time) (*time.Time, error) {
+	if expiresIn != "" && expiresAt != nil {
+		return nil, errors.New("isi expires_in atau expires_at, bukan keduanya")
+	}
+	if expiresIn != "" {
+		d, err := time.ParseDuration(expiresIn)
+		if err != nil {
+			return nil, err
+		}
+		if d < 0 {
+			return nil, errors.New("expires_in tidak boleh negatif")
+		}
+		t := time.Now().Add(d)
+		return &t, nil
+	}
+	return expiresAt, nil
+}
+
+// APIKeyAdminHandler menangani endpoint admin untuk consumer dan API key.
+type APIKeyAdminHandler struct {
+	keys *database.APIKeyRepository
+}
+
+// NewAPIKeyAdminHandler membuat APIKeyAdminHandler.
+func NewAPIKeyAdminHandler(keys *database.APIKeyRepository) *APIKeyAdminHandler {
+	return &APIKeyAdminHandler{keys: keys}
+}
+
+// ListConsumers menangani GET /admin/consumers.
+func (h *APIKeyAdminHandler) ListConsumers(c *gin.Context) {
+	consumers, err := h.keys.ListConsumers(c.Request.Context())
+	if err != nil {
+		abortInternal(c, err)
+		return
+	}
+	views := make([]ConsumerView, 0, len(consumers))
+	for _, consumer := range consumers {
+		views = append(views, NewConsumerView(consumer))
+	}
+	c.JSON(http.StatusOK, gin.H{"consumers": views})
+}
+
+// CreateConsumer menangani POST /admin/consumers.
+func (h *APIKeyAdminHandler) CreateConsumer(c *gin.Context) {
+	var req ConsumerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+		return
+	}
+	if req.Name == "" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "name is required"))
+		return
+	}
+	if !validRateLimit(c, req.RateLimit) {
+		return
+	}
+	consumer := &database.Consumer{
+		Name:               req.Name,
+		Roles:              req.Roles,
+		RateLimitRequests:  req.RateLimit.Requests,
+		RateLimitWindowSec: req.RateLimit.WindowSec,
+	}
+	err := h.keys.CreateConsumer(c.Request.Context(), consumer)
+	if errors.Is(err, database.ErrConsumerExists) {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeConsumerExists, "Consumer already exists"))
+		return
+	}
+	if err != nil {
+		abortInternal(c, err)
+		return
+	}
+	requestid.Logf(c.Request.Context(), "[AUTH] Admin membuat consumer %s", consumer.Name)
+	c.JSON(http.StatusCreated, NewConsumerView(*consumer))
+}
+
+// UpdateConsumer menangani PUT /admin/consumers/:name dan mengganti role serta
+// rate limit consumer.
+func (h *APIKeyAdminHandler) UpdateConsumer(c *gin.Context) {
+	var req ConsumerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+		return
+	}
+	if !validRateLimit(c, req.RateLimit) {
+		return
+	}
+	ctx := c.Request.Context()
+	err := h.keys.UpdateConsumer(ctx, c.Param("name"), req.Roles, req.RateLimit.Requests, req.RateLimit.WindowSec)
+	if h.abortKeyError(c, err) {
+		return
+	}
+	consumer, err := h.keys.FindConsumer(ctx, c.Param("name"))
+	if h.abortKeyError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, NewConsumerView(*consumer))
+}
+
+// DeleteConsumer menangani DELETE /admin/consumers/:name. Semua API key
+// consumer ikut dihapus.
+func (h *APIKeyAdminHandler) DeleteConsumer(c *gin.Context) {
+	if h.abortKeyError(c, h.keys.DeleteConsumer(c.Request.Context(), c.Param("name"))) {
+		return
+	}
+	requestid.Logf(c.Request.Context(), "[AUTH] Admin menghapus consumer %s", c.Param("name"))
+	c.Status(http.StatusNoContent)
+}
+
+// ListKeys menangani GET /admin/consumers/:name/keys.
+func (h *APIKeyAdminHandler) ListKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+	consumer, err := h.keys.FindConsumer(ctx, c.Param("name"))
+	if h.abortKeyError(c, err) {
+		return
+	}
+	keys, err := h.keys.ListKeys(ctx, consumer.ID)
+	if err != nil {
+		abortInternal(c, err)
+		return
+	}
+	views := make([]APIKeyView, 0, len(keys))
+	for _, key := range keys {
+		views = append(views, NewAPIKeyView(key))
+	}
+	c.JSON(http.StatusOK, gin.H{"keys": views})
+}
+
+// CreateKey menangani POST /admin/consumers/:name/keys. Key lengkap hanya
+// dikembalikan di response ini.
+func (h *APIKeyAdminHandler) CreateKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+			return
+		}
+	}
+	expiresAt, err := ResolveExpiry(req.ExpiresIn, req.ExpiresAt)
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, err.Error()))
+		return
+	}
+	ctx := c.Request.Context()
+	consumer, err := h.keys.FindConsumer(ctx, c.Param("name"))
+	if h.abortKeyError(c, err) {
+		return
+	}
+	key, record, err := IssueAPIKey(ctx, h.keys, consumer, req.Name, expiresAt)
+	if err != nil {
+		abortInternal(c, err)
+		return
+	}
+	requestid.Logf(ctx, "[AUTH] Admin membuat API key %s untuk consumer %s", record.Prefix, consumer.Name)
+	c.JSON(http.StatusCreated, gin.H{
+		"key":     key,
+		"api_key": NewAPIKeyView(*record),
+	})
+}
+
+// RevokeKey menangani POST /admin/api-keys/:prefix/revoke.
+func (h *APIKeyAdminHandler) RevokeKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	if h.abortKeyError(c, h.keys.RevokeKey(ctx, c.Param("prefix"))) {
+		return
+	}
+	requestid.Logf(ctx, "[AUTH] Admin mencabut API key %s", c.Param("prefix"))
+	h.writeKey(c, c.Param("prefix"))
+}
+
+// ExpireKey menangani POST /admin/api-keys/:prefix/expire. Tanpa body, key
+// langsung kedaluwarsa.
+func (h *APIKeyAdminHandler) ExpireKey(c *gin.Context) {
+	var req ExpireAPIKeyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+			return
+		}
+	}
+	expiresAt, err := ResolveExpiry(req.ExpiresIn, req.ExpiresAt)
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, err.Error()))
+		return
+	}
+	if expiresAt == nil {
+		t := time.Now()
+		expiresAt = &t
+	}
+	ctx := c.Request.Context()
+	if h.abortKeyError(c, h.keys.SetKeyExpiry(ctx, c.Param("prefix"), *expiresAt)) {
+		return
+	}
+	requestid.Logf(ctx, "[AUTH] Admin mengatur API key %s kedaluwarsa pada %s", c.Param("prefix"), expiresAt.Format(time.RFC3339))
+	h.writeKey(c, c.Param("prefix"))
+}
+
+func (h *APIKeyAdminHandler) writeKey(c *gin.Context, prefix string) {
+	key, err := h.keys.FindKey(c.Request.Context(), prefix)
+	if h.abortKeyError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, NewAPIKeyView(*key))
+}
+
+// abortKeyError mengirim problem yang sesuai untuk err dan melaporkan apakah
+// request sudah dihentikan.
+func (h *APIKeyAdminHandler) abortKeyError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, database.ErrConsumerNotFound):
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeConsumerNotFound, "Consumer not found"))
+	case errors.Is(err, database.ErrAPIKeyNotFound):
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeAPIKeyNotFound, "API key not found"))
+	default:
+		abortInternal(c, err)
+	}
+	return true
+}
+
+func validRateLimit(c *gin.Context, rl RateLimitInput) bool {
+	if rl.Requests < 0 || rl.WindowSec < 0 || (rl.Requests > 0) != (rl.WindowSec > 0) {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "rate_limit requires both requests and window_sec, or neither"))
+		return false
+	}
+	return true
+}
+
+func abortInternal(c *gin.Context, err error) {
+	requestid.Logf(c.Request.Context(), "Kesalahan admin API key: %v", err)
+	problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Internal server error"))
+}
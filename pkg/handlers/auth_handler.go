@@ -2,82 +2,399 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
+	"api-gateway-go/pkg/auth"
 	"api-gateway-go/pkg/config" // Sesuaikan dengan nama modul Anda
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 // Credentials adalah struktur untuk data login yang diterima
 type Credentials struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Session  bool   `json:"session"` // Kirim token sebagai cookie sesi (AUTH.SESSION)
+}
+
+// RefreshRequest adalah body untuk POST /auth/refresh.
+// Dalam mode sesi, body boleh kosong dan refresh token dibaca dari cookie.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// LogoutRequest adalah body opsional untuk POST /auth/logout.
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"` // Ikut dicabut beserta family-nya jika diisi
+	All          bool   `json:"all"`           // Cabut semua token pengguna di semua perangkat
 }
 
 // Claims adalah struktur untuk data yang akan disimpan dalam token JWT
-type Claims struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	jwt.RegisteredClaims
-}
-
-// LoginHandler menangani permintaan login dan menghasilkan token JWT
-func LoginHandler(appConfig config.Config) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var creds Credentials
-		if err := c.ShouldBindJSON(&creds); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload: " + err.Error()})
+type Claims = auth.Claims
+
+// AuthHandler menangani login dan pembaruan token.
+type AuthHandler struct {
+	issuer        *auth.Issuer
+	keys          *auth.KeySet
+	mfa           config.MFAConfig
+	session       config.SessionConfig
+	refreshTTL    time.Duration
+	users         *database.UserRepository
+	refreshTokens *database.RefreshTokenRepository
+	revoked       *auth.RevocationStore
+	guard         *auth.LoginGuard // nil berarti AUTH.LOGIN_PROTECTION dimatikan
+	passwords     *auth.PasswordLimiter
+	passwordHash  string // AUTH.PASSWORD_HASH, untuk verifikasi dummy
+}
+
+// NewAuthHandler membuat AuthHandler dari konfigurasi AUTH. keys berisi kunci
+// aktif untuk menandatangani access token. guard dan passwords boleh nil.
+func NewAuthHandler(appConfig config.Config, keys *auth.KeySet, users *database.UserRepository, refreshTokens *database.RefreshTokenRepository, revoked *auth.RevocationStore, guard *auth.LoginGuard, passwords *auth.PasswordLimiter) *AuthHandler {
+	return &AuthHandler{
+		issuer:        auth.NewIssuer(keys, appConfig.Auth.AccessTokenTTL),
+		keys:          keys,
+		mfa:           appConfig.Auth.MFA,
+		session:       appConfig.Auth.Session,
+		refreshTTL:    appConfig.Auth.RefreshTokenTTL,
+		users:         users,
+		refreshTokens: refreshTokens,
+		revoked:       revoked,
+		guard:         guard,
+		passwords:     passwords,
+		passwordHash:  appConfig.Auth.PasswordHash,
+	}
+}
+
+// Login menangani permintaan login dan menghasilkan access token JWT serta
+// refresh token. Kredensial divalidasi terhadap tabel pengguna di database.
+// Untuk pengguna dengan MFA aktif (atau wajib karena role-nya), response
+// berisi mfa_token yang harus ditukar di /auth/mfa/verify atau dipakai untuk
+// enrollment.
+func (h *AuthHandler) Login(c *gin.Context) {
+	var creds Credentials
+	if err := c.ShouldBindJSON(&creds); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+		return
+	}
+	session, ok := h.sessionRequested(c, creds.Session)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+	if h.guard != nil {
+		// Diperiksa sebelum membaca database dan tanpa melihat apakah username
+		// terdaftar, sehingga respons ini tidak membocorkan keberadaan akun
+		if wait := h.guard.Attempt(creds.Username, ip); wait > 0 {
+			abortThrottled(c, wait)
 			return
 		}
+	}
 
-		// --- MULAI VALIDASI KREDENSIAL (SIMULASI) ---
-		// Di aplikasi nyata, Anda akan memvalidasi username dan password dengan database.
-		// Untuk contoh ini, kita akan hardcode kredensial yang valid.
-		var userID string
-		if creds.Username == "user123" && creds.Password == "password123" {
-			userID = "USR_001" // ID pengguna dari database
-		} else if creds.Username == "admin" && creds.Password == "adminpass" {
-			userID = "ADM_001"
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+	user, err := h.users.FindByUsername(ctx, creds.Username)
+	if errors.Is(err, database.ErrUserNotFound) {
+		// Tetap hitung hash agar waktu respons sama dengan username yang ada
+		if err := h.passwords.VerifyDummy(ctx, creds.Password, h.passwordHash); err != nil {
+			h.passwordBusy(c, creds.Username, ip, err)
 			return
 		}
-		// --- SELESAI VALIDASI KREDENSIAL (SIMULASI) ---
-
-		// Tentukan waktu kedaluwarsa token (misalnya, 1 jam)
-		expirationTime := time.Now().Add(1 * time.Hour)
-
-		// Buat claims
-		claims := &Claims{
-			UserID:   userID,
-			Username: creds.Username,
-			RegisteredClaims: jwt.RegisteredClaims{
-				ExpiresAt: jwt.NewNumericDate(expirationTime),
-				IssuedAt:  jwt.NewNumericDate(time.Now()),
-				NotBefore: jwt.NewNumericDate(time.Now()),
-				Issuer:    "api-gateway", // Nama penerbit token
-				Subject:   userID,        // Subjek token (seringkali ID pengguna)
-			},
+		h.loginFailed(c, creds.Username, ip)
+		return
+	}
+	if err != nil {
+		requestid.Logf(ctx, "Gagal membaca pengguna %q: %v", creds.Username, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not verify credentials"))
+		return
+	}
+	var valid bool
+	if user.PasswordHash == "" {
+		// Pengguna dari login OIDC tidak punya password lokal
+		err = h.passwords.VerifyDummy(ctx, creds.Password, h.passwordHash)
+	} else {
+		valid, err = h.passwords.Verify(ctx, user.PasswordHash, creds.Password)
+	}
+	if errors.Is(err, auth.ErrPasswordBusy) {
+		h.passwordBusy(c, creds.Username, ip, err)
+		return
+	}
+	if err != nil {
+		requestid.Logf(ctx, "Hash password pengguna %q tidak valid: %v", user.Username, err)
+	}
+	if !valid {
+		h.loginFailed(c, creds.Username, ip)
+		return
+	}
+	if user.Disabled {
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAccountDisabled, "This account has been disabled"))
+		return
+	}
+	if user.MFAEnabled || h.mfa.Required(user.Roles) {
+		// Hitungan kegagalan baru direset setelah faktor kedua lolos, agar
+		// login ulang dengan password yang benar tidak memberi jatah tebakan
+		// kode TOTP baru
+		if h.guard != nil {
+			h.guard.Release(creds.Username, ip)
+		}
+		h.writeMFAChallenge(c, user, session)
+		return
+	}
+	h.completeLogin(c, user, tokenDelivery{session: session}, nil)
+}
+
+// completeLogin mencatat login dan menerbitkan token untuk pengguna yang sudah
+// lolos semua faktor autentikasi. extra ditambahkan ke response token.
+func (h *AuthHandler) completeLogin(c *gin.Context, user *database.User, delivery tokenDelivery, extra gin.H) {
+	ctx := c.Request.Context()
+	if h.guard != nil {
+		h.guard.Succeeded(user.Username, c.ClientIP())
+	}
+	if err := h.users.RecordLogin(ctx, user.ID); err != nil {
+		requestid.Logf(ctx, "Gagal mencatat login pengguna %q: %v", user.Username, err)
+	}
+
+	// Login selalu memulai family refresh token baru
+	refreshToken, record, err := h.newRefreshToken()
+	if err == nil {
+		record.UserID = user.ID
+		err = h.refreshTokens.Create(ctx, record)
+	}
+	if err != nil {
+		requestid.Logf(ctx, "Gagal menyimpan refresh token pengguna %q: %v", user.Username, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not generate token"))
+		return
+	}
+	h.writeTokens(c, user, refreshToken, record.ExpiresAt, delivery, extra)
+}
+
+// loginFailed mencatat kegagalan login lalu mengirim 401 yang sama untuk
+// username terdaftar maupun tidak.
+func (h *AuthHandler) loginFailed(c *gin.Context, username, ip string) {
+	h.recordFailure(c, username, ip)
+	problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials, "Invalid username or password"))
+}
+
+// recordFailure mencatat kegagalan ke guard dan menulis audit jika username
+// atau IP baru saja dikunci.
+func (h *AuthHandler) recordFailure(c *gin.Context, username, ip string) {
+	if h.guard == nil {
+		return
+	}
+	for _, kind := range h.guard.Failed(username, ip) {
+		subject := ip
+		if kind == auth.LoginSubjectUser {
+			subject = username
 		}
+		requestid.Logf(c.Request.Context(), "[AUDIT] event=login_lockout kind=%s subject=%q ip=%s", kind, subject, ip)
+	}
+}
 
-		// Buat token baru dengan metode signing HS256 dan claims
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+// passwordBusy mengirim 503 saat verifikasi password tidak mendapat slot
+// PasswordLimiter. Percobaan ini tidak dihitung sebagai kegagalan login.
+func (h *AuthHandler) passwordBusy(c *gin.Context, username, ip string, err error) {
+	if h.guard != nil {
+		h.guard.Release(username, ip)
+	}
+	requestid.Logf(c.Request.Context(), "[AUTH] Login %q dari %s ditolak: %v", username, ip, err)
+	c.Header("Retry-After", "1")
+	problem.Abort(c, problem.New(http.StatusServiceUnavailable, problem.CodeLoginBusy,
+		"Too many logins are being processed. Please try again shortly.").
+		With("retry_after", 1))
+}
 
-		// Dapatkan string token yang ditandatangani menggunakan secret dari config
-		tokenString, err := token.SignedString([]byte(appConfig.AuthSecret))
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not generate token"})
+// abortThrottled mengirim 429 untuk percobaan yang ditolak LoginGuard.
+func abortThrottled(c *gin.Context, wait time.Duration) {
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeLoginThrottled,
+		"Too many failed login attempts. Please try again later.").
+		With("retry_after", retryAfter))
+}
+
+// Refresh menukar refresh token dengan access token baru. Refresh token lama
+// langsung dirotasi; memakai ulang token yang sudah dirotasi mencabut seluruh
+// family sehingga pencuri maupun pemilik asli harus login ulang.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+			return
+		}
+	}
+	var delivery tokenDelivery
+	if req.RefreshToken == "" {
+		token, csrf, ok := h.sessionRefreshToken(c)
+		if !ok {
 			return
 		}
+		req.RefreshToken, delivery = token, tokenDelivery{session: true, csrf: csrf}
+	}
+	if req.RefreshToken == "" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "refresh_token is required"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	refreshToken, next, err := h.newRefreshToken()
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not generate token"))
+		return
+	}
+	current, err := h.refreshTokens.Rotate(ctx, auth.HashRefreshToken(req.RefreshToken), next)
+	switch {
+	case errors.Is(err, database.ErrRefreshTokenReused):
+		if delivery.session {
+			h.clearSessionCookies(c)
+		}
+		requestid.Logf(ctx, "[AUTH] Refresh token dipakai ulang, family %s milik UserID %s dicabut", current.FamilyID, current.UserID)
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeRefreshTokenReused, "Refresh token has already been used; all sessions from this login have been revoked"))
+		return
+	case errors.Is(err, database.ErrRefreshTokenInvalid):
+		if delivery.session {
+			h.clearSessionCookies(c)
+		}
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeRefreshTokenInvalid, "Refresh token is invalid or expired"))
+		return
+	case err != nil:
+		requestid.Logf(ctx, "Gagal merotasi refresh token: %v", err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not refresh token"))
+		return
+	}
+
+	// Pengguna bisa dihapus atau dinonaktifkan setelah refresh token diterbitkan
+	user, err := h.users.FindByID(ctx, current.UserID)
+	if err == nil && user.Disabled {
+		if err := h.refreshTokens.RevokeFamily(ctx, current.FamilyID); err != nil {
+			requestid.Logf(ctx, "Gagal mencabut family refresh token %s: %v", current.FamilyID, err)
+		}
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAccountDisabled, "This account has been disabled"))
+		return
+	}
+	if errors.Is(err, database.ErrUserNotFound) {
+		if err := h.refreshTokens.RevokeFamily(ctx, current.FamilyID); err != nil {
+			requestid.Logf(ctx, "Gagal mencabut family refresh token %s: %v", current.FamilyID, err)
+		}
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeRefreshTokenInvalid, "Refresh token is invalid or expired"))
+		return
+	}
+	if err != nil {
+		requestid.Logf(ctx, "Gagal membaca pengguna %s: %v", current.UserID, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not refresh token"))
+		return
+	}
+
+	h.writeTokens(c, user, refreshToken, next.ExpiresAt, delivery, nil)
+}
 
-		c.JSON(http.StatusOK, gin.H{
-			"token":      tokenString,
-			"expires_at": expirationTime.Format(time.RFC3339),
-			"user_id":    userID,
-			"username":   creds.Username,
-		})
+// Logout mencabut access token yang dipakai untuk request ini. Refresh token
+// di body ikut dicabut beserta family-nya; "all": true mencabut semua access
+// dan refresh token pengguna. Harus dipasang di belakang AuthMiddleware.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+			return
+		}
+	}
+	claims := c.MustGet(auth.ClaimsContextKey).(*auth.Claims)
+	ctx := c.Request.Context()
+	if req.RefreshToken == "" && h.session.Enabled {
+		req.RefreshToken, _ = c.Cookie(h.session.RefreshCookieName)
+	}
+	h.clearSessionCookies(c)
+
+	if req.All {
+		if _, err := h.RevokeUser(ctx, claims.UserID); err != nil {
+			requestid.Logf(ctx, "Gagal mencabut token UserID %s: %v", claims.UserID, err)
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not revoke tokens"))
+			return
+		}
+		requestid.Logf(ctx, "[AUTH] Logout semua sesi UserID %s", claims.UserID)
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	if claims.ID != "" {
+		if err := h.revoked.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			// Pencabutan di memori tetap berlaku, hanya penyimpanannya yang gagal
+			requestid.Logf(ctx, "Gagal menyimpan pencabutan token %s: %v", claims.ID, err)
+		}
+	}
+	if req.RefreshToken != "" {
+		if err := h.refreshTokens.RevokeFamilyOf(ctx, auth.HashRefreshToken(req.RefreshToken), claims.UserID); err != nil {
+			requestid.Logf(ctx, "Gagal mencabut refresh token UserID %s: %v", claims.UserID, err)
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not revoke tokens"))
+			return
+		}
+	}
+	requestid.Logf(ctx, "[AUTH] Logout UserID %s, jti %s", claims.UserID, claims.ID)
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeUser mencabut semua access token dan refresh token milik pengguna,
+// lalu mengembalikan jumlah refresh token yang dicabut.
+func (h *AuthHandler) RevokeUser(ctx context.Context, userID string) (int64, error) {
+	return RevokeUserTokens(ctx, h.revoked, h.refreshTokens, userID, h.issuer.AccessTokenTTL())
+}
+
+// RevokeUserTokens mencabut semua access token (sampai accessTTL ke depan) dan
+// refresh token milik pengguna. Dipakai juga oleh endpoint admin.
+func RevokeUserTokens(ctx context.Context, revoked *auth.RevocationStore, refreshTokens *database.RefreshTokenRepository, userID string, accessTTL time.Duration) (int64, error) {
+	if err := revoked.RevokeUser(ctx, userID, accessTTL); err != nil {
+		// Pencabutan di memori tetap berlaku, hanya penyimpanannya yang gagal
+		requestid.Logf(ctx, "Gagal menyimpan pencabutan token UserID %s: %v", userID, err)
+	}
+	return refreshTokens.RevokeUser(ctx, userID)
+}
+
+// newRefreshToken membuat refresh token baru beserta record yang akan
+// disimpan; record hanya berisi hash token.
+func (h *AuthHandler) newRefreshToken() (string, *database.RefreshToken, error) {
+	token, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return "", nil, err
+	}
+	return token, &database.RefreshToken{TokenHash: hash, ExpiresAt: time.Now().Add(h.refreshTTL).UTC()}, nil
+}
+
+// writeTokens menerbitkan access token dan mengirim response token, di body
+// atau sebagai cookie sesi sesuai delivery. extra, jika tidak nil,
+// ditambahkan ke response.
+func (h *AuthHandler) writeTokens(c *gin.Context, user *database.User, refreshToken string, refreshExpiresAt time.Time, delivery tokenDelivery, extra gin.H) {
+	if delivery.session && h.session.Enabled {
+		h.writeSession(c, user, refreshToken, refreshExpiresAt, delivery, extra)
+		return
+	}
+	accessToken, claims, err := h.issuer.IssueAccessToken(user.ID, user.Username, user.Roles)
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not generate token"))
+		return
+	}
+
+	body := gin.H{
+		"access_token":       accessToken,
+		"token_type":         "Bearer",
+		"expires_in":         int(h.issuer.AccessTokenTTL().Seconds()),
+		"expires_at":         claims.ExpiresAt.Time.Format(time.RFC3339),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": refreshExpiresAt.Format(time.RFC3339),
+		"token":              accessToken, // Kompatibilitas dengan klien lama
+		"user_id":            user.ID,
+		"username":           user.Username,
+		"roles":              claims.Roles,
+	}
+	for k, v := range extra {
+		body[k] = v
 	}
+	c.JSON(http.StatusOK, body)
 }
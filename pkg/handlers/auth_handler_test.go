@@ -0,0 +1,157 @@
+// pkg/handlers/auth_handler_test.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// openTestDB membuka database SQLite baru di direktori sementara test.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := database.InitDB(config.DatabaseConfig{Driver: config.DriverSQLite, DSN: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// testConfig mengembalikan konfigurasi minimal untuk menerbitkan token HS256.
+func testConfig() config.Config {
+	var cfg config.Config
+	cfg.AuthSecret = "test-secret-yang-cukup-panjang-untuk-hs256"
+	cfg.Auth.AccessTokenTTL = 15 * time.Minute
+	cfg.Auth.RefreshTokenTTL = time.Hour
+	return cfg
+}
+
+// refreshFixture adalah AuthHandler di atas database SQLite sementara dengan
+// satu pengguna dan satu refresh token awal.
+type refreshFixture struct {
+	handler       *AuthHandler
+	users         *database.UserRepository
+	refreshTokens *database.RefreshTokenRepository
+	user          *database.User
+	initial       string
+}
+
+func newRefreshFixture(t *testing.T) *refreshFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	db := openTestDB(t)
+	cfg := testConfig()
+	keys, err := auth.LoadKeySet(cfg)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+
+	f := &refreshFixture{
+		users:         database.NewUserRepository(db),
+		refreshTokens: database.NewRefreshTokenRepository(db),
+	}
+	f.handler = NewAuthHandler(cfg, keys, f.users, f.refreshTokens, auth.NewRevocationStore(nil), nil, nil)
+	f.user = &database.User{Username: "alice", PasswordHash: "-", Roles: []string{"employee"}}
+	if err := f.users.Create(ctx, f.user); err != nil {
+		t.Fatalf("Create user: %v", err)
+	}
+	token, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.refreshTokens.Create(ctx, &database.RefreshToken{UserID: f.user.ID, TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour).UTC()}); err != nil {
+		t.Fatalf("Create refresh token: %v", err)
+	}
+	f.initial = token
+	return f
+}
+
+// refresh memanggil Refresh dan mengembalikan status, kode problem, dan
+// refresh token baru (jika berhasil).
+func (f *refreshFixture) refresh(t *testing.T, token string) (int, string, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body, _ := json.Marshal(RefreshRequest{RefreshToken: token})
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(string(body)))
+	c.Request.Header.Set("Content-Type", "application/json")
+	f.handler.Refresh(c)
+
+	var resp struct {
+		Code         string `json:"code"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response bukan JSON: %s", w.Body.String())
+	}
+	return w.Code, resp.Code, resp.RefreshToken
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	f := newRefreshFixture(t)
+	tokens := map[string]string{"initial": f.initial}
+
+	steps := []struct {
+		name       string
+		use        string // Kunci token di tokens
+		save       string // Simpan refresh token baru dengan kunci ini
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "rotasi pertama", use: "initial", save: "second", wantStatus: http.StatusOK},
+		{name: "rotasi kedua", use: "second", save: "third", wantStatus: http.StatusOK},
+		{name: "token awal dipakai ulang", use: "initial", wantStatus: http.StatusUnauthorized, wantCode: problem.CodeRefreshTokenReused},
+		{name: "token terbaru ikut dicabut", use: "third", wantStatus: http.StatusUnauthorized, wantCode: problem.CodeRefreshTokenInvalid},
+		{name: "token tidak dikenal", use: "unknown", wantStatus: http.StatusUnauthorized, wantCode: problem.CodeRefreshTokenInvalid},
+	}
+	tokens["unknown"] = "rt_tidak-pernah-diterbitkan"
+
+	for _, step := range steps {
+		status, code, next := f.refresh(t, tokens[step.use])
+		if status != step.wantStatus || code != step.wantCode {
+			t.Fatalf("%s: status %d kode %q, want %d %q", step.name, status, code, step.wantStatus, step.wantCode)
+		}
+		if step.save != "" {
+			if next == "" || next == tokens[step.use] {
+				t.Fatalf("%s: refresh token tidak dirotasi", step.name)
+			}
+			tokens[step.save] = next
+		}
+	}
+}
+
+func TestRefreshDisabledUser(t *testing.T) {
+	f := newRefreshFixture(t)
+	if err := f.users.SetDisabled(context.Background(), f.user.Username, true); err != nil {
+		t.Fatal(err)
+	}
+	if status, code, _ := f.refresh(t, f.initial); status != http.StatusForbidden || code != problem.CodeAccountDisabled {
+		t.Fatalf("status %d kode %q, want 403 %s", status, code, problem.CodeAccountDisabled)
+	}
+	// Family dicabut, jadi mengaktifkan kembali akun tidak menghidupkan token lama
+	if err := f.users.SetDisabled(context.Background(), f.user.Username, false); err != nil {
+		t.Fatal(err)
+	}
+	if status, code, _ := f.refresh(t, f.initial); status != http.StatusUnauthorized || code != problem.CodeRefreshTokenInvalid {
+		t.Fatalf("setelah enable: status %d kode %q, want 401 %s", status, code, problem.CodeRefreshTokenInvalid)
+	}
+}
@@ -0,0 +1,139 @@
+// pkg/handlers/forwarded.go
+package handlers
+
+import (
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/upstream"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// GatewayTimestampHeader berisi waktu gateway meneruskan request (RFC 3339, UTC).
+const GatewayTimestampHeader = "X-Gateway-Timestamp"
+
+// forwardingHeaders adalah header yang hanya dipercaya jika dikirim oleh proxy
+// di TRUSTED_PROXIES. Dari klien lain header ini dibuang sebelum diteruskan.
+var forwardingHeaders = []string{
+	"Forwarded",
+	"X-Forwarded-For",
+	"X-Forwarded-Proto",
+	"X-Forwarded-Host",
+	"X-Forwarded-Prefix",
+	"X-Real-IP",
+}
+
+// TrustedProxies adalah daftar jaringan proxy yang header forwarding-nya dipercaya.
+type TrustedProxies []netip.Prefix
+
+// NewTrustedProxies mengurai entri TRUSTED_PROXIES. Entri yang tidak valid
+// dilewati; config.Validate sudah menolaknya saat konfigurasi dimuat.
+func NewTrustedProxies(entries []string) TrustedProxies {
+	var trusted TrustedProxies
+	for _, e := range entries {
+		if prefix, err := config.ParseTrustedProxy(e); err == nil {
+			trusted = append(trusted, prefix)
+		}
+	}
+	return trusted
+}
+
+// Contains melaporkan apakah peer dengan remoteAddr (host:port) adalah proxy tepercaya.
+func (t TrustedProxies) Contains(remoteAddr string) bool {
+	addr, ok := remoteIP(remoteAddr)
+	if !ok {
+		return false
+	}
+	for _, prefix := range t {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func remoteIP(remoteAddr string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// setForwardingHeaders menambahkan informasi hop gateway ke request yang akan
+// diteruskan: Forwarded (RFC 7239), X-Forwarded-Proto, X-Forwarded-Host,
+// X-Forwarded-Prefix, X-Real-IP, dan X-Gateway-Timestamp. Jika peer bukan
+// proxy tepercaya, header forwarding dari klien dibuang lebih dulu.
+//
+// X-Forwarded-For tidak diisi di sini: httputil.ReverseProxy menambahkan IP
+// peer ke rantai yang ada setelah Director dijalankan.
+func setForwardingHeaders(req *http.Request, trusted TrustedProxies, prefix string) {
+	if !trusted.Contains(req.RemoteAddr) {
+		for _, h := range forwardingHeaders {
+			req.Header.Del(h)
+		}
+	}
+
+	proto := "http"
+	if req.TLS != nil {
+		proto = "https"
+	}
+
+	element := "for=" + forwardedNode(req.RemoteAddr) + ";host=" + forwardedValue(req.Host) + ";proto=" + proto
+	if prior := req.Header.Values("Forwarded"); len(prior) > 0 {
+		element = strings.Join(prior, ", ") + ", " + element
+	}
+	req.Header.Set("Forwarded", element)
+
+	if req.Header.Get("X-Forwarded-Proto") == "" {
+		req.Header.Set("X-Forwarded-Proto", proto)
+	}
+	if req.Header.Get("X-Forwarded-Host") == "" {
+		req.Header.Set("X-Forwarded-Host", req.Host)
+	}
+	if prefix != "" {
+		// Prefix dari proxy sebelumnya tetap di depan prefix yang dihapus gateway
+		req.Header.Set("X-Forwarded-Prefix", strings.TrimRight(req.Header.Get("X-Forwarded-Prefix"), "/")+prefix)
+	}
+	if ip := upstream.ClientIP(req); ip != "" {
+		req.Header.Set("X-Real-IP", ip)
+	}
+	req.Header.Set(GatewayTimestampHeader, time.Now().UTC().Format(time.RFC3339Nano))
+}
+
+// forwardedNode memformat alamat peer untuk parameter for= di header Forwarded.
+// Alamat IPv6 wajib diapit kurung siku dan tanda kutip (RFC 7239 bagian 6).
+func forwardedNode(remoteAddr string) string {
+	addr, ok := remoteIP(remoteAddr)
+	if !ok {
+		return "unknown"
+	}
+	if addr.Is6() {
+		return `"[` + addr.String() + `]"`
+	}
+	return addr.String()
+}
+
+// forwardedValue mengutip nilai yang bukan token HTTP, misal host dengan port.
+func forwardedValue(v string) string {
+	for _, r := range v {
+		if !isTokenChar(r) {
+			return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(v) + `"`
+		}
+	}
+	return v
+}
+
+func isTokenChar(r rune) bool {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		return true
+	}
+	return strings.ContainsRune("!#$%&'*+-.^_`|~", r)
+}
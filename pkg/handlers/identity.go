@@ -0,0 +1,99 @@
+// pkg/handlers/identity.go
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/requestid"
+)
+
+// IdentityHeaders menghapus header identitas kiriman klien dan mengisinya
+// ulang dari claims hasil autentikasi sebelum request diteruskan ke upstream.
+type IdentityHeaders struct {
+	strip        []string
+	headers      map[string]string // Nama klaim -> nama header
+	signedHeader string
+	signedTTL    time.Duration
+	keys         *auth.KeySet
+	audience     string
+}
+
+// NewIdentityHeaders membuat IdentityHeaders untuk satu rute. strip berisi
+// semua header identitas yang dikenal gateway (config.IdentityHeaderNames),
+// audience adalah nama service upstream untuk klaim aud di SIGNED_HEADER.
+func NewIdentityHeaders(cfg config.IdentityConfig, strip []string, keys *auth.KeySet, audience string) *IdentityHeaders {
+	cfg = cfg.WithDefaults()
+	return &IdentityHeaders{
+		strip:        strip,
+		headers:      cfg.Headers,
+		signedHeader: cfg.SignedHeader,
+		signedTTL:    cfg.SignedTTL,
+		keys:         keys,
+		audience:     audience,
+	}
+}
+
+// Apply menghapus header identitas dari req lalu, jika claims tidak nil,
+// mengisi header sesuai pemetaan klaim rute.
+func (ih *IdentityHeaders) Apply(req *http.Request, claims *auth.Claims) {
+	for _, h := range ih.strip {
+		req.Header.Del(h)
+	}
+	if claims == nil {
+		return
+	}
+
+	for claim, header := range ih.headers {
+		if header == "" {
+			continue
+		}
+		if value := identityClaim(claims, claim); value != "" {
+			req.Header.Set(header, sanitizeHeaderValue(value))
+		}
+	}
+	if ih.signedHeader != "" {
+		assertion, err := ih.keys.SignIdentity(claims, ih.audience, ih.signedTTL)
+		if err != nil {
+			// Upstream yang mewajibkan header ini akan menolak request
+			requestid.Logf(req.Context(), "Gagal menandatangani identity assertion: %v", err)
+			return
+		}
+		req.Header.Set(ih.signedHeader, assertion)
+	}
+}
+
+// identityClaim mengembalikan nilai klaim untuk header identitas.
+func identityClaim(claims *auth.Claims, claim string) string {
+	switch claim {
+	case config.IdentityClaimUserID, config.IdentityClaimSubject:
+		return claims.UserID
+	case config.IdentityClaimUsername:
+		return claims.Username
+	case config.IdentityClaimRoles:
+		return strings.Join(claims.Roles, ",")
+	case config.IdentityClaimScope:
+		return claims.Scope
+	case config.IdentityClaimIssuer:
+		return claims.Issuer
+	case config.IdentityClaimTokenID:
+		return claims.ID
+	case config.IdentityClaimClientID:
+		return claims.ClientID
+	}
+	return ""
+}
+
+// sanitizeHeaderValue membuang karakter kontrol (misal CR/LF dari klaim IdP
+// eksternal) yang membuat header tidak valid.
+func sanitizeHeaderValue(v string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, v)
+}
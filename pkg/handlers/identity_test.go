@@ -0,0 +1,81 @@
+// pkg/handlers/identity_test.go
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestIdentityHeadersApply(t *testing.T) {
+	cfg := testConfig()
+	keys, err := auth.LoadKeySet(cfg)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	public := config.RouteConfig{Upstream: "catalog"}
+	tenant := config.RouteConfig{Upstream: "tenant-service", IdentityHeaders: &config.IdentityConfig{
+		Headers:      map[string]string{config.IdentityClaimUserID: "X-Tenant-User", config.IdentityClaimUsername: "X-Tenant-Name"},
+		SignedHeader: "X-Gateway-Identity",
+	}}
+	cfg.Routes = []config.RouteConfig{public, tenant}
+	strip := cfg.IdentityHeaderNames()
+
+	// Header palsu dari klien, termasuk header milik rute lain
+	spoofed := map[string]string{
+		"X-User-ID":          "admin",
+		"X-Username":         "admin",
+		"X-User-Roles":       "admin",
+		"X-Tenant-User":      "admin",
+		"X-Tenant-Name":      "admin",
+		"X-Gateway-Identity": "eyJ.palsu.token",
+	}
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for h, v := range spoofed {
+			req.Header.Set(h, v)
+		}
+		req.Header.Set("X-Trace", "abc")
+		return req
+	}
+
+	t.Run("rute tanpa autentikasi", func(t *testing.T) {
+		req := newRequest()
+		NewIdentityHeaders(cfg.IdentityFor(public), strip, keys, public.Upstream).Apply(req, nil)
+		for h := range spoofed {
+			if v := req.Header.Get(h); v != "" {
+				t.Errorf("header %s = %q, want dihapus", h, v)
+			}
+		}
+		if req.Header.Get("X-Trace") != "abc" {
+			t.Error("header lain ikut dihapus")
+		}
+	})
+
+	t.Run("rute dengan pemetaan sendiri", func(t *testing.T) {
+		req := newRequest()
+		claims := &auth.Claims{UserID: "user-7", Username: "bob\r\nX-Evil: 1", Roles: []string{"employee"}}
+		NewIdentityHeaders(cfg.IdentityFor(tenant), strip, keys, tenant.Upstream).Apply(req, claims)
+
+		want := map[string]string{"X-Tenant-User": "user-7", "X-Tenant-Name": "bobX-Evil: 1", "X-User-ID": "", "X-Username": "", "X-User-Roles": ""}
+		for h, v := range want {
+			if got := req.Header.Get(h); got != v {
+				t.Errorf("header %s = %q, want %q", h, got, v)
+			}
+		}
+
+		assertion := &auth.Claims{}
+		token, err := jwt.ParseWithClaims(req.Header.Get("X-Gateway-Identity"), assertion, keys.Keyfunc, jwt.WithAudience(tenant.Upstream))
+		if err != nil {
+			t.Fatalf("identity assertion tidak valid: %v", err)
+		}
+		if token.Header["typ"] != auth.IdentityTokenType || assertion.UserID != "user-7" {
+			t.Errorf("assertion typ %v user %q, want %s user-7", token.Header["typ"], assertion.UserID, auth.IdentityTokenType)
+		}
+	})
+}
@@ -0,0 +1,177 @@
+// pkg/handlers/introspect_handler.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IntrospectionHandler menjawab pertanyaan upstream tentang status token
+// (RFC 7662) tanpa upstream perlu memverifikasi JWT sendiri.
+type IntrospectionHandler struct {
+	verifier      *auth.Verifier
+	revoked       *auth.RevocationStore
+	users         *database.UserRepository
+	refreshTokens *database.RefreshTokenRepository
+}
+
+// NewIntrospectionHandler membuat IntrospectionHandler. Access token diperiksa
+// dengan verifier dan revoked seperti di AuthMiddleware.
+func NewIntrospectionHandler(verifier *auth.Verifier, revoked *auth.RevocationStore, users *database.UserRepository, refreshTokens *database.RefreshTokenRepository) *IntrospectionHandler {
+	return &IntrospectionHandler{verifier: verifier, revoked: revoked, users: users, refreshTokens: refreshTokens}
+}
+
+// Introspect menangani POST /auth/introspect. Body berformat
+// application/x-www-form-urlencoded dengan field token dan token_type_hint
+// opsional. Token yang tidak valid, kedaluwarsa, atau dicabut menghasilkan
+// {"active": false}; token yang dicabut ditandai "revoked": true.
+func (h *IntrospectionHandler) Introspect(c *gin.Context) {
+	token := c.PostForm("token")
+	if token == "" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "token is required"))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+
+	var (
+		body gin.H
+		err  error
+	)
+	// token_type_hint hanya petunjuk (RFC 7662 bagian 2.1); format token
+	// sudah cukup untuk membedakan refresh token dari JWT
+	if auth.IsRefreshToken(token) {
+		body, err = h.introspectRefreshToken(c, token)
+	} else {
+		body = h.introspectAccessToken(c, token)
+	}
+	if err != nil {
+		requestid.Logf(c.Request.Context(), "Gagal introspeksi token: %v", err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not introspect token"))
+		return
+	}
+	requestid.Logf(c.Request.Context(), "[AUTH] Introspeksi token oleh %s: active=%v", c.GetString("username"), body["active"])
+	c.JSON(http.StatusOK, body)
+}
+
+func (h *IntrospectionHandler) introspectAccessToken(c *gin.Context, token string) gin.H {
+	claims, err := h.verifier.Verify(c.Request.Context(), token)
+	if err != nil {
+		return gin.H{"active": false}
+	}
+	if h.revoked != nil && h.revoked.IsRevoked(claims) {
+		return gin.H{"active": false, "revoked": true}
+	}
+
+	body := gin.H{
+		"active":     true,
+		"token_type": "Bearer",
+		"sub":        claims.Subject,
+		"user_id":    claims.UserID,
+		"username":   claims.Username,
+		"iss":        claims.Issuer,
+		"jti":        claims.ID,
+		"revoked":    false,
+	}
+	if len(claims.Roles) > 0 {
+		body["roles"] = claims.Roles
+	}
+	if claims.Scope != "" {
+		body["scope"] = claims.Scope
+	}
+	if claims.Machine() {
+		body["client_id"] = claims.ClientID
+	}
+	if len(claims.Audience) > 0 {
+		body["aud"] = claims.Audience
+	}
+	if claims.ExpiresAt != nil {
+		body["exp"] = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		body["iat"] = claims.IssuedAt.Unix()
+	}
+	if claims.NotBefore != nil {
+		body["nbf"] = claims.NotBefore.Unix()
+	}
+	return body
+}
+
+func (h *IntrospectionHandler) introspectRefreshToken(c *gin.Context, token string) (gin.H, error) {
+	ctx := c.Request.Context()
+	stored, err := h.refreshTokens.FindByHash(ctx, auth.HashRefreshToken(token))
+	if errors.Is(err, database.ErrRefreshTokenInvalid) {
+		return gin.H{"active": false}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !stored.Active(time.Now()) {
+		return gin.H{"active": false, "revoked": stored.RevokedAt != nil}, nil
+	}
+
+	// Pengguna yang dihapus atau dinonaktifkan tidak bisa lagi memakai
+	// refresh token-nya (lihat Refresh)
+	user, err := h.users.FindByID(ctx, stored.UserID)
+	if errors.Is(err, database.ErrUserNotFound) {
+		return gin.H{"active": false}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled {
+		return gin.H{"active": false}, nil
+	}
+	return gin.H{
+		"active":     true,
+		"token_type": "refresh_token",
+		"sub":        user.ID,
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"iss":        auth.TokenIssuer,
+		"exp":        stored.ExpiresAt.Unix(),
+		"iat":        stored.CreatedAt.Unix(),
+		"revoked":    false,
+	}, nil
+}
+
+// Me menangani GET /auth/me: identitas pemanggil dari token yang sudah
+// diverifikasi AuthMiddleware.
+func Me(c *gin.Context) {
+	claims := c.MustGet(auth.ClaimsContextKey).(*auth.Claims)
+	roles := claims.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	body := gin.H{
+		"user_id":  claims.UserID,
+		"username": claims.Username,
+		"roles":    roles,
+		"scopes":   claims.Scopes(),
+		"issuer":   claims.Issuer,
+		"session":  claims.CSRF != "",
+		"machine":  claims.Machine(),
+	}
+	if claims.Machine() {
+		body["client_id"] = claims.ClientID
+	}
+	if claims.ID != "" {
+		body["jti"] = claims.ID
+	}
+	if claims.IssuedAt != nil {
+		body["issued_at"] = claims.IssuedAt.Time.UTC().Format(time.RFC3339)
+	}
+	if claims.ExpiresAt != nil {
+		body["expires_at"] = claims.ExpiresAt.Time.UTC().Format(time.RFC3339)
+		body["expires_in"] = max(0, int(time.Until(claims.ExpiresAt.Time).Seconds()))
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, body)
+}
@@ -0,0 +1,20 @@
+// pkg/handlers/jwks_handler.go
+package handlers
+
+import (
+	"net/http"
+
+	"api-gateway-go/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler mempublikasikan public key penandatangan access token agar
+// upstream bisa memverifikasi token sendiri tanpa memegang secret.
+func JWKSHandler(keys *auth.KeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Cache singkat supaya kunci baru cepat terlihat setelah rotasi
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, gin.H{"keys": keys.JWKS()})
+	}
+}
@@ -0,0 +1,323 @@
+// pkg/handlers/mfa_handler.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAVerifyRequest adalah body /auth/mfa/verify. Isi code (TOTP) atau
+// recovery_code.
+type MFAVerifyRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// MFACodeRequest adalah body endpoint MFA yang membutuhkan bukti faktor kedua.
+type MFACodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// writeMFAChallenge mengirim mfa_token sebagai pengganti token akses. Jika
+// MFA wajib tetapi belum didaftarkan, token hanya berlaku untuk enrollment.
+// session dibawa di challenge agar langkah berikutnya memakai mode yang sama.
+func (h *AuthHandler) writeMFAChallenge(c *gin.Context, user *database.User, session bool) {
+	purpose := auth.MFAPurposeVerify
+	if !user.MFAEnabled {
+		purpose = auth.MFAPurposeEnroll
+	}
+	token, challenge, err := h.keys.SignMFAChallenge(user.ID, purpose, session, h.mfa.ChallengeTTL)
+	if err != nil {
+		requestid.Logf(c.Request.Context(), "Gagal membuat MFA challenge pengguna %q: %v", user.Username, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not generate token"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"mfa_required":            true,
+		"mfa_enrollment_required": purpose == auth.MFAPurposeEnroll,
+		"mfa_token":               token,
+		"expires_in":              int(h.mfa.ChallengeTTL.Seconds()),
+		"expires_at":              challenge.ExpiresAt.Time.Format(time.RFC3339),
+	})
+}
+
+// VerifyMFA menukar mfa_token dari /auth/login beserta kode TOTP atau
+// recovery code dengan access token dan refresh token.
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req MFAVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+		return
+	}
+
+	ctx := c.Request.Context()
+	challenge, err := h.keys.ParseMFAChallenge(req.MFAToken, auth.MFAPurposeVerify)
+	if err != nil || h.revoked.IsTokenRevoked(challenge.ID) {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeMFATokenInvalid, "MFA token is invalid or expired"))
+		return
+	}
+	user, err := h.users.FindByID(ctx, challenge.Subject)
+	if err != nil && !errors.Is(err, database.ErrUserNotFound) {
+		requestid.Logf(ctx, "Gagal membaca pengguna %s: %v", challenge.Subject, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not verify MFA code"))
+		return
+	}
+	// Pengguna bisa dihapus, dinonaktifkan, atau di-reset MFA-nya setelah login
+	if err != nil || user.Disabled || !user.MFAEnabled {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeMFATokenInvalid, "MFA token is invalid or expired"))
+		return
+	}
+
+	if !h.checkSecondFactor(c, user, req.Code, req.RecoveryCode) {
+		return
+	}
+	// mfa_token hanya boleh ditukar sekali
+	if err := h.revoked.RevokeToken(ctx, challenge.ID, challenge.ExpiresAt.Time); err != nil {
+		requestid.Logf(ctx, "Gagal menyimpan pencabutan MFA challenge %s: %v", challenge.ID, err)
+	}
+	h.completeLogin(c, user, tokenDelivery{session: challenge.Session}, nil)
+}
+
+// MFAStatus menampilkan status MFA pengguna yang sedang login.
+func (h *AuthHandler) MFAStatus(c *gin.Context) {
+	user, ok := h.mfaUser(c)
+	if !ok {
+		return
+	}
+	remaining, err := h.users.CountRecoveryCodes(c.Request.Context(), user.ID)
+	if err != nil {
+		requestid.Logf(c.Request.Context(), "Gagal menghitung recovery code pengguna %q: %v", user.Username, err)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":                  user.MFAEnabled,
+		"required":                 h.mfa.Required(user.Roles),
+		"recovery_codes_remaining": remaining,
+	})
+}
+
+// EnrollMFA membuat secret TOTP baru yang belum aktif dan mengembalikan URI
+// otpauth:// untuk QR code. MFA baru aktif setelah /auth/mfa/confirm.
+// Diautentikasi dengan access token atau mfa_token enrollment.
+func (h *AuthHandler) EnrollMFA(c *gin.Context) {
+	user, ok := h.mfaUser(c)
+	if !ok {
+		return
+	}
+	if user.MFAEnabled {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeMFAAlreadyEnabled, "MFA is already enabled; disable it first to enroll a new authenticator"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	secret, err := auth.NewTOTPSecret()
+	if err == nil {
+		err = h.users.StartMFAEnrollment(ctx, user.ID, secret)
+	}
+	if err != nil {
+		requestid.Logf(ctx, "Gagal memulai enrollment MFA pengguna %q: %v", user.Username, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not start MFA enrollment"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"secret":      secret,
+		"otpauth_uri": auth.TOTPProvisioningURI(h.mfa.Issuer, user.Username, secret),
+	})
+}
+
+// ConfirmMFA mengaktifkan MFA setelah kode TOTP pertama dari authenticator
+// benar, lalu mengembalikan recovery code (hanya sekali). Jika diautentikasi
+// dengan mfa_token enrollment, login sekaligus diselesaikan dan response
+// berisi token akses.
+func (h *AuthHandler) ConfirmMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.Code == "" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Field code is required"))
+		return
+	}
+	user, ok := h.mfaUser(c)
+	if !ok {
+		return
+	}
+	if user.MFAEnabled {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeMFAAlreadyEnabled, "MFA is already enabled"))
+		return
+	}
+	if user.MFASecret == "" {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeMFANotEnabled, "Start enrollment with /auth/mfa/enroll first"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	step, ok := auth.VerifyTOTP(user.MFASecret, req.Code, time.Now(), h.mfa.Skew, 0)
+	if !ok {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeMFACodeInvalid, "Invalid MFA code"))
+		return
+	}
+	codes, hashes, err := auth.NewRecoveryCodes(h.mfa.RecoveryCodes)
+	if err == nil {
+		err = h.users.EnableMFA(ctx, user.ID, step, hashes)
+	}
+	if err != nil {
+		requestid.Logf(ctx, "Gagal mengaktifkan MFA pengguna %q: %v", user.Username, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not enable MFA"))
+		return
+	}
+	requestid.Logf(ctx, "[AUDIT] event=mfa_enabled user=%q", user.Username)
+
+	extra := gin.H{"recovery_codes": codes}
+	if challenge, ok := c.Get(auth.MFAChallengeContextKey); ok {
+		challenge := challenge.(*auth.MFAChallenge)
+		if err := h.revoked.RevokeToken(ctx, challenge.ID, challenge.ExpiresAt.Time); err != nil {
+			requestid.Logf(ctx, "Gagal menyimpan pencabutan MFA challenge %s: %v", challenge.ID, err)
+		}
+		h.completeLogin(c, user, tokenDelivery{session: challenge.Session}, extra)
+		return
+	}
+	c.JSON(http.StatusOK, extra)
+}
+
+// DisableMFA menonaktifkan MFA pengguna yang sedang login. Butuh kode TOTP
+// atau recovery code agar access token yang dicuri tidak cukup untuk
+// mematikan MFA.
+func (h *AuthHandler) DisableMFA(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+		return
+	}
+	user, ok := h.mfaUser(c)
+	if !ok {
+		return
+	}
+	if !user.MFAEnabled {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeMFANotEnabled, "MFA is not enabled"))
+		return
+	}
+	if !h.checkSecondFactor(c, user, req.Code, req.RecoveryCode) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.users.DisableMFA(ctx, user.ID); err != nil {
+		requestid.Logf(ctx, "Gagal menonaktifkan MFA pengguna %q: %v", user.Username, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not disable MFA"))
+		return
+	}
+	requestid.Logf(ctx, "[AUDIT] event=mfa_disabled user=%q by=self", user.Username)
+	c.Status(http.StatusNoContent)
+}
+
+// RegenerateRecoveryCodes mengganti semua recovery code pengguna yang sedang
+// login. Butuh kode TOTP atau recovery code.
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+		return
+	}
+	user, ok := h.mfaUser(c)
+	if !ok {
+		return
+	}
+	if !user.MFAEnabled {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeMFANotEnabled, "MFA is not enabled"))
+		return
+	}
+	if !h.checkSecondFactor(c, user, req.Code, req.RecoveryCode) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	codes, hashes, err := auth.NewRecoveryCodes(h.mfa.RecoveryCodes)
+	if err == nil {
+		err = h.users.ReplaceRecoveryCodes(ctx, user.ID, hashes)
+	}
+	if err != nil {
+		requestid.Logf(ctx, "Gagal membuat recovery code pengguna %q: %v", user.Username, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not generate recovery codes"))
+		return
+	}
+	requestid.Logf(ctx, "[AUDIT] event=mfa_recovery_codes_regenerated user=%q", user.Username)
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// mfaUser membaca pengguna lokal dari claims request. Pengguna dari issuer
+// eksternal tidak tersimpan di database dan tidak bisa memakai MFA gateway.
+func (h *AuthHandler) mfaUser(c *gin.Context) (*database.User, bool) {
+	claims := c.MustGet(auth.ClaimsContextKey).(*auth.Claims)
+	ctx := c.Request.Context()
+	user, err := h.users.FindByID(ctx, claims.UserID)
+	if errors.Is(err, database.ErrUserNotFound) {
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeUserNotFound, "MFA is only available for gateway accounts"))
+		return nil, false
+	}
+	if err != nil {
+		requestid.Logf(ctx, "Gagal membaca pengguna %s: %v", claims.UserID, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not read user"))
+		return nil, false
+	}
+	if user.Disabled {
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAccountDisabled, "This account has been disabled"))
+		return nil, false
+	}
+	return user, true
+}
+
+// checkSecondFactor memverifikasi kode TOTP atau recovery code. Percobaan
+// dihitung LoginGuard per username seperti password, sehingga kode 6 digit
+// tidak bisa ditebak habis-habisan. Jika gagal, response error sudah dikirim.
+func (h *AuthHandler) checkSecondFactor(c *gin.Context, user *database.User, code, recoveryCode string) bool {
+	if (code == "") == (recoveryCode == "") {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Provide either code or recovery_code"))
+		return false
+	}
+
+	ctx := c.Request.Context()
+	ip := c.ClientIP()
+	if h.guard != nil {
+		if wait := h.guard.Attempt(user.Username, ip); wait > 0 {
+			abortThrottled(c, wait)
+			return false
+		}
+	}
+
+	var ok bool
+	var err error
+	if code != "" {
+		var step int64
+		if step, ok = auth.VerifyTOTP(user.MFASecret, code, time.Now(), h.mfa.Skew, user.MFALastStep); ok {
+			// Gagal jika request paralel baru saja memakai kode yang sama
+			ok, err = h.users.AdvanceMFAStep(ctx, user.ID, step)
+		}
+	} else {
+		ok, err = h.users.UseRecoveryCode(ctx, user.ID, auth.HashRecoveryCode(recoveryCode))
+		if ok {
+			remaining, _ := h.users.CountRecoveryCodes(ctx, user.ID)
+			requestid.Logf(ctx, "[AUDIT] event=mfa_recovery_code_used user=%q remaining=%d", user.Username, remaining)
+		}
+	}
+	if err != nil {
+		requestid.Logf(ctx, "Gagal memverifikasi MFA pengguna %q: %v", user.Username, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not verify MFA code"))
+		return false
+	}
+	if !ok {
+		h.recordFailure(c, user.Username, ip)
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeMFACodeInvalid, "Invalid MFA code"))
+		return false
+	}
+	if h.guard != nil {
+		h.guard.Succeeded(user.Username, ip)
+	}
+	return true
+}
@@ -0,0 +1,289 @@
+// pkg/handlers/oauth_client_handler.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthClientRequest adalah body untuk mendaftarkan atau mengubah OAuth client.
+type OAuthClientRequest struct {
+	ClientID string   `json:"client_id"`
+	Scopes   []string `json:"scopes"`
+	Roles    []string `json:"roles"`
+	TokenTTL string   `json:"token_ttl"` // Durasi Go, misal "30m"; kosong = AUTH.CLIENT_CREDENTIALS.TOKEN_TTL
+	Disabled bool     `json:"disabled"`
+}
+
+// OAuthClientView adalah representasi OAuth client di admin API. Secret
+// tidak pernah ditampilkan kecuali saat dibuat atau dirotasi.
+type OAuthClientView struct {
+	ID         string     `json:"id"`
+	ClientID   string     `json:"client_id"`
+	Scopes     []string   `json:"scopes"`
+	Roles      []string   `json:"roles"`
+	TokenTTL   string     `json:"token_ttl,omitempty"`
+	Disabled   bool       `json:"disabled"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewOAuthClientView mengubah OAuth client database menjadi OAuthClientView.
+func NewOAuthClientView(client database.OAuthClient) OAuthClientView {
+	view := OAuthClientView{
+		ID:         client.ID,
+		ClientID:   client.ClientID,
+		Scopes:     client.Scopes,
+		Roles:      client.Roles,
+		Disabled:   client.Disabled,
+		LastUsedAt: client.LastUsedAt,
+		CreatedAt:  client.CreatedAt,
+	}
+	if view.Scopes == nil {
+		view.Scopes = []string{}
+	}
+	if view.Roles == nil {
+		view.Roles = []string{}
+	}
+	if client.TokenTTLSeconds > 0 {
+		view.TokenTTL = client.TokenTTL(0).String()
+	}
+	return view
+}
+
+// ApplyOAuthClientSettings memvalidasi scope dan TTL (maksimal maxTTL) lalu
+// menyalinnya ke client. Dipakai juga oleh CLI.
+func ApplyOAuthClientSettings(client *database.OAuthClient, scopes, roles []string, tokenTTL string, maxTTL time.Duration) error {
+	for _, s := range scopes {
+		if !auth.ValidScope(s) {
+			return fmt.Errorf("scope %q tidak valid", s)
+		}
+	}
+	ttl := time.Duration(0)
+	if tokenTTL != "" {
+		d, err := time.ParseDuration(tokenTTL)
+		if err != nil {
+			return fmt.Errorf("token_ttl tidak valid: %w", err)
+		}
+		if d < time.Second {
+			return errors.New("token_ttl minimal 1s")
+		}
+		if d > maxTTL {
+			return fmt.Errorf("token_ttl maksimal %s (AUTH.CLIENT_CREDENTIALS.MAX_TOKEN_TTL)", maxTTL)
+		}
+		ttl = d
+	}
+	client.Scopes = scopes
+	client.Roles = roles
+	client.TokenTTLSeconds = int(ttl.Seconds())
+	return nil
+}
+
+// RegisterOAuthClient membuat secret lalu menyimpan client baru, dan
+// mengembalikan secret yang hanya bisa dilihat sekali ini. Dipakai juga oleh CLI.
+func RegisterOAuthClient(ctx context.Context, clients *database.OAuthClientRepository, client *database.OAuthClient) (string, error) {
+	secret, hash, err := auth.NewClientSecret()
+	if err != nil {
+		return "", err
+	}
+	client.SecretHash = hash
+	if err := clients.Create(ctx, client); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ClientRevocationTTL mengembalikan berapa lama pencabutan token client harus
+// diingat: TTL terpanjang yang mungkin pernah dipakai untuk token client ini.
+// ttls adalah TTL client sebelum dan sesudah perubahan. Dipakai juga oleh CLI.
+func ClientRevocationTTL(cc config.ClientCredentialsConfig, ttls ...time.Duration) time.Duration {
+	ttl := max(cc.TokenTTL, cc.MaxTokenTTL)
+	for _, t := range ttls {
+		ttl = max(ttl, t)
+	}
+	return ttl
+}
+
+// RotateOAuthClientSecret mengganti secret client dan mengembalikan secret baru.
+// Token yang sudah diterbitkan tetap berlaku sampai kedaluwarsa.
+func RotateOAuthClientSecret(ctx context.Context, clients *database.OAuthClientRepository, clientID string) (string, error) {
+	secret, hash, err := auth.NewClientSecret()
+	if err != nil {
+		return "", err
+	}
+	if err := clients.SetSecretHash(ctx, clientID, hash); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// OAuthClientAdminHandler menangani endpoint admin untuk OAuth client.
+type OAuthClientAdminHandler struct {
+	clients  *database.OAuthClientRepository
+	revoked  *auth.RevocationStore
+	settings func() config.ClientCredentialsConfig // AUTH.CLIENT_CREDENTIALS generasi aktif
+}
+
+// NewOAuthClientAdminHandler membuat OAuthClientAdminHandler. settings
+// dipakai untuk validasi TTL dan menentukan berapa lama pencabutan token
+// client harus diingat.
+func NewOAuthClientAdminHandler(clients *database.OAuthClientRepository, revoked *auth.RevocationStore, settings func() config.ClientCredentialsConfig) *OAuthClientAdminHandler {
+	return &OAuthClientAdminHandler{clients: clients, revoked: revoked, settings: settings}
+}
+
+// List menangani GET /admin/clients.
+func (h *OAuthClientAdminHandler) List(c *gin.Context) {
+	clients, err := h.clients.List(c.Request.Context())
+	if err != nil {
+		abortInternal(c, err)
+		return
+	}
+	views := make([]OAuthClientView, 0, len(clients))
+	for _, client := range clients {
+		views = append(views, NewOAuthClientView(client))
+	}
+	c.JSON(http.StatusOK, gin.H{"clients": views})
+}
+
+// Create menangani POST /admin/clients. Secret hanya dikembalikan di response ini.
+func (h *OAuthClientAdminHandler) Create(c *gin.Context) {
+	var req OAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+		return
+	}
+	if req.ClientID == "" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "client_id is required"))
+		return
+	}
+	client := &database.OAuthClient{ClientID: req.ClientID, Disabled: req.Disabled}
+	if err := ApplyOAuthClientSettings(client, req.Scopes, req.Roles, req.TokenTTL, h.settings().MaxTokenTTL); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, err.Error()))
+		return
+	}
+	ctx := c.Request.Context()
+	secret, err := RegisterOAuthClient(ctx, h.clients, client)
+	if h.abortClientError(c, err) {
+		return
+	}
+	requestid.Logf(ctx, "[AUTH] Admin mendaftarkan OAuth client %s", client.ClientID)
+	c.JSON(http.StatusCreated, gin.H{
+		"client_secret": secret,
+		"client":        NewOAuthClientView(*client),
+	})
+}
+
+// Update menangani PUT /admin/clients/:client_id dan mengganti scope, role,
+// TTL token, dan status client. Menonaktifkan client atau memperpendek TTL
+// mencabut token-nya, karena token lama bisa berlaku lebih lama dari TTL baru.
+func (h *OAuthClientAdminHandler) Update(c *gin.Context) {
+	var req OAuthClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request payload: "+err.Error()))
+		return
+	}
+	ctx := c.Request.Context()
+	client, err := h.clients.Find(ctx, c.Param("client_id"))
+	if h.abortClientError(c, err) {
+		return
+	}
+	cc := h.settings()
+	prevTTL := client.TokenTTL(cc.TokenTTL)
+	if err := ApplyOAuthClientSettings(client, req.Scopes, req.Roles, req.TokenTTL, cc.MaxTokenTTL); err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, err.Error()))
+		return
+	}
+	client.Disabled = req.Disabled
+	if h.abortClientError(c, h.clients.Update(ctx, client)) {
+		return
+	}
+	if client.Disabled || client.TokenTTL(cc.TokenTTL) < prevTTL {
+		h.revokeTokens(c, client, prevTTL)
+	}
+	requestid.Logf(ctx, "[AUTH] Admin mengubah OAuth client %s (disabled=%v)", client.ClientID, client.Disabled)
+	c.JSON(http.StatusOK, NewOAuthClientView(*client))
+}
+
+// Delete menangani DELETE /admin/clients/:client_id. Token client ikut dicabut.
+func (h *OAuthClientAdminHandler) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	client, err := h.clients.Find(ctx, c.Param("client_id"))
+	if h.abortClientError(c, err) {
+		return
+	}
+	if h.abortClientError(c, h.clients.Delete(ctx, client.ClientID)) {
+		return
+	}
+	h.revokeTokens(c, client, 0)
+	requestid.Logf(ctx, "[AUTH] Admin menghapus OAuth client %s", client.ClientID)
+	c.Status(http.StatusNoContent)
+}
+
+// RotateSecret menangani POST /admin/clients/:client_id/secret. Secret lama
+// langsung tidak berlaku; token yang sudah terbit tetap berlaku sampai
+// kedaluwarsa kecuali dicabut dengan revoke-tokens.
+func (h *OAuthClientAdminHandler) RotateSecret(c *gin.Context) {
+	ctx := c.Request.Context()
+	secret, err := RotateOAuthClientSecret(ctx, h.clients, c.Param("client_id"))
+	if h.abortClientError(c, err) {
+		return
+	}
+	client, err := h.clients.Find(ctx, c.Param("client_id"))
+	if h.abortClientError(c, err) {
+		return
+	}
+	requestid.Logf(ctx, "[AUTH] Admin merotasi secret OAuth client %s", client.ClientID)
+	c.JSON(http.StatusOK, gin.H{
+		"client_secret": secret,
+		"client":        NewOAuthClientView(*client),
+	})
+}
+
+// RevokeTokens menangani POST /admin/clients/:client_id/revoke-tokens.
+func (h *OAuthClientAdminHandler) RevokeTokens(c *gin.Context) {
+	ctx := c.Request.Context()
+	client, err := h.clients.Find(ctx, c.Param("client_id"))
+	if h.abortClientError(c, err) {
+		return
+	}
+	h.revokeTokens(c, client, 0)
+	requestid.Logf(ctx, "[AUTH] Admin mencabut semua token OAuth client %s", client.ClientID)
+	c.Status(http.StatusNoContent)
+}
+
+// revokeTokens mencabut semua token client yang masih mungkin berlaku.
+// prevTTL adalah TTL client sebelum diubah, atau 0.
+func (h *OAuthClientAdminHandler) revokeTokens(c *gin.Context, client *database.OAuthClient, prevTTL time.Duration) {
+	ttl := ClientRevocationTTL(h.settings(), prevTTL, client.TokenTTL(0))
+	if err := h.revoked.RevokeUser(c.Request.Context(), client.ID, ttl); err != nil {
+		// Pencabutan di memori tetap berlaku, hanya penyimpanannya yang gagal
+		requestid.Logf(c.Request.Context(), "Gagal menyimpan pencabutan token client %s: %v", client.ClientID, err)
+	}
+}
+
+// abortClientError mengirim problem yang sesuai untuk err dan melaporkan
+// apakah request sudah dihentikan.
+func (h *OAuthClientAdminHandler) abortClientError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, database.ErrClientNotFound):
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeClientNotFound, "OAuth client not found"))
+	case errors.Is(err, database.ErrClientExists):
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeClientExists, "OAuth client already exists"))
+	default:
+		abortInternal(c, err)
+	}
+	return true
+}
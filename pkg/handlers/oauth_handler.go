@@ -0,0 +1,169 @@
+// pkg/handlers/oauth_handler.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GrantClientCredentials adalah satu-satunya grant_type yang didukung /oauth/token.
+const GrantClientCredentials = "client_credentials"
+
+// OAuthHandler adalah authorization server OAuth2 minimal untuk panggilan
+// antar service: hanya grant client_credentials, tanpa refresh token.
+type OAuthHandler struct {
+	issuer  *auth.Issuer
+	clients *database.OAuthClientRepository
+	cfg     config.ClientCredentialsConfig
+}
+
+// NewOAuthHandler membuat OAuthHandler. Token ditandatangani dengan kunci aktif
+// keys, sama seperti token pengguna.
+func NewOAuthHandler(cfg config.ClientCredentialsConfig, keys *auth.KeySet, clients *database.OAuthClientRepository) *OAuthHandler {
+	return &OAuthHandler{
+		issuer:  auth.NewIssuer(keys, cfg.TokenTTL),
+		clients: clients,
+		cfg:     cfg,
+	}
+}
+
+// Token menangani POST /oauth/token (RFC 6749 bagian 4.4). Client
+// mengautentikasi diri dengan HTTP Basic (client_secret_basic) atau field
+// client_id dan client_secret di body (client_secret_post).
+func (h *OAuthHandler) Token(c *gin.Context) {
+	ctx := c.Request.Context()
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	grantType := c.PostForm("grant_type")
+	if grantType == "" {
+		abortOAuth(c, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid_request", "grant_type is required")
+		return
+	}
+	if grantType != GrantClientCredentials {
+		abortOAuth(c, http.StatusBadRequest, problem.CodeOAuthUnsupported, "unsupported_grant_type", "Only the client_credentials grant is supported")
+		return
+	}
+
+	clientID, secret, ok := clientCredentials(c)
+	if !ok {
+		return
+	}
+	client, err := h.clients.Find(ctx, clientID)
+	if err != nil && !errors.Is(err, database.ErrClientNotFound) {
+		requestid.Logf(ctx, "Gagal membaca OAuth client %q: %v", clientID, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not authenticate client"))
+		return
+	}
+	switch {
+	case client == nil:
+		requestid.Logf(ctx, "[AUTH] Token client_credentials ditolak: client %q tidak dikenal", clientID)
+	case !auth.ClientSecretMatches(secret, client.SecretHash):
+		requestid.Logf(ctx, "[AUTH] Token client_credentials ditolak: secret salah untuk client %s", client.ClientID)
+	case client.Disabled:
+		requestid.Logf(ctx, "[AUTH] Token client_credentials ditolak: client %s dinonaktifkan", client.ClientID)
+	default:
+		h.issue(c, client)
+		return
+	}
+	// Alasan penolakan hanya di log; response sama untuk semua kasus
+	c.Header("WWW-Authenticate", `Basic realm="api-gateway"`)
+	abortOAuth(c, http.StatusUnauthorized, problem.CodeOAuthInvalidClient, "invalid_client", "Client authentication failed")
+}
+
+func (h *OAuthHandler) issue(c *gin.Context, client *database.OAuthClient) {
+	ctx := c.Request.Context()
+	scopes, ok := grantedScopes(c.PostForm("scope"), client.Scopes)
+	if !ok {
+		abortOAuth(c, http.StatusBadRequest, problem.CodeOAuthInvalidScope, "invalid_scope", "The requested scope exceeds the scopes allowed for this client")
+		return
+	}
+	scope := strings.Join(scopes, " ")
+	ttl := min(client.TokenTTL(h.cfg.TokenTTL), h.cfg.MaxTokenTTL)
+	token, claims, err := h.issuer.IssueClientToken(client.ID, client.ClientID, client.Roles, scope, ttl)
+	if err != nil {
+		requestid.Logf(ctx, "Gagal menerbitkan token untuk client %s: %v", client.ClientID, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not generate token"))
+		return
+	}
+	if err := h.clients.Touch(ctx, client.ID, time.Now().UTC()); err != nil {
+		requestid.Logf(ctx, "Gagal mencatat pemakaian client %s: %v", client.ClientID, err)
+	}
+	requestid.Logf(ctx, "[AUTH] Token client_credentials diterbitkan untuk client %s, scope %q, jti %s", client.ClientID, scope, claims.ID)
+
+	body := gin.H{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int(ttl.Seconds()),
+	}
+	if scope != "" {
+		body["scope"] = scope
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// clientCredentials membaca client_id dan client_secret dari header Basic
+// atau body. Jika tidak ada atau keduanya dipakai sekaligus, response error
+// sudah dikirim dan ok bernilai false.
+func clientCredentials(c *gin.Context) (clientID, secret string, ok bool) {
+	formID, formSecret := c.PostForm("client_id"), c.PostForm("client_secret")
+	basicID, basicSecret, hasBasic := c.Request.BasicAuth()
+	if hasBasic {
+		if formSecret != "" {
+			abortOAuth(c, http.StatusBadRequest, problem.CodeInvalidRequest, "invalid_request", "Use only one client authentication method")
+			return "", "", false
+		}
+		// RFC 6749 bagian 2.3.1: nilai Basic di-encode form-urlencoded
+		var err1, err2 error
+		clientID, err1 = url.QueryUnescape(basicID)
+		secret, err2 = url.QueryUnescape(basicSecret)
+		if err1 == nil && err2 == nil && clientID != "" {
+			return clientID, secret, true
+		}
+	} else if formID != "" && formSecret != "" {
+		return formID, formSecret, true
+	}
+	c.Header("WWW-Authenticate", `Basic realm="api-gateway"`)
+	abortOAuth(c, http.StatusUnauthorized, problem.CodeOAuthInvalidClient, "invalid_client", "Client credentials are required")
+	return "", "", false
+}
+
+// grantedScopes menentukan scope token: semua scope client jika requested
+// kosong, atau requested jika semuanya diizinkan untuk client.
+func grantedScopes(requested string, allowed []string) ([]string, bool) {
+	fields := strings.Fields(requested)
+	if len(fields) == 0 {
+		return allowed, true
+	}
+	var scopes []string
+	for _, s := range fields {
+		if !slices.Contains(allowed, s) {
+			return nil, false
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes, true
+}
+
+// abortOAuth mengirim problem dengan field error dan error_description
+// tambahan (RFC 6749 bagian 5.2) agar library OAuth2 klien tetap bisa
+// membacanya.
+func abortOAuth(c *gin.Context, status int, code, oauthError, detail string) {
+	problem.Abort(c, problem.New(status, code, detail).
+		With("error", oauthError).
+		With("error_description", detail))
+}
@@ -0,0 +1,196 @@
+// pkg/handlers/oauth_handler_test.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestGrantedScopes(t *testing.T) {
+	allowed := []string{"orders:read", "orders:write", "reports:read"}
+	tests := []struct {
+		name      string
+		requested string
+		allowed   []string
+		want      []string
+		wantOK    bool
+	}{
+		{"tanpa scope berarti semua scope client", "", allowed, allowed, true},
+		{"hanya spasi", "   ", allowed, allowed, true},
+		{"dipersempit", "orders:read", allowed, []string{"orders:read"}, true},
+		{"urutan permintaan dipertahankan", "reports:read orders:read", allowed, []string{"reports:read", "orders:read"}, true},
+		{"duplikat dibuang", "orders:read  orders:read", allowed, []string{"orders:read"}, true},
+		{"melebihi izin client", "orders:read admin", allowed, nil, false},
+		{"client tanpa scope", "orders:read", nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := grantedScopes(tt.requested, tt.allowed)
+			if ok != tt.wantOK || !slices.Equal(got, tt.want) {
+				t.Errorf("grantedScopes(%q) = %v, %v, want %v, %v", tt.requested, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+// oauthFixture adalah OAuthHandler di atas database SQLite sementara.
+type oauthFixture struct {
+	router  *gin.Engine
+	keys    *auth.KeySet
+	clients *database.OAuthClientRepository
+}
+
+func newOAuthFixture(t *testing.T, cfg config.ClientCredentialsConfig) *oauthFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	keys, err := auth.LoadKeySet(testConfig())
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	f := &oauthFixture{keys: keys, clients: database.NewOAuthClientRepository(openTestDB(t))}
+	f.router = gin.New()
+	f.router.POST("/oauth/token", NewOAuthHandler(cfg, keys, f.clients).Token)
+	return f
+}
+
+// addClient mendaftarkan client dan mengembalikan secret-nya.
+func (f *oauthFixture) addClient(t *testing.T, client *database.OAuthClient) string {
+	t.Helper()
+	secret, hash, err := auth.NewClientSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.SecretHash = hash
+	if err := f.clients.Create(context.Background(), client); err != nil {
+		t.Fatalf("Create client: %v", err)
+	}
+	return secret
+}
+
+// oauthResponse adalah gabungan field response sukses dan error /oauth/token.
+type oauthResponse struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+	Code        string `json:"code"`
+	Error       string `json:"error"`
+}
+
+func (f *oauthFixture) token(t *testing.T, clientID, secret string, form url.Values) (int, oauthResponse) {
+	t.Helper()
+	form.Set("grant_type", GrantClientCredentials)
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+
+	var resp oauthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response bukan JSON: %s", w.Body.String())
+	}
+	return w.Code, resp
+}
+
+// parseAccessToken memverifikasi access token dengan keys dan mengembalikan claims-nya.
+func parseAccessToken(t *testing.T, keys *auth.KeySet, token string) *auth.Claims {
+	t.Helper()
+	claims := &auth.Claims{}
+	if _, err := jwt.ParseWithClaims(token, claims, keys.Keyfunc, jwt.WithValidMethods(keys.ValidMethods())); err != nil {
+		t.Fatalf("access token tidak valid: %v", err)
+	}
+	return claims
+}
+
+func TestOAuthTokenScopes(t *testing.T) {
+	f := newOAuthFixture(t, config.ClientCredentialsConfig{Enabled: true, TokenTTL: time.Hour, MaxTokenTTL: 24 * time.Hour})
+	secret := f.addClient(t, &database.OAuthClient{ClientID: "billing", Scopes: []string{"orders:read", "orders:write"}, Roles: []string{"service"}})
+	disabledSecret := f.addClient(t, &database.OAuthClient{ClientID: "legacy", Scopes: []string{"orders:read"}, Disabled: true})
+
+	tests := []struct {
+		name       string
+		clientID   string
+		secret     string
+		scope      string
+		wantStatus int
+		wantScope  string
+		wantError  string
+		wantCode   string
+	}{
+		{"semua scope client", "billing", secret, "", http.StatusOK, "orders:read orders:write", "", ""},
+		{"scope dipersempit", "billing", secret, "orders:read", http.StatusOK, "orders:read", "", ""},
+		{"scope di luar izin", "billing", secret, "orders:read admin", http.StatusBadRequest, "", "invalid_scope", problem.CodeOAuthInvalidScope},
+		{"secret salah", "billing", "secret-salah", "", http.StatusUnauthorized, "", "invalid_client", problem.CodeOAuthInvalidClient},
+		{"client dinonaktifkan", "legacy", disabledSecret, "", http.StatusUnauthorized, "", "invalid_client", problem.CodeOAuthInvalidClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{}
+			if tt.scope != "" {
+				form.Set("scope", tt.scope)
+			}
+			status, resp := f.token(t, tt.clientID, tt.secret, form)
+			if status != tt.wantStatus || resp.Error != tt.wantError {
+				t.Fatalf("status %d error %q, want %d %q", status, resp.Error, tt.wantStatus, tt.wantError)
+			}
+			if resp.Code != tt.wantCode {
+				t.Errorf("kode %q, want %q", resp.Code, tt.wantCode)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			if resp.Scope != tt.wantScope {
+				t.Errorf("scope response %q, want %q", resp.Scope, tt.wantScope)
+			}
+			claims := parseAccessToken(t, f.keys, resp.AccessToken)
+			if claims.Scope != tt.wantScope || !claims.Machine() || !claims.HasRole("service") {
+				t.Errorf("claims scope %q machine %v roles %v, want %q true [service]", claims.Scope, claims.Machine(), claims.Roles, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestOAuthTokenMaxTTL(t *testing.T) {
+	f := newOAuthFixture(t, config.ClientCredentialsConfig{Enabled: true, TokenTTL: time.Hour, MaxTokenTTL: 6 * time.Hour})
+	tests := []struct {
+		name     string
+		clientID string
+		ttlSec   int
+		wantTTL  time.Duration
+	}{
+		{"TTL default", "default-ttl", 0, time.Hour},
+		{"TTL client di bawah batas", "short-ttl", 1800, 30 * time.Minute},
+		{"TTL client dibatasi MAX_TOKEN_TTL", "long-ttl", 48 * 3600, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := f.addClient(t, &database.OAuthClient{ClientID: tt.clientID, TokenTTLSeconds: tt.ttlSec})
+			status, resp := f.token(t, tt.clientID, secret, url.Values{})
+			if status != http.StatusOK {
+				t.Fatalf("status %d, want 200", status)
+			}
+			if got := time.Duration(resp.ExpiresIn) * time.Second; got != tt.wantTTL {
+				t.Errorf("expires_in = %v, want %v", got, tt.wantTTL)
+			}
+			claims := parseAccessToken(t, f.keys, resp.AccessToken)
+			if got := claims.ExpiresAt.Sub(claims.IssuedAt.Time); got != tt.wantTTL {
+				t.Errorf("exp - iat = %v, want %v", got, tt.wantTTL)
+			}
+		})
+	}
+}
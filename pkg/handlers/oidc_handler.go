@@ -0,0 +1,273 @@
+// pkg/handlers/oidc_handler.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// oidcStateCookie menyimpan state login OIDC selama pengguna berada di IdP.
+// Path-nya dibatasi ke /auth/oidc sehingga tidak pernah dikirim ke upstream.
+const oidcStateCookie = "gw_oidc_state"
+
+// OIDCHandler menangani login lewat identity provider eksternal dengan
+// authorization code flow + PKCE. Setelah identitas diverifikasi, token atau
+// sesi diterbitkan dengan cara yang sama seperti /auth/login.
+type OIDCHandler struct {
+	auth     *AuthHandler
+	provider *auth.OIDCProvider
+	cfg      config.OIDCConfig
+	origins  []string // CORS.ALLOWED_ORIGINS, tujuan return_to yang diizinkan selain path lokal
+}
+
+// NewOIDCHandler membuat OIDCHandler. authHandler dipakai untuk menerbitkan
+// token setelah login berhasil.
+func NewOIDCHandler(appConfig config.Config, authHandler *AuthHandler, provider *auth.OIDCProvider) *OIDCHandler {
+	return &OIDCHandler{
+		auth:     authHandler,
+		provider: provider,
+		cfg:      appConfig.Auth.OIDC,
+		origins:  appConfig.CORS.AllowedOrigins,
+	}
+}
+
+// Login memulai login OIDC: membuat state, nonce, dan PKCE code verifier,
+// menyimpannya di cookie bertanda tangan, lalu me-redirect browser ke IdP.
+// Query opsional: session (default sesuai AUTH.SESSION.ENABLED) dan return_to
+// (path lokal atau URL di CORS.ALLOWED_ORIGINS, hanya untuk mode sesi).
+func (h *OIDCHandler) Login(c *gin.Context) {
+	session := h.auth.session.Enabled
+	if v := c.Query("session"); v != "" {
+		requested, err := strconv.ParseBool(v)
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "session must be true or false"))
+			return
+		}
+		var ok bool
+		if session, ok = h.auth.sessionRequested(c, requested); !ok {
+			return
+		}
+	}
+	returnTo := h.cfg.PostLoginRedirect
+	if v := c.Query("return_to"); v != "" {
+		if !h.allowedReturnTo(v) {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "return_to must be a local path or an allowed origin"))
+			return
+		}
+		returnTo = v
+	}
+
+	state := &auth.OIDCState{ReturnTo: returnTo, Session: session}
+	for _, field := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+		value, err := auth.NewOIDCNonce()
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not start login"))
+			return
+		}
+		*field = value
+	}
+
+	ctx := c.Request.Context()
+	authURL, err := h.provider.AuthCodeURL(ctx, state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		requestid.Logf(ctx, "[AUTH] Login OIDC gagal dimulai: %v", err)
+		problem.Abort(c, problem.New(http.StatusBadGateway, problem.CodeOIDCUnavailable, "The identity provider is unavailable"))
+		return
+	}
+	signed, err := h.auth.keys.SignOIDCState(state, h.cfg.StateTTL)
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not start login"))
+		return
+	}
+	h.setStateCookie(c, signed, int(h.cfg.StateTTL.Seconds()))
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback menyelesaikan login OIDC: memeriksa state terhadap cookie, menukar
+// authorization code (dengan PKCE code verifier), memverifikasi ID token dan
+// nonce, memetakan identitas ke pengguna lokal, lalu menerbitkan token.
+// Pengguna yang wajib MFA di gateway (MFA aktif atau role di REQUIRED_ROLES)
+// ditolak kecuali klaim amr/acr ID token menunjukkan IdP sudah memverifikasi
+// MFA; challenge TOTP gateway tidak dijalankan untuk login OIDC.
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	ctx := c.Request.Context()
+	raw, _ := c.Cookie(oidcStateCookie)
+	// State hanya berlaku sekali, apa pun hasil callback
+	h.setStateCookie(c, "", -1)
+	c.Header("Cache-Control", "no-store")
+
+	state, err := h.auth.keys.ParseOIDCState(raw, c.Query("state"))
+	if err != nil {
+		requestid.Logf(ctx, "[AUTH] Callback OIDC ditolak: %v", err)
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeOIDCStateInvalid, "Login state is missing, expired, or does not match. Please start the login again."))
+		return
+	}
+	if idpErr := c.Query("error"); idpErr != "" {
+		requestid.Logf(ctx, "[AUTH] Login OIDC ditolak IdP: %s %s", idpErr, c.Query("error_description"))
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeOIDCLoginFailed, "The identity provider did not complete the login").
+			With("error", idpErr))
+		return
+	}
+	code := c.Query("code")
+	if code == "" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Missing authorization code"))
+		return
+	}
+
+	identity, err := h.provider.Exchange(ctx, code, state.Verifier, state.Nonce)
+	if errors.Is(err, auth.ErrOIDCUnavailable) {
+		requestid.Logf(ctx, "[AUTH] Login OIDC gagal: %v", err)
+		problem.Abort(c, problem.New(http.StatusBadGateway, problem.CodeOIDCUnavailable, "The identity provider is unavailable"))
+		return
+	}
+	if err != nil {
+		requestid.Logf(ctx, "[AUTH] Login OIDC gagal: %v", err)
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeOIDCLoginFailed, "Could not verify the login with the identity provider"))
+		return
+	}
+
+	user, created, ok := h.resolveUser(c, identity)
+	if !ok {
+		return
+	}
+	if user.Disabled {
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAccountDisabled, "This account has been disabled"))
+		return
+	}
+	if (user.MFAEnabled || h.auth.mfa.Required(user.Roles)) && !identity.MFA {
+		requestid.Logf(ctx, "[AUTH] Login OIDC pengguna %q ditolak: MFA wajib tetapi ID token tidak menunjukkan MFA", user.Username)
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeOIDCMFARequired, "This account requires multi-factor authentication at the identity provider"))
+		return
+	}
+	if err := h.auth.users.RecordIdentityLogin(ctx, identity.Issuer, identity.Subject); err != nil {
+		requestid.Logf(ctx, "Gagal mencatat login OIDC pengguna %q: %v", user.Username, err)
+	}
+	requestid.Logf(ctx, "[AUDIT] event=oidc_login user=%q issuer=%s subject=%q created=%v roles=%v", user.Username, identity.Issuer, identity.Subject, created, user.Roles)
+
+	delivery := tokenDelivery{session: state.Session && h.auth.session.Enabled}
+	if delivery.session {
+		delivery.redirect = state.ReturnTo
+	}
+	h.auth.completeLogin(c, user, delivery, gin.H{"identity_provider": identity.Issuer})
+}
+
+// resolveUser mencari pengguna lokal untuk identitas IdP: lewat tautan
+// issuer+subject, lalu (jika diizinkan) menautkan pengguna dengan username
+// sama atau membuat pengguna baru. Role disinkronkan dari IdP jika SYNC_ROLES
+// aktif. Jika gagal, response error sudah dikirim dan ok bernilai false.
+func (h *OIDCHandler) resolveUser(c *gin.Context, identity *auth.OIDCIdentity) (user *database.User, created, ok bool) {
+	ctx := c.Request.Context()
+	users := h.auth.users
+	roles := h.cfg.MapRoles(identity.Roles)
+
+	user, err := users.FindByIdentity(ctx, identity.Issuer, identity.Subject)
+	if errors.Is(err, database.ErrUserNotFound) {
+		user, created, err = h.provisionUser(ctx, identity, roles)
+	}
+	switch {
+	case errors.Is(err, errOIDCNotProvisioned):
+		requestid.Logf(ctx, "[AUTH] Login OIDC subject %q ditolak: pengguna belum terdaftar", identity.Subject)
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeOIDCNotProvisioned, "No local account exists for this identity"))
+		return nil, false, false
+	case errors.Is(err, database.ErrUserExists):
+		requestid.Logf(ctx, "[AUTH] Login OIDC subject %q ditolak: username %q sudah dipakai akun lain", identity.Subject, identity.Username)
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeOIDCAccountConflict, "A local account with this username already exists and is not linked to this identity"))
+		return nil, false, false
+	case err != nil:
+		requestid.Logf(ctx, "Gagal memetakan identitas OIDC %q: %v", identity.Subject, err)
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not complete login"))
+		return nil, false, false
+	}
+
+	if h.cfg.SyncRoles && !created && !slices.Equal(user.Roles, roles) {
+		if err := users.SetRoles(ctx, user.ID, roles); err != nil {
+			requestid.Logf(ctx, "Gagal menyinkronkan role pengguna %q: %v", user.Username, err)
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not complete login"))
+			return nil, false, false
+		}
+		requestid.Logf(ctx, "[AUDIT] event=oidc_roles_synced user=%q from=%v to=%v", user.Username, user.Roles, roles)
+		user.Roles = roles
+	}
+	return user, created, true
+}
+
+// errOIDCNotProvisioned menandai identitas tanpa pengguna lokal saat
+// AUTO_CREATE_USERS dimatikan.
+var errOIDCNotProvisioned = errors.New("pengguna OIDC belum terdaftar")
+
+// provisionUser menautkan identitas yang belum dikenal ke pengguna lokal
+// dengan username sama (LINK_EXISTING_USERS) atau membuat pengguna baru
+// (AUTO_CREATE_USERS).
+func (h *OIDCHandler) provisionUser(ctx context.Context, identity *auth.OIDCIdentity, roles []string) (*database.User, bool, error) {
+	users := h.auth.users
+	username := identity.Username
+	if username == "" {
+		username = identity.Subject
+	}
+
+	existing, err := users.FindByUsername(ctx, username)
+	switch {
+	case err == nil:
+		if !h.cfg.LinkExistingUsers {
+			return nil, false, database.ErrUserExists
+		}
+		// Akun yang sudah tertaut ke subject lain di IdP ini tidak boleh diambil alih
+		linked, err := users.HasIdentity(ctx, existing.ID, identity.Issuer)
+		if err != nil {
+			return nil, false, err
+		}
+		if linked {
+			return nil, false, database.ErrUserExists
+		}
+		if err := users.LinkIdentity(ctx, existing.ID, identity.Issuer, identity.Subject); err != nil {
+			return nil, false, err
+		}
+		requestid.Logf(ctx, "[AUDIT] event=oidc_identity_linked user=%q issuer=%s subject=%q", existing.Username, identity.Issuer, identity.Subject)
+		return existing, false, nil
+	case !errors.Is(err, database.ErrUserNotFound):
+		return nil, false, err
+	case !h.cfg.AutoCreateUsers:
+		return nil, false, errOIDCNotProvisioned
+	}
+
+	user := &database.User{Username: username, Roles: roles}
+	if err := users.CreateExternal(ctx, user, identity.Issuer, identity.Subject); err != nil {
+		return nil, false, err
+	}
+	requestid.Logf(ctx, "[AUDIT] event=oidc_user_created user=%q issuer=%s subject=%q roles=%v", user.Username, identity.Issuer, identity.Subject, roles)
+	return user, true, nil
+}
+
+// allowedReturnTo mencegah open redirect: hanya path lokal atau URL di origin
+// frontend yang terdaftar di CORS.ALLOWED_ORIGINS.
+func (h *OIDCHandler) allowedReturnTo(target string) bool {
+	return config.AllowedRedirect(target, h.origins)
+}
+
+// setStateCookie menulis cookie state OIDC; maxAge negatif menghapusnya.
+// SameSite Lax diperlukan agar cookie ikut terkirim saat IdP me-redirect
+// browser kembali ke callback.
+func (h *OIDCHandler) setStateCookie(c *gin.Context, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/auth/oidc",
+		MaxAge:   maxAge,
+		Secure:   strings.HasPrefix(h.cfg.RedirectURL, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,368 @@
+// pkg/handlers/oidc_handler_test.go
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"api-gateway-go/dummy-services/oidc-provider/idp"
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+const testRedirectURL = "http://gateway.test/auth/oidc/callback"
+
+// oidcFixture adalah OIDCHandler yang login ke identity provider tiruan dari
+// dummy-services yang berjalan di httptest.
+type oidcFixture struct {
+	router *gin.Engine
+	idp    *httptest.Server
+	users  *database.UserRepository
+	// client tidak mengikuti redirect agar Location bisa diperiksa
+	client *http.Client
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewUnstartedServer(nil)
+	provider, err := idp.New("http://"+server.Listener.Addr().String(), "")
+	if err != nil {
+		t.Fatalf("idp.New: %v", err)
+	}
+	server.Config.Handler = provider.Handler()
+	server.Start()
+	t.Cleanup(server.Close)
+
+	cfg := testConfig()
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	cfg.Auth.MFA.RequiredRoles = []string{"admin"}
+	cfg.Auth.OIDC = config.OIDCConfig{
+		Enabled:           true,
+		Issuer:            provider.Issuer(),
+		ClientID:          "api-gateway",
+		RedirectURL:       testRedirectURL,
+		UsernameClaim:     "preferred_username",
+		RolesClaim:        "roles",
+		RoleMapping:       []config.OIDCRoleMapping{{IdPRole: "gateway-admins", Roles: []string{"admin"}}},
+		SyncRoles:         true,
+		AutoCreateUsers:   true,
+		PostLoginRedirect: "/",
+		StateTTL:          10 * time.Minute,
+		ClockSkew:         30 * time.Second,
+		MFAAMR:            []string{"mfa"},
+	}
+	keys, err := auth.LoadKeySet(cfg)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	db := openTestDB(t)
+	f := &oidcFixture{
+		router: gin.New(),
+		idp:    server,
+		users:  database.NewUserRepository(db),
+		client: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }},
+	}
+	authHandler := NewAuthHandler(cfg, keys, f.users, database.NewRefreshTokenRepository(db), auth.NewRevocationStore(nil), nil, nil)
+	h := NewOIDCHandler(cfg, authHandler, auth.NewOIDCProvider(cfg.Auth.OIDC))
+	f.router.GET("/auth/oidc/login", h.Login)
+	f.router.GET("/auth/oidc/callback", h.Callback)
+	return f
+}
+
+// login memanggil /auth/oidc/login dan mengembalikan response-nya.
+func (f *oidcFixture) login(query string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oidc/login?"+query, nil))
+	return w
+}
+
+// start memulai login dan mengembalikan URL otorisasi IdP serta cookie state.
+func (f *oidcFixture) start(t *testing.T) (*url.URL, *http.Cookie) {
+	t.Helper()
+	w := f.login("")
+	if w.Code != http.StatusFound {
+		t.Fatalf("login status %d: %s", w.Code, w.Body.String())
+	}
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(authURL.String(), f.idp.URL+"/authorize") {
+		t.Fatalf("login me-redirect ke %q, want authorize endpoint IdP", w.Header().Get("Location"))
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oidcStateCookie {
+			return authURL, cookie
+		}
+	}
+	t.Fatal("cookie state OIDC tidak diset")
+	return nil, nil
+}
+
+// authorize mengisi form login IdP dengan parameter dari authURL (setelah
+// diubah oleh tamper) dan mengembalikan query redirect IdP ke callback.
+func (f *oidcFixture) authorize(t *testing.T, authURL *url.URL, tamper func(url.Values)) url.Values {
+	t.Helper()
+	form := authURL.Query()
+	form.Set("username", "alice")
+	form.Set("roles", "employee")
+	if tamper != nil {
+		tamper(form)
+	}
+	resp, err := f.client.PostForm(f.idp.URL+"/authorize", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if resp.StatusCode != http.StatusFound || err != nil || !strings.HasPrefix(location.String(), testRedirectURL) {
+		t.Fatalf("IdP status %d Location %q, want redirect ke callback", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	return location.Query()
+}
+
+// callback memanggil /auth/oidc/callback dengan query dan cookie state.
+func (f *oidcFixture) callback(query url.Values, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOIDCCallback(t *testing.T) {
+	tests := []struct {
+		name string
+		// run menjalankan flow login dan mengembalikan response callback
+		run        func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder
+		wantStatus int
+		wantCode   string
+		wantUser   bool // Pengguna lokal alice ada setelah flow
+	}{
+		{
+			name: "login berhasil",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, cookie := f.start(t)
+				return f.callback(f.authorize(t, authURL, nil), cookie)
+			},
+			wantStatus: http.StatusOK,
+			wantUser:   true,
+		},
+		{
+			name: "parameter state tidak cocok",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, cookie := f.start(t)
+				query := f.authorize(t, authURL, nil)
+				query.Set("state", "state-penyerang")
+				return f.callback(query, cookie)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   problem.CodeOIDCStateInvalid,
+		},
+		{
+			name: "tanpa cookie state",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, _ := f.start(t)
+				return f.callback(f.authorize(t, authURL, nil), nil)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   problem.CodeOIDCStateInvalid,
+		},
+		{
+			name: "cookie state dari login lain",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, _ := f.start(t)
+				_, otherCookie := f.start(t)
+				return f.callback(f.authorize(t, authURL, nil), otherCookie)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   problem.CodeOIDCStateInvalid,
+		},
+		{
+			name: "nonce di ID token tidak cocok",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, cookie := f.start(t)
+				return f.callback(f.authorize(t, authURL, func(form url.Values) { form.Set("nonce", "nonce-lain") }), cookie)
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   problem.CodeOIDCLoginFailed,
+		},
+		{
+			name: "PKCE code challenge tidak cocok",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, cookie := f.start(t)
+				tamper := func(form url.Values) { form.Set("code_challenge", auth.PKCEChallenge("verifier-penyerang")) }
+				return f.callback(f.authorize(t, authURL, tamper), cookie)
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   problem.CodeOIDCLoginFailed,
+		},
+		{
+			name: "code disuntikkan ke sesi login lain",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				// Code milik login A dipakai dengan state dan cookie login B:
+				// state cocok, tetapi code verifier B tidak cocok dengan challenge A
+				victimURL, _ := f.start(t)
+				stolen := f.authorize(t, victimURL, nil)
+				attackerURL, attackerCookie := f.start(t)
+				query := f.authorize(t, attackerURL, nil)
+				query.Set("code", stolen.Get("code"))
+				return f.callback(query, attackerCookie)
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   problem.CodeOIDCLoginFailed,
+		},
+		{
+			name: "code dipakai ulang",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, cookie := f.start(t)
+				query := f.authorize(t, authURL, nil)
+				if w := f.callback(query, cookie); w.Code != http.StatusOK {
+					t.Fatalf("callback pertama status %d: %s", w.Code, w.Body.String())
+				}
+				return f.callback(query, cookie)
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   problem.CodeOIDCLoginFailed,
+			wantUser:   true,
+		},
+		{
+			name: "role wajib MFA tanpa amr",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, cookie := f.start(t)
+				return f.callback(f.authorize(t, authURL, func(form url.Values) { form.Set("roles", "gateway-admins") }), cookie)
+			},
+			wantStatus: http.StatusForbidden,
+			wantCode:   problem.CodeOIDCMFARequired,
+			wantUser:   true,
+		},
+		{
+			name: "role wajib MFA dengan amr bukan MFA",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, cookie := f.start(t)
+				return f.callback(f.authorize(t, authURL, func(form url.Values) {
+					form.Set("roles", "gateway-admins")
+					form.Set("amr", "pwd,otp")
+				}), cookie)
+			},
+			wantStatus: http.StatusForbidden,
+			wantCode:   problem.CodeOIDCMFARequired,
+			wantUser:   true,
+		},
+		{
+			name: "role wajib MFA dengan amr mfa",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, cookie := f.start(t)
+				return f.callback(f.authorize(t, authURL, func(form url.Values) {
+					form.Set("roles", "gateway-admins")
+					form.Set("amr", "pwd,mfa")
+				}), cookie)
+			},
+			wantStatus: http.StatusOK,
+			wantUser:   true,
+		},
+		{
+			name: "MFA gateway aktif tanpa amr",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, cookie := f.start(t)
+				if w := f.callback(f.authorize(t, authURL, nil), cookie); w.Code != http.StatusOK {
+					t.Fatalf("login pertama status %d: %s", w.Code, w.Body.String())
+				}
+				ctx := context.Background()
+				user, err := f.users.FindByUsername(ctx, "alice")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := f.users.EnableMFA(ctx, user.ID, 1, nil); err != nil {
+					t.Fatal(err)
+				}
+				authURL, cookie = f.start(t)
+				return f.callback(f.authorize(t, authURL, nil), cookie)
+			},
+			wantStatus: http.StatusForbidden,
+			wantCode:   problem.CodeOIDCMFARequired,
+			wantUser:   true,
+		},
+		{
+			name: "login ditolak di IdP",
+			run: func(t *testing.T, f *oidcFixture) *httptest.ResponseRecorder {
+				authURL, cookie := f.start(t)
+				return f.callback(f.authorize(t, authURL, func(form url.Values) { form.Set("deny", "1") }), cookie)
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   problem.CodeOIDCLoginFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newOIDCFixture(t)
+			w := tt.run(t, f)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var body struct {
+				Code        string `json:"code"`
+				AccessToken string `json:"access_token"`
+				Username    string `json:"username"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("response bukan JSON: %s", w.Body.String())
+			}
+			if body.Code != tt.wantCode {
+				t.Errorf("kode %q, want %q", body.Code, tt.wantCode)
+			}
+			if tt.wantCode == "" && (body.AccessToken == "" || body.Username != "alice") {
+				t.Errorf("login berhasil tanpa token untuk alice: %s", w.Body.String())
+			}
+			if _, err := f.users.FindByUsername(context.Background(), "alice"); (err == nil) != tt.wantUser {
+				t.Errorf("pengguna alice ada = %v, want %v", err == nil, tt.wantUser)
+			}
+		})
+	}
+}
+
+func TestOIDCLoginReturnTo(t *testing.T) {
+	f := newOIDCFixture(t)
+	tests := []struct {
+		returnTo string
+		allowed  bool
+	}{
+		{"/dashboard", true},
+		{"/dashboard?tab=1#x", true},
+		{"https://app.example.com/home", true},
+		{"//evil.example/", false},
+		{"/\\evil.example", false},
+		{"/\t/evil.example", false},
+		{"/\n/evil.example", false},
+		{"/dash\x7fboard", false},
+		{"https://app.example.com\\@evil.example/", false},
+		{"https://evil.example/", false},
+		{"https://app.example.com.evil.example/", false},
+		{"http://app.example.com/", false},
+		{"https://user@app.example.com/", false},
+		{"javascript:alert(1)", false},
+		{"dashboard", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.returnTo, func(t *testing.T) {
+			w := f.login(url.Values{"return_to": {tt.returnTo}}.Encode())
+			wantStatus := http.StatusBadRequest
+			if tt.allowed {
+				wantStatus = http.StatusFound
+			}
+			if w.Code != wantStatus {
+				t.Errorf("status %d, want %d (body %s)", w.Code, wantStatus, w.Body.String())
+			}
+		})
+	}
+}
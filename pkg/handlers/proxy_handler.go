@@ -2,58 +2,136 @@
 package handlers
 
 import (
-	"log"
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+	"api-gateway-go/pkg/upstream"
+	"context"
+	"errors"
+	"math"
 	"net/http"
 	"net/http/httputil"
-	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// UpstreamHeader berisi instance yang melayani request; hanya dikirim ke klien
+// pada mode development.
+const UpstreamHeader = "X-Gateway-Upstream"
+
 type ProxyHandler struct {
-	target *url.URL
-	proxy  *httputil.ReverseProxy
+	service  *upstream.Service
+	proxy    *httputil.ReverseProxy
+	timeout  time.Duration
+	identity *IdentityHeaders
 }
 
-func NewProxyHandler(targetURL *url.URL) *ProxyHandler {
-	proxy := httputil.NewSingleHostReverseProxy(targetURL)
-
-	// Simpan director asli untuk digunakan kembali
-	originalDirector := proxy.Director
-	proxy.Director = func(req *http.Request) {
-		// Panggil director asli yang mengatur skema, host, dan path dasar
-		originalDirector(req)
+// ProxyOptions adalah pengaturan per rute untuk ProxyHandler.
+type ProxyOptions struct {
+	// StripPrefix, jika tidak kosong, dihapus dari path request sebelum digabung
+	// dengan path instance (misal /api/v1/users/profile -> <target>/profile).
+	StripPrefix string
+	// ExposeUpstream menambahkan header X-Gateway-Upstream ke response.
+	ExposeUpstream bool
+	// RequestIDHeader adalah header request ID (REQUEST_ID.HEADER). Nilai dari
+	// upstream di header ini diganti dengan request ID gateway.
+	RequestIDHeader string
+	// TrustedProxies menentukan peer yang header forwarding-nya dipercaya.
+	TrustedProxies TrustedProxies
+	// Transport dipakai untuk mengirim request ke instance; nil berarti
+	// http.DefaultTransport.
+	Transport http.RoundTripper
+	// Timeout membatasi seluruh request ke upstream, termasuk retry dan body
+	// response; 0 berarti tanpa batas.
+	Timeout time.Duration
+	// Retry dan RetryBudget mengatur retry otomatis; nil berarti tanpa retry.
+	Retry       *upstream.RetryPolicy
+	RetryBudget *upstream.RetryBudget
+	// Identity menghapus header identitas kiriman klien dan mengisinya dari
+	// claims hasil autentikasi; nil berarti header diteruskan apa adanya.
+	Identity *IdentityHeaders
+	// StripCookies adalah nama cookie milik gateway (cookie sesi) yang dihapus
+	// dari header Cookie sebelum diteruskan agar token tidak bocor ke upstream.
+	StripCookies []string
+}
 
-		// Pastikan header Host diatur ke host target,
-		// ini penting untuk service backend yang menggunakan virtual hosting.
-		req.Host = targetURL.Host
+// NewProxyHandler membuat reverse proxy ke service. Instance dipilih per request
+// oleh upstream.Transport sesuai strategi load balancing service.
+func NewProxyHandler(service *upstream.Service, opts ProxyOptions) *ProxyHandler {
+	stripPrefix := opts.StripPrefix
+	proxy := &httputil.ReverseProxy{
+		Transport: &upstream.Transport{
+			Service: service,
+			Base:    opts.Transport,
+			Retry:   opts.Retry,
+			Budget:  opts.RetryBudget,
+		},
+	}
 
-		// Tambahkan atau modifikasi header sesuai kebutuhan
-		req.Header.Set("X-Forwarded-For", req.RemoteAddr)                           // Atau ambil dari c.ClientIP() jika lebih akurat
-		req.Header.Set("X-Gateway-Timestamp", http.Header{"Date": nil}.Get("Date")) // Contoh header kustom
+	proxy.Director = func(req *http.Request) {
+		if stripPrefix != "" {
+			req.URL.Path = ensureLeadingSlash(strings.TrimPrefix(req.URL.Path, stripPrefix))
+			if req.URL.RawPath != "" {
+				req.URL.RawPath = ensureLeadingSlash(strings.TrimPrefix(req.URL.RawPath, stripPrefix))
+			}
+		}
+		// Skema, host, dan path dasar diatur oleh upstream.Transport setelah instance dipilih.
 
-		// Log URL yang akan dipanggil ke backend
-		log.Printf("Proxying request to: %s%s", targetURL.Scheme+"://"+targetURL.Host, req.URL.Path)
+		setForwardingHeaders(req, opts.TrustedProxies, stripPrefix)
+		stripCookies(req, opts.StripCookies)
 	}
 
 	// (Opsional) Modifikasi response dari backend sebelum dikirim ke client
 	proxy.ModifyResponse = func(resp *http.Response) error {
-		log.Printf("Received response from backend %s: Status %d", resp.Request.URL.Host, resp.StatusCode)
-		// resp.Header.Set("X-Gateway-Processed", "true") // Contoh modifikasi header response
+		requestid.Logf(resp.Request.Context(), "Received response from backend %s (service %s): Status %d", resp.Request.URL.Host, service.Name, resp.StatusCode)
+		if opts.RequestIDHeader != "" {
+			// Header ini sudah diisi oleh RequestIDMiddleware; jangan digandakan
+			resp.Header.Del(opts.RequestIDHeader)
+		}
+		if opts.ExposeUpstream {
+			resp.Header.Set(UpstreamHeader, resp.Request.URL.Host)
+		}
 		return nil
 	}
 
 	// (Opsional) Custom error handler jika backend tidak bisa dihubungi
 	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
-		log.Printf("Error proxying to %s: %v", targetURL, err)
+		requestid.Logf(req.Context(), "Error proxying to service %s: %v", service.Name, err)
 		// Berikan pesan error yang lebih informatif ke client
 		// Pastikan tidak membocorkan detail internal
-		http.Error(rw, "The upstream service is unavailable.", http.StatusBadGateway)
+		var circuitOpen *upstream.CircuitOpenError
+		if errors.As(err, &circuitOpen) {
+			// Gagal cepat: beri tahu klien kapan sebaiknya mencoba lagi
+			retryAfter := int(math.Ceil(circuitOpen.RetryAfter.Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			rw.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			problem.Write(rw, req, problem.New(http.StatusServiceUnavailable, problem.CodeCircuitOpen,
+				"The upstream service is temporarily unavailable (circuit open). Please retry later.").
+				With("service", service.Name).
+				With("retry_after", retryAfter))
+			return
+		}
+		switch {
+		case errors.Is(err, upstream.ErrNoAvailableTarget):
+			problem.Write(rw, req, problem.New(http.StatusServiceUnavailable, problem.CodeNoHealthyUpstream, "No upstream instance is available."))
+		case upstream.IsTimeout(err):
+			problem.Write(rw, req, problem.New(http.StatusGatewayTimeout, problem.CodeUpstreamTimeout, "The upstream service did not respond in time."))
+		default:
+			problem.Write(rw, req, problem.New(http.StatusBadGateway, problem.CodeUpstreamError, "The upstream service is unavailable."))
+		}
 	}
 
 	return &ProxyHandler{
-		target: targetURL,
-		proxy:  proxy,
+		service:  service,
+		proxy:    proxy,
+		timeout:  opts.Timeout,
+		identity: opts.Identity,
 	}
 }
 
@@ -61,9 +139,40 @@ func (h *ProxyHandler) Handle(c *gin.Context) {
 	// Logika sebelum meneruskan, misal transformasi request (jika diperlukan)
 	// `c.Param("proxyPath")` akan berisi path yang cocok dengan wildcard, misal "/details/1"
 
-	// Gin biasanya sudah menangani c.Request.URL.Path dengan benar.
-	// Jika Anda perlu memodifikasi path yang dikirim ke backend secara spesifik,
-	// Anda bisa melakukannya di `proxy.Director`.
+	if h.identity != nil {
+		value, _ := c.Get(auth.ClaimsContextKey)
+		claims, _ := value.(*auth.Claims)
+		h.identity.Apply(c.Request, claims)
+	}
 
-	h.proxy.ServeHTTP(c.Writer, c.Request)
+	// IP klien versi Gin dipakai sebagai kunci consistent hash
+	ctx := upstream.WithClientIP(c.Request.Context(), c.ClientIP())
+	if h.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+	}
+	h.proxy.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
+
+func ensureLeadingSlash(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		return "/" + p
+	}
+	return p
+}
+
+// stripCookies menghapus cookie bernama names dari header Cookie req. Cookie
+// lain diteruskan apa adanya.
+func stripCookies(req *http.Request, names []string) {
+	if len(names) == 0 || req.Header.Get("Cookie") == "" {
+		return
+	}
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if !slices.Contains(names, cookie.Name) {
+			req.AddCookie(cookie)
+		}
+	}
 }
@@ -0,0 +1,131 @@
+// pkg/handlers/session.go
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tokenDelivery menentukan cara token dikirim ke klien.
+type tokenDelivery struct {
+	session bool   // Sebagai cookie sesi (AUTH.SESSION), bukan di body
+	csrf    string // Token CSRF yang dipertahankan saat refresh; kosong = buat baru
+	// redirect, jika diisi, menggantikan body JSON mode sesi dengan redirect
+	// 302, misalnya kembali ke frontend setelah login OIDC
+	redirect string
+}
+
+// writeSession menerbitkan access token yang terikat ke token CSRF lalu
+// mengirim access token dan refresh token sebagai cookie HttpOnly. Body hanya
+// berisi token CSRF yang harus dikirim ulang di CSRF_HEADER; jika
+// delivery.redirect diisi, frontend membacanya dari cookie CSRF.
+func (h *AuthHandler) writeSession(c *gin.Context, user *database.User, refreshToken string, refreshExpiresAt time.Time, delivery tokenDelivery, extra gin.H) {
+	csrf := delivery.csrf
+	if csrf == "" {
+		var err error
+		if csrf, err = auth.NewCSRFToken(); err != nil {
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not generate token"))
+			return
+		}
+	}
+	accessToken, claims, err := h.issuer.IssueSessionAccessToken(user.ID, user.Username, user.Roles, auth.HashCSRFToken(csrf))
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not generate token"))
+		return
+	}
+
+	// Cookie access token hidup selama refresh token agar token yang
+	// kedaluwarsa menghasilkan AUTH_TOKEN_EXPIRED, bukan AUTH_TOKEN_MISSING
+	setSessionCookie(c, h.session, h.session.CookieName, accessToken, "/", refreshExpiresAt, true)
+	setSessionCookie(c, h.session, h.session.RefreshCookieName, refreshToken, "/auth", refreshExpiresAt, true)
+	setSessionCookie(c, h.session, h.session.CSRFCookieName, csrf, "/", refreshExpiresAt, false)
+	c.Header("Cache-Control", "no-store")
+	if delivery.redirect != "" {
+		c.Redirect(http.StatusFound, delivery.redirect)
+		return
+	}
+
+	body := gin.H{
+		"session":            true,
+		"csrf_token":         csrf,
+		"csrf_header":        h.session.CSRFHeader,
+		"expires_in":         int(h.issuer.AccessTokenTTL().Seconds()),
+		"expires_at":         claims.ExpiresAt.Time.Format(time.RFC3339),
+		"refresh_expires_at": refreshExpiresAt.Format(time.RFC3339),
+		"user_id":            user.ID,
+		"username":           user.Username,
+		"roles":              claims.Roles,
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// sessionRequested memeriksa permintaan mode sesi dari klien. Jika mode sesi
+// dimatikan, response 400 sudah dikirim dan ok bernilai false.
+func (h *AuthHandler) sessionRequested(c *gin.Context, requested bool) (session, ok bool) {
+	if requested && !h.session.Enabled {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "Session mode is not enabled on this gateway"))
+		return false, false
+	}
+	return requested, true
+}
+
+// sessionRefreshToken membaca refresh token dari cookie untuk /auth/refresh
+// mode sesi. Cookie dikirim browser secara otomatis, jadi request juga harus
+// membawa token CSRF yang sama dengan cookie CSRF (double-submit); token itu
+// dipertahankan untuk access token berikutnya. Mengembalikan token kosong jika
+// tidak ada cookie refresh; jika CSRF tidak cocok, response 403 sudah dikirim.
+func (h *AuthHandler) sessionRefreshToken(c *gin.Context) (token, csrf string, ok bool) {
+	if !h.session.Enabled {
+		return "", "", true
+	}
+	token, err := c.Cookie(h.session.RefreshCookieName)
+	if err != nil || token == "" {
+		return "", "", true
+	}
+	csrf, _ = c.Cookie(h.session.CSRFCookieName)
+	if csrf == "" || c.GetHeader(h.session.CSRFHeader) != csrf {
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeCSRFInvalid, "CSRF token is missing or invalid"))
+		return "", "", false
+	}
+	return token, csrf, true
+}
+
+// clearSessionCookies menghapus semua cookie sesi di browser.
+func (h *AuthHandler) clearSessionCookies(c *gin.Context) {
+	if !h.session.Enabled {
+		return
+	}
+	setSessionCookie(c, h.session, h.session.CookieName, "", "/", time.Time{}, true)
+	setSessionCookie(c, h.session, h.session.RefreshCookieName, "", "/auth", time.Time{}, true)
+	setSessionCookie(c, h.session, h.session.CSRFCookieName, "", "/", time.Time{}, false)
+}
+
+// setSessionCookie menulis satu cookie sesi; expires nol menghapus cookie.
+func setSessionCookie(c *gin.Context, cfg config.SessionConfig, name, value, path string, expires time.Time, httpOnly bool) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   cfg.Domain,
+		Secure:   cfg.Secure,
+		HttpOnly: httpOnly,
+		SameSite: cfg.SameSiteMode(),
+	}
+	if expires.IsZero() {
+		cookie.MaxAge = -1
+	} else {
+		cookie.Expires = expires
+		cookie.MaxAge = int(time.Until(expires).Seconds())
+	}
+	http.SetCookie(c.Writer, cookie)
+}
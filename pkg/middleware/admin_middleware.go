@@ -0,0 +1,24 @@
+// pkg/middleware/admin_middleware.go
+package middleware
+
+import (
+	"api-gateway-go/pkg/problem"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminTokenMiddleware membatasi endpoint admin dengan bearer token statis
+// dari konfigurasi ADMIN.TOKEN.
+func AdminTokenMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeAdminUnauthorized, "Invalid admin token"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,146 @@
+// pkg/middleware/apikey_middleware.go
+package middleware
+
+import (
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// apiKeyConsumerContextKey menandai request yang diautentikasi dengan API key.
+const apiKeyConsumerContextKey = "apiKeyConsumer"
+
+// apiKeyTouchInterval membatasi penulisan LastUsedAt agar key yang sering
+// dipakai tidak menulis ke database di setiap request.
+const apiKeyTouchInterval = time.Minute
+
+// APIKeyMiddleware mengautentikasi consumer dengan API key dari header atau
+// query parameter di cfg. Jika berhasil, claims berisi ID, nama, dan role
+// consumer sehingga AuthorizeMiddleware berlaku sama seperti untuk JWT. Key
+// selalu dihapus dari request agar tidak diteruskan ke upstream atau tercatat
+// di log.
+func APIKeyMiddleware(cfg config.APIKeyConfig, keys *database.APIKeyRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		presented := extractAPIKey(c, cfg)
+		stripAPIKey(c, cfg)
+		if presented == "" {
+			abortAPIKey(c, problem.CodeAPIKeyMissing, "API key is required")
+			return
+		}
+
+		prefix, ok := auth.ParseAPIKey(presented)
+		if !ok {
+			abortAPIKey(c, problem.CodeAPIKeyInvalid, "API key is invalid")
+			return
+		}
+		key, err := keys.FindKey(ctx, prefix)
+		if errors.Is(err, database.ErrAPIKeyNotFound) {
+			requestid.Logf(ctx, "API key ditolak: prefix %s tidak dikenal", prefix)
+			abortAPIKey(c, problem.CodeAPIKeyInvalid, "API key is invalid")
+			return
+		}
+		if err != nil {
+			requestid.Logf(ctx, "Gagal membaca API key %s: %v", prefix, err)
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Could not verify API key"))
+			return
+		}
+		if !auth.APIKeyMatches(presented, key.KeyHash) {
+			requestid.Logf(ctx, "API key ditolak: secret salah untuk prefix %s", prefix)
+			abortAPIKey(c, problem.CodeAPIKeyInvalid, "API key is invalid")
+			return
+		}
+		now := time.Now()
+		if key.RevokedAt != nil {
+			abortAPIKey(c, problem.CodeAPIKeyRevoked, "API key has been revoked")
+			return
+		}
+		if key.Expired(now) {
+			abortAPIKey(c, problem.CodeAPIKeyExpired, "API key has expired")
+			return
+		}
+
+		consumer := key.Consumer
+		if consumer.RateLimitRequests > 0 && consumer.RateLimitWindowSec > 0 {
+			limit := rate.Limit(float64(consumer.RateLimitRequests) / float64(consumer.RateLimitWindowSec))
+			if !limiterFor("consumer:"+consumer.ID, limit, consumer.RateLimitRequests).Allow() {
+				problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited,
+					"You have exceeded the request limit for this API key. Please try again later."))
+				return
+			}
+		}
+
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+			if err := keys.TouchKey(ctx, key.ID, now.UTC()); err != nil {
+				requestid.Logf(ctx, "Gagal mencatat pemakaian API key %s: %v", prefix, err)
+			}
+		}
+
+		claims := &auth.Claims{
+			UserID:   consumer.ID,
+			Username: consumer.Name,
+			Roles:    consumer.Roles,
+		}
+		c.Set("userID", claims.UserID)
+		c.Set("username", claims.Username)
+		c.Set(auth.ClaimsContextKey, claims)
+		c.Set(apiKeyConsumerContextKey, consumer.Name)
+
+		requestid.Logf(ctx, "Authenticated consumer %s dengan API key %s", consumer.Name, prefix)
+		c.Next()
+	}
+}
+
+// EitherAuthMiddleware menerima JWT atau API key: request yang membawa API key
+// diautentikasi dengan apiKeyAuth, selebihnya dengan jwtAuth.
+func EitherAuthMiddleware(cfg config.APIKeyConfig, jwtAuth, apiKeyAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if extractAPIKey(c, cfg) != "" {
+			apiKeyAuth(c)
+			return
+		}
+		jwtAuth(c)
+	}
+}
+
+// extractAPIKey membaca API key dari header, lalu dari query parameter jika
+// diizinkan.
+func extractAPIKey(c *gin.Context, cfg config.APIKeyConfig) string {
+	if cfg.Header != "" {
+		if key := c.GetHeader(cfg.Header); key != "" {
+			return key
+		}
+	}
+	if cfg.QueryParam != "" {
+		return c.Query(cfg.QueryParam)
+	}
+	return ""
+}
+
+// stripAPIKey menghapus API key dari header dan query string request.
+func stripAPIKey(c *gin.Context, cfg config.APIKeyConfig) {
+	if cfg.Header != "" {
+		c.Request.Header.Del(cfg.Header)
+	}
+	if cfg.QueryParam != "" && c.Request.URL.RawQuery != "" {
+		q := c.Request.URL.Query()
+		if q.Has(cfg.QueryParam) {
+			q.Del(cfg.QueryParam)
+			c.Request.URL.RawQuery = q.Encode()
+		}
+	}
+}
+
+// abortAPIKey mengirim 401 untuk API key yang tidak ada atau tidak valid.
+func abortAPIKey(c *gin.Context, code, detail string) {
+	c.Header("WWW-Authenticate", `APIKey realm="api-gateway"`)
+	problem.Abort(c, problem.New(http.StatusUnauthorized, code, detail))
+}
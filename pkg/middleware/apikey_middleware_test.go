@@ -0,0 +1,165 @@
+// pkg/middleware/apikey_middleware_test.go
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAPIKeyRepo membuka database SQLite sementara untuk API key.
+func newAPIKeyRepo(t *testing.T) *database.APIKeyRepository {
+	t.Helper()
+	db, err := database.InitDB(config.DatabaseConfig{Driver: config.DriverSQLite, DSN: filepath.Join(t.TempDir(), "test.db")})
+	if err != nil {
+		t.Fatalf("InitDB: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return database.NewAPIKeyRepository(db)
+}
+
+// seedAPIKey membuat key untuk consumer dan mengembalikan key mentahnya.
+func seedAPIKey(t *testing.T, repo *database.APIKeyRepository, consumer *database.Consumer, modify func(*database.APIKey)) string {
+	t.Helper()
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := &database.APIKey{ConsumerID: consumer.ID, Prefix: prefix, KeyHash: hash}
+	if modify != nil {
+		modify(record)
+	}
+	if err := repo.CreateKey(context.Background(), record); err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	return key
+}
+
+// serveAPIKey mengirim request ke router dan mengembalikan status serta kode problem.
+func serveAPIKey(router *gin.Engine, target, header string) (int, string) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if header != "" {
+		req.Header.Set("X-API-Key", header)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var body struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return w.Code, body.Code
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	repo := newAPIKeyRepo(t)
+	consumer := &database.Consumer{Name: "batch", Roles: []string{"reporting"}}
+	if err := repo.CreateConsumer(ctx, consumer); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute).UTC()
+	active := seedAPIKey(t, repo, consumer, nil)
+	revoked := seedAPIKey(t, repo, consumer, func(k *database.APIKey) { k.RevokedAt = &past })
+	expired := seedAPIKey(t, repo, consumer, func(k *database.APIKey) { k.ExpiresAt = &past })
+	prefix, _ := auth.ParseAPIKey(active)
+	wrongSecret := prefix + "_secret-yang-salah"
+
+	cfg := config.APIKeyConfig{Header: "X-API-Key", QueryParam: "api_key"}
+	router := gin.New()
+	router.GET("/reports", APIKeyMiddleware(cfg, repo), func(c *gin.Context) {
+		value, _ := c.Get(auth.ClaimsContextKey)
+		claims := value.(*auth.Claims)
+		// Key tidak boleh ikut diteruskan ke upstream
+		if c.GetHeader("X-API-Key") != "" || c.Request.URL.Query().Has("api_key") {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		if claims.Username != "batch" || !claims.HasRole("reporting") {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name       string
+		target     string
+		header     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"key aktif di header", "/reports", active, http.StatusNoContent, ""},
+		{"key aktif di query", "/reports?api_key=" + active + "&page=2", "", http.StatusNoContent, ""},
+		{"tanpa key", "/reports", "", http.StatusUnauthorized, problem.CodeAPIKeyMissing},
+		{"format tidak dikenal", "/reports", "bukan-api-key", http.StatusUnauthorized, problem.CodeAPIKeyInvalid},
+		{"secret salah", "/reports", wrongSecret, http.StatusUnauthorized, problem.CodeAPIKeyInvalid},
+		{"key dicabut", "/reports", revoked, http.StatusUnauthorized, problem.CodeAPIKeyRevoked},
+		{"key kedaluwarsa", "/reports", expired, http.StatusUnauthorized, problem.CodeAPIKeyExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code := serveAPIKey(router, tt.target, tt.header)
+			if status != tt.wantStatus || code != tt.wantCode {
+				t.Errorf("status %d kode %q, want %d %q", status, code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAPIKeyMiddlewareConsumerRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	repo := newAPIKeyRepo(t)
+	limited := &database.Consumer{Name: "limited", RateLimitRequests: 2, RateLimitWindowSec: 60}
+	other := &database.Consumer{Name: "other", RateLimitRequests: 2, RateLimitWindowSec: 60}
+	for _, consumer := range []*database.Consumer{limited, other} {
+		if err := repo.CreateConsumer(ctx, consumer); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Dua key milik consumer yang sama berbagi satu kuota
+	first := seedAPIKey(t, repo, limited, nil)
+	second := seedAPIKey(t, repo, limited, nil)
+	otherKey := seedAPIKey(t, repo, other, nil)
+
+	router := gin.New()
+	router.GET("/reports", APIKeyMiddleware(config.APIKeyConfig{Header: "X-API-Key"}, repo), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	steps := []struct {
+		name       string
+		key        string
+		wantStatus int
+	}{
+		{"request pertama", first, http.StatusNoContent},
+		{"request kedua dengan key lain", second, http.StatusNoContent},
+		{"kuota consumer habis", first, http.StatusTooManyRequests},
+		{"consumer lain tidak terpengaruh", otherKey, http.StatusNoContent},
+	}
+	for _, step := range steps {
+		status, code := serveAPIKey(router, "/reports", step.key)
+		if status != step.wantStatus {
+			t.Fatalf("%s: status %d, want %d", step.name, status, step.wantStatus)
+		}
+		if status == http.StatusTooManyRequests && code != problem.CodeRateLimited {
+			t.Errorf("%s: kode %q, want %s", step.name, code, problem.CodeRateLimited)
+		}
+	}
+}
@@ -2,9 +2,11 @@
 package middleware
 
 import (
-	"api-gateway-go/pkg/handlers" // Untuk akses ke struct Claims
-	"fmt"
-	"log"
+	"api-gateway-go/pkg/auth" // Untuk akses ke struct Claims
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
@@ -13,64 +15,70 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// AuthMiddleware membuat middleware untuk otentikasi menggunakan JWT.
-func AuthMiddleware(secretKey string) gin.HandlerFunc {
+// AuthMiddleware membuat middleware untuk otentikasi menggunakan JWT. Token
+// buatan gateway diverifikasi dengan kunci lokal berdasarkan header kid, token
+// dari issuer eksternal dengan JWKS issuer tersebut (lihat auth.Verifier).
+// Token yang ada di daftar pencabutan revoked ditolak meskipun belum kedaluwarsa.
+// Jika mode sesi aktif dan header Authorization tidak ada, token dibaca dari
+// cookie sesi; request yang mengubah state lalu wajib membawa token CSRF.
+func AuthMiddleware(verifier *auth.Verifier, revoked *auth.RevocationStore, session config.SessionConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		var tokenString string
+		fromCookie := false
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			return
+		if authHeader != "" {
+			parts := strings.Split(authHeader, " ")
+			if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+				abortUnauthorized(c, problem.CodeTokenMalformed, "Authorization header format must be Bearer {token}")
+				return
+			}
+			tokenString = parts[1]
+		} else if session.Enabled {
+			if cookie, err := c.Cookie(session.CookieName); err == nil && cookie != "" {
+				tokenString = cookie
+				fromCookie = true
+			}
 		}
-
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header format must be Bearer {token}"})
+		if tokenString == "" {
+			abortUnauthorized(c, problem.CodeTokenMissing, "Authorization header is required")
 			return
 		}
 
-		tokenString := parts[1]
-		claims := &handlers.Claims{} // Menggunakan struct Claims dari handlers
-
-		// Parse token JWT
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			// Pastikan metode signing adalah yang diharapkan (HS256)
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(secretKey), nil
-		})
-
+		// Parse dan verifikasi token JWT
+		claims, err := verifier.Verify(c.Request.Context(), tokenString)
 		if err != nil {
-			if err == jwt.ErrSignatureInvalid {
-				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token signature"})
-				return
+			// Bedakan penyebab agar klien tahu kapan harus login ulang
+			switch {
+			case errors.Is(err, jwt.ErrTokenExpired):
+				abortUnauthorized(c, problem.CodeTokenExpired, "Token has expired")
+			case errors.Is(err, jwt.ErrTokenMalformed):
+				abortUnauthorized(c, problem.CodeTokenMalformed, "Token is malformed")
+			default:
+				// Alasan detail (kid tidak dikenal, audience salah, JWKS gagal diambil) hanya di log
+				requestid.Logf(c.Request.Context(), "Token ditolak: %v", err)
+				abortUnauthorized(c, problem.CodeTokenInvalid, "Token is invalid")
 			}
-			// Tangani error kedaluwarsa secara spesifik
-			// if verr, ok := err.(*jwt.ValidationError); ok {
-			// 	if verr.Errors&jwt.ValidationErrorMalformed != 0 {
-			// 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Malformed token"})
-			// 		return
-			// 	} else if verr.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet) != 0 {
-			// 		// Token kedaluwarsa atau belum valid
-			// 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token is expired or not valid yet"})
-			// 		return
-			// 	}
-			// }
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token: " + err.Error()})
 			return
 		}
 
-		if !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		if revoked != nil && revoked.IsRevoked(claims) {
+			abortUnauthorized(c, problem.CodeTokenRevoked, "Token has been revoked")
+			return
+		}
+
+		// Cookie dikirim browser secara otomatis, jadi request lintas situs yang
+		// mengubah state harus membuktikan asalnya dengan token CSRF
+		if fromCookie && !safeMethod(c.Request.Method) && !auth.CSRFMatches(c.GetHeader(session.CSRFHeader), claims.CSRF) {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeCSRFInvalid, "CSRF token is missing or invalid"))
 			return
 		}
 
 		// Token valid. Anda bisa menyimpan informasi dari claims ke context jika perlu.
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
-		// c.Set("claims", claims) // Atau simpan seluruh claims
+		c.Set(auth.ClaimsContextKey, claims)
 
-		log.Printf("Authenticated UserID: %s, Username: %s, ExpiresAt: %s",
+		requestid.Logf(c.Request.Context(), "Authenticated UserID: %s, Username: %s, ExpiresAt: %s",
 			claims.UserID,
 			claims.Username,
 			claims.ExpiresAt.Format(time.RFC3339),
@@ -79,3 +87,18 @@ func AuthMiddleware(secretKey string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// abortUnauthorized mengirim 401 dengan header WWW-Authenticate (RFC 6750).
+func abortUnauthorized(c *gin.Context, code, detail string) {
+	c.Header("WWW-Authenticate", `Bearer realm="api-gateway", error="invalid_token"`)
+	problem.Abort(c, problem.New(http.StatusUnauthorized, code, detail))
+}
+
+// safeMethod melaporkan apakah method HTTP tidak mengubah state (RFC 9110).
+func safeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
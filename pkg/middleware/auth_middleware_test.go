@@ -0,0 +1,114 @@
+// pkg/middleware/auth_middleware_test.go
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuthMiddlewareCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.AuthSecret = "test-secret-yang-cukup-panjang-untuk-hs256"
+	verifier, err := auth.NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	issuer := auth.NewIssuer(verifier.Keys(), time.Minute)
+
+	csrf, err := auth.NewCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, _, err := issuer.IssueSessionAccessToken("user-1", "alice", nil, auth.HashCSRFToken(csrf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bearer, _, err := issuer.IssueAccessToken("user-1", "alice", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := auth.NewCSRFToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessionCfg := config.SessionConfig{Enabled: true, CookieName: "gw_session", CSRFHeader: "X-CSRF-Token"}
+	router := gin.New()
+	router.Any("/resource", AuthMiddleware(verifier, nil, sessionCfg), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		cookie     string
+		bearer     string
+		csrfHeader string
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "cookie GET tanpa CSRF", method: http.MethodGet, cookie: session, wantStatus: http.StatusNoContent},
+		{name: "cookie POST tanpa CSRF", method: http.MethodPost, cookie: session, wantStatus: http.StatusForbidden, wantCode: problem.CodeCSRFInvalid},
+		{name: "cookie POST dengan CSRF benar", method: http.MethodPost, cookie: session, csrfHeader: csrf, wantStatus: http.StatusNoContent},
+		{name: "cookie DELETE dengan CSRF sesi lain", method: http.MethodDelete, cookie: session, csrfHeader: other, wantStatus: http.StatusForbidden, wantCode: problem.CodeCSRFInvalid},
+		{name: "cookie token tanpa klaim csrf", method: http.MethodPost, cookie: bearer, csrfHeader: csrf, wantStatus: http.StatusForbidden, wantCode: problem.CodeCSRFInvalid},
+		{name: "bearer POST tidak butuh CSRF", method: http.MethodPost, bearer: bearer, wantStatus: http.StatusNoContent},
+		{name: "header Authorization diutamakan atas cookie", method: http.MethodPost, cookie: session, bearer: bearer, wantStatus: http.StatusNoContent},
+		{name: "tanpa token", method: http.MethodPost, wantStatus: http.StatusUnauthorized, wantCode: problem.CodeTokenMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/resource", nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: sessionCfg.CookieName, Value: tt.cookie})
+			}
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.csrfHeader != "" {
+				req.Header.Set(sessionCfg.CSRFHeader, tt.csrfHeader)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				var body struct {
+					Code string `json:"code"`
+				}
+				json.Unmarshal(w.Body.Bytes(), &body)
+				if body.Code != tt.wantCode {
+					t.Errorf("kode %q, want %q", body.Code, tt.wantCode)
+				}
+			}
+		})
+	}
+
+	// Tanpa mode sesi, cookie diabaikan sama sekali
+	t.Run("mode sesi mati", func(t *testing.T) {
+		r := gin.New()
+		r.POST("/resource", AuthMiddleware(verifier, nil, config.SessionConfig{}), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		req := httptest.NewRequest(http.MethodPost, "/resource", nil)
+		req.AddCookie(&http.Cookie{Name: sessionCfg.CookieName, Value: session})
+		req.Header.Set(sessionCfg.CSRFHeader, csrf)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("status %d, want 401", w.Code)
+		}
+	})
+}
@@ -0,0 +1,74 @@
+// pkg/middleware/authz_middleware.go
+package middleware
+
+import (
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthorizeMiddleware memeriksa role dan scope token terhadap aturan
+// AUTHORIZATION rute. Harus dipasang setelah AuthMiddleware; rules sudah
+// disaring untuk method rute ini sehingga pemeriksaan tidak butuh lookup.
+// Token valid yang tidak memenuhi syarat mendapat 403, bukan 401.
+func AuthorizeMiddleware(rules []config.AuthzRuleConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get(auth.ClaimsContextKey)
+		claims, ok := value.(*auth.Claims)
+		if !ok {
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "Authorization requires an authenticated request"))
+			return
+		}
+
+		for _, rule := range rules {
+			if len(rule.Roles) > 0 && !slices.ContainsFunc(rule.Roles, claims.HasRole) {
+				requestid.Logf(c.Request.Context(), "Akses ditolak untuk UserID %s: butuh salah satu role %v, punya %v", claims.UserID, rule.Roles, claims.Roles)
+				problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeInsufficientRole, "This action requires one of the roles: "+strings.Join(rule.Roles, ", ")).
+					With("required_roles", rule.Roles))
+				return
+			}
+			for _, scope := range rule.Scopes {
+				if claims.HasScope(scope) {
+					continue
+				}
+				requestid.Logf(c.Request.Context(), "Akses ditolak untuk UserID %s: butuh scope %v, punya %q", claims.UserID, rule.Scopes, claims.Scope)
+				// RFC 6750 bagian 3.1
+				c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer realm="api-gateway", error="insufficient_scope", scope="%s"`, strings.Join(rule.Scopes, " ")))
+				problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeInsufficientScope, "This action requires the scopes: "+strings.Join(rule.Scopes, " ")).
+					With("required_scopes", rule.Scopes))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// MachineOnlyMiddleware hanya meneruskan identitas mesin: consumer API key dan
+// access token client_credentials yang diterbitkan gateway. Token pengguna,
+// termasuk token issuer eksternal, ditolak apa pun role-nya. Harus dipasang
+// setelah AuthMiddleware atau APIKeyMiddleware.
+func MachineOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(apiKeyConsumerContextKey); ok {
+			c.Next()
+			return
+		}
+		value, _ := c.Get(auth.ClaimsContextKey)
+		claims, ok := value.(*auth.Claims)
+		if ok && claims.Machine() && claims.Issuer == auth.TokenIssuer {
+			c.Next()
+			return
+		}
+		if ok {
+			requestid.Logf(c.Request.Context(), "[AUTHZ] Akses ditolak untuk UserID %s: %s hanya untuk API key atau OAuth client", claims.UserID, c.Request.URL.Path)
+		}
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeMachineRequired, "This endpoint requires an API key or OAuth client credentials"))
+	}
+}
@@ -0,0 +1,184 @@
+// pkg/middleware/authz_middleware_test.go
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestAuthorizeMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var cfg config.Config
+	cfg.AuthSecret = "test-secret-yang-cukup-panjang-untuk-hs256"
+	verifier, err := auth.NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	sign := func(roles []string, scope string) string {
+		t.Helper()
+		token, err := verifier.Keys().Sign(&auth.Claims{
+			UserID:           "user-1",
+			Username:         "alice",
+			Roles:            roles,
+			Scope:            scope,
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	rules := []config.AuthzRuleConfig{
+		{Methods: []string{"*"}, Roles: []string{"admin", "auditor"}},
+		{Methods: []string{"*"}, Scopes: []string{"reports:read", "reports:export"}},
+	}
+	router := gin.New()
+	router.GET("/reports", AuthMiddleware(verifier, nil, config.SessionConfig{}), AuthorizeMiddleware(rules), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name       string
+		token      string
+		wantStatus int
+		wantCode   string
+	}{
+		{"role dan semua scope", sign([]string{"auditor"}, "reports:read reports:export"), http.StatusNoContent, ""},
+		{"tanpa role yang diminta", sign([]string{"employee"}, "reports:read reports:export"), http.StatusForbidden, problem.CodeInsufficientRole},
+		{"scope kurang satu", sign([]string{"admin"}, "reports:read"), http.StatusForbidden, problem.CodeInsufficientScope},
+		{"tanpa token", "", http.StatusUnauthorized, problem.CodeTokenMissing},
+		{"token tidak valid", "bukan.jwt.valid", http.StatusUnauthorized, problem.CodeTokenMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/reports", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode == "" {
+				return
+			}
+			var body struct {
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.Code != tt.wantCode {
+				t.Errorf("kode %q, want %q", body.Code, tt.wantCode)
+			}
+			// Hanya kekurangan scope yang punya challenge RFC 6750; role tidak
+			challenge := w.Header().Get("WWW-Authenticate")
+			if tt.wantCode == problem.CodeInsufficientScope && !strings.Contains(challenge, `error="insufficient_scope"`) {
+				t.Errorf("WWW-Authenticate = %q, want insufficient_scope", challenge)
+			}
+			if tt.wantCode == problem.CodeInsufficientRole && challenge != "" {
+				t.Errorf("WWW-Authenticate = %q, want kosong", challenge)
+			}
+		})
+	}
+}
+
+func TestMachineOnlyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	var cfg config.Config
+	cfg.AuthSecret = "test-secret-yang-cukup-panjang-untuk-hs256"
+	verifier, err := auth.NewVerifier(cfg)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	issuer := auth.NewIssuer(verifier.Keys(), time.Minute)
+	userToken, _, err := issuer.IssueAccessToken("user-1", "alice", []string{"introspect"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientToken, _, err := issuer.IssueClientToken("client-1", "billing", []string{"introspect"}, "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainClient, _, err := issuer.IssueClientToken("client-2", "reporting", nil, "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Klaim client_id dari issuer lain tidak menjadikan token identitas mesin gateway
+	foreignClient, err := verifier.Keys().Sign(&auth.Claims{
+		UserID:           "client-3",
+		ClientID:         "asing",
+		Roles:            []string{"introspect"},
+		RegisteredClaims: jwt.RegisteredClaims{Issuer: "https://idp.example.com", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := newAPIKeyRepo(t)
+	consumer := &database.Consumer{Name: "monitor", Roles: []string{"introspect"}}
+	if err := repo.CreateConsumer(ctx, consumer); err != nil {
+		t.Fatal(err)
+	}
+	apiKey := seedAPIKey(t, repo, consumer, nil)
+
+	// Rantai yang sama dengan /auth/introspect
+	apiKeyCfg := config.APIKeyConfig{Header: "X-API-Key"}
+	router := gin.New()
+	router.POST("/auth/introspect",
+		EitherAuthMiddleware(apiKeyCfg, AuthMiddleware(verifier, nil, config.SessionConfig{}), APIKeyMiddleware(apiKeyCfg, repo)),
+		MachineOnlyMiddleware(),
+		AuthorizeMiddleware([]config.AuthzRuleConfig{{Roles: []string{"introspect"}}}),
+		func(c *gin.Context) { c.Status(http.StatusNoContent) },
+	)
+
+	tests := []struct {
+		name       string
+		bearer     string
+		apiKey     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"token pengguna dengan role introspect", userToken, "", http.StatusForbidden, problem.CodeMachineRequired},
+		{"token client_credentials", clientToken, "", http.StatusNoContent, ""},
+		{"token client tanpa role", plainClient, "", http.StatusForbidden, problem.CodeInsufficientRole},
+		{"client_id dari issuer lain", foreignClient, "", http.StatusForbidden, problem.CodeMachineRequired},
+		{"consumer API key", "", apiKey, http.StatusNoContent, ""},
+		{"tanpa kredensial", "", "", http.StatusUnauthorized, problem.CodeTokenMissing},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/introspect", nil)
+			if tt.bearer != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.apiKey != "" {
+				req.Header.Set(apiKeyCfg.Header, tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			var body struct {
+				Code string `json:"code"`
+			}
+			json.Unmarshal(w.Body.Bytes(), &body)
+			if w.Code != tt.wantStatus || body.Code != tt.wantCode {
+				t.Errorf("status %d kode %q, want %d %q", w.Code, body.Code, tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+// pkg/middleware/ext_authz_middleware.go
+package middleware
+
+import (
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxExtAuthzBody adalah ukuran maksimum response policy service.
+const maxExtAuthzBody = 64 << 10
+
+// extAuthzCredentialHeaders tidak dikirim ke policy service kecuali disebut
+// eksplisit di INCLUDE_HEADERS; identitas pemanggil sudah dikirim sebagai klaim.
+var extAuthzCredentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// ExtAuthzRequest adalah body JSON yang dikirim ke policy service.
+type ExtAuthzRequest struct {
+	Route     string            `json:"route"` // PATH_PREFIX rute
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Query     string            `json:"query,omitempty"`
+	Headers   map[string]string `json:"headers"`
+	ClientIP  string            `json:"client_ip"`
+	RequestID string            `json:"request_id,omitempty"`
+	Identity  *ExtAuthzIdentity `json:"identity"` // null untuk request tanpa autentikasi
+}
+
+// ExtAuthzIdentity adalah klaim pemanggil dari AuthMiddleware atau
+// APIKeyMiddleware.
+type ExtAuthzIdentity struct {
+	UserID   string   `json:"user_id"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
+	Scopes   []string `json:"scopes"`
+	Issuer   string   `json:"issuer,omitempty"`
+	ClientID string   `json:"client_id,omitempty"`
+	Machine  bool     `json:"machine"`
+}
+
+// ExtAuthzResponse adalah jawaban policy service. Status 2xx berarti izinkan
+// kecuali allow bernilai false; 401/403 berarti tolak. Body boleh kosong.
+type ExtAuthzResponse struct {
+	Allow   *bool             `json:"allow"`
+	Reason  string            `json:"reason"`  // Dikirim ke klien sebagai detail jika ditolak
+	Headers map[string]string `json:"headers"` // Ditambahkan ke request upstream jika diizinkan
+}
+
+// extAuthzDecision adalah keputusan policy service yang bisa disimpan di cache.
+type extAuthzDecision struct {
+	allow   bool
+	status  int // Status untuk klien jika ditolak: 401 atau 403
+	reason  string
+	headers map[string]string
+	expires time.Time
+}
+
+// ExtAuthzMiddleware meminta keputusan policy service untuk setiap request ke
+// rute sebelum diteruskan. Dipasang setelah autentikasi dan AUTHORIZATION
+// (jika ada) sehingga klaim pemanggil ikut dikirim. Kegagalan callout (timeout,
+// error jaringan, status selain 2xx/401/403) ditolak dengan 503 kecuali
+// FAIL_OPEN aktif. identityHeaders adalah header identitas yang dihapus proxy;
+// nilainya dari klien tidak dikirim ke policy service karena bisa dipalsukan.
+func ExtAuthzMiddleware(route string, cfg config.ExtAuthzConfig, identityHeaders []string) gin.HandlerFunc {
+	cfg = cfg.WithDefaults()
+	spoofable := make(map[string]bool, len(identityHeaders))
+	for _, name := range identityHeaders {
+		spoofable[http.CanonicalHeaderKey(name)] = true
+	}
+	client := &http.Client{Timeout: cfg.Timeout}
+	var cache *extAuthzCache
+	if cfg.Cache.TTL > 0 {
+		cache = newExtAuthzCache(cfg.Cache.MaxEntries)
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var claims *auth.Claims
+		if value, ok := c.Get(auth.ClaimsContextKey); ok {
+			claims, _ = value.(*auth.Claims)
+		}
+
+		var key string
+		if cache != nil {
+			key = extAuthzCacheKey(c, claims, cfg.Cache.Key)
+			if decision, ok := cache.get(key); ok {
+				applyExtAuthz(c, decision, "cache")
+				return
+			}
+		}
+
+		decision, err := callExtAuthz(ctx, client, cfg, buildExtAuthzRequest(c, route, claims, cfg.IncludeHeaders, spoofable))
+		if err != nil {
+			if cfg.FailOpen {
+				requestid.Logf(ctx, "[AUTHZ] Callout ext_authz rute %s gagal, request diizinkan (FAIL_OPEN): %v", route, err)
+				c.Next()
+				return
+			}
+			requestid.Logf(ctx, "[AUTHZ] Callout ext_authz rute %s gagal, request ditolak: %v", route, err)
+			problem.Abort(c, problem.New(http.StatusServiceUnavailable, problem.CodeExtAuthzUnavailable, "Authorization service is unavailable. Please try again later."))
+			return
+		}
+		if cache != nil {
+			decision.expires = time.Now().Add(cfg.Cache.TTL)
+			cache.put(key, decision)
+		}
+		applyExtAuthz(c, decision, "policy")
+	}
+}
+
+// applyExtAuthz meneruskan atau menolak request sesuai keputusan.
+func applyExtAuthz(c *gin.Context, d *extAuthzDecision, source string) {
+	if !d.allow {
+		user := "-"
+		if v, ok := c.Get(auth.ClaimsContextKey); ok {
+			if claims, ok := v.(*auth.Claims); ok {
+				user = claims.UserID
+			}
+		}
+		requestid.Logf(c.Request.Context(), "[AUTHZ] Akses ditolak ext_authz (%s) untuk UserID %s: %s %s %s", source, user, c.Request.Method, c.Request.URL.Path, d.reason)
+		detail := d.reason
+		if detail == "" {
+			detail = "Access denied by authorization policy"
+		}
+		problem.Abort(c, problem.New(d.status, problem.CodeExtAuthzDenied, detail))
+		return
+	}
+	for name, value := range d.headers {
+		c.Request.Header.Set(name, value)
+	}
+	c.Next()
+}
+
+// buildExtAuthzRequest mengumpulkan metadata request untuk policy service.
+func buildExtAuthzRequest(c *gin.Context, route string, claims *auth.Claims, include []string, spoofable map[string]bool) *ExtAuthzRequest {
+	headers := make(map[string]string)
+	if len(include) > 0 {
+		for _, name := range include {
+			name = http.CanonicalHeaderKey(name)
+			if values := c.Request.Header.Values(name); len(values) > 0 && !spoofable[name] {
+				headers[name] = strings.Join(values, ", ")
+			}
+		}
+	} else {
+		for name, values := range c.Request.Header {
+			if !slices.Contains(extAuthzCredentialHeaders, name) && !spoofable[name] {
+				headers[name] = strings.Join(values, ", ")
+			}
+		}
+	}
+
+	req := &ExtAuthzRequest{
+		Route:     route,
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Query:     c.Request.URL.RawQuery,
+		Headers:   headers,
+		ClientIP:  c.ClientIP(),
+		RequestID: requestid.Get(c),
+	}
+	if claims != nil {
+		req.Identity = &ExtAuthzIdentity{
+			UserID:   claims.UserID,
+			Username: claims.Username,
+			Roles:    claims.Roles,
+			Scopes:   claims.Scopes(),
+			Issuer:   claims.Issuer,
+			ClientID: claims.ClientID,
+			Machine:  claims.Machine(),
+		}
+	}
+	return req
+}
+
+// callExtAuthz mengirim request ke policy service dan membaca keputusannya.
+// Error berarti tidak ada keputusan (bukan penolakan).
+func callExtAuthz(ctx context.Context, client *http.Client, cfg config.ExtAuthzConfig, body *ExtAuthzRequest) (*extAuthzDecision, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var decision extAuthzDecision
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		decision.allow = true
+		decision.status = http.StatusForbidden
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		decision.status = resp.StatusCode
+	default:
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxExtAuthzBody))
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return &decision, nil
+	}
+	var answer ExtAuthzResponse
+	if err := json.Unmarshal(raw, &answer); err != nil {
+		if decision.allow {
+			// Jawaban izinkan yang tidak bisa dibaca tidak boleh dianggap izin
+			return nil, fmt.Errorf("response tidak valid: %w", err)
+		}
+		return &decision, nil
+	}
+	if answer.Allow != nil && !*answer.Allow {
+		decision.allow = false
+	}
+	decision.reason = answer.Reason
+	if decision.allow && len(answer.Headers) > 0 {
+		decision.headers = make(map[string]string, len(answer.Headers))
+		for name, value := range answer.Headers {
+			if validExtAuthzHeader(name, value) {
+				decision.headers[http.CanonicalHeaderKey(name)] = value
+			}
+		}
+	}
+	return &decision, nil
+}
+
+// validExtAuthzHeader menolak header yang tidak boleh diubah policy service:
+// nama atau nilai tidak valid, header hop-by-hop, dan Host.
+func validExtAuthzHeader(name, value string) bool {
+	if name == "" || strings.ContainsAny(value, "\r\n\x00") {
+		return false
+	}
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	switch http.CanonicalHeaderKey(name) {
+	case "Host", "Connection", "Content-Length", "Transfer-Encoding", "Upgrade", "Te", "Trailer", "Keep-Alive":
+		return false
+	}
+	return true
+}
+
+// extAuthzCacheKey menyusun kunci cache dari atribut di CACHE.KEY. Kunci
+// di-hash agar panjangnya tetap meskipun atribut berisi nilai panjang.
+func extAuthzCacheKey(c *gin.Context, claims *auth.Claims, attrs []string) string {
+	h := sha256.New()
+	for _, attr := range attrs {
+		var value string
+		switch {
+		case attr == config.ExtAuthzKeyMethod:
+			value = c.Request.Method
+		case attr == config.ExtAuthzKeyPath:
+			value = c.Request.URL.Path
+		case attr == config.ExtAuthzKeyClientIP:
+			value = c.ClientIP()
+		case strings.HasPrefix(attr, "header:"):
+			value = strings.Join(c.Request.Header.Values(strings.TrimPrefix(attr, "header:")), ", ")
+		case strings.HasPrefix(attr, "query:"):
+			value = strings.Join(c.Request.URL.Query()[strings.TrimPrefix(attr, "query:")], ",")
+		case claims != nil:
+			switch attr {
+			case config.ExtAuthzKeyUserID:
+				value = claims.Issuer + "|" + claims.UserID
+			case config.ExtAuthzKeyRoles:
+				roles := slices.Clone(claims.Roles)
+				slices.Sort(roles)
+				value = strings.Join(roles, ",")
+			case config.ExtAuthzKeyScope:
+				scopes := claims.Scopes()
+				slices.Sort(scopes)
+				value = strings.Join(scopes, " ")
+			case config.ExtAuthzKeyClientID:
+				value = claims.ClientID
+			}
+		}
+		h.Write([]byte(attr))
+		h.Write([]byte{0})
+		h.Write([]byte(value))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// extAuthzCache menyimpan keputusan ext_authz satu rute di memori. Cache
+// dibuat ulang setiap reload konfigurasi.
+type extAuthzCache struct {
+	max int
+
+	mu      sync.Mutex
+	entries map[string]*extAuthzDecision
+}
+
+func newExtAuthzCache(max int) *extAuthzCache {
+	return &extAuthzCache{max: max, entries: make(map[string]*extAuthzDecision)}
+}
+
+func (c *extAuthzCache) get(key string) (*extAuthzDecision, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(d.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return d, true
+}
+
+// put menyimpan keputusan. Jika cache penuh, entri kedaluwarsa dibuang lebih
+// dulu; jika masih penuh, satu entri sembarang dibuang.
+func (c *extAuthzCache) put(key string, d *extAuthzDecision) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.max {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < c.max {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = d
+}
@@ -0,0 +1,291 @@
+// pkg/middleware/ext_authz_middleware_test.go
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// policyServer adalah policy service palsu. Path /deny ditolak, path /error
+// dijawab 500, selain itu diizinkan dengan header tambahan. Jika failing
+// aktif semua request dijawab 503.
+type policyServer struct {
+	*httptest.Server
+	calls   atomic.Int32
+	failing atomic.Bool
+	last    atomic.Pointer[ExtAuthzRequest]
+}
+
+func newPolicyServer(t *testing.T) *policyServer {
+	t.Helper()
+	p := &policyServer{}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.calls.Add(1)
+		var req ExtAuthzRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "body tidak valid", http.StatusBadRequest)
+			return
+		}
+		p.last.Store(&req)
+		switch {
+		case p.failing.Load():
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case req.Path == "/deny":
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(ExtAuthzResponse{Reason: "Denied by test policy"})
+		case req.Path == "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			json.NewEncoder(w).Encode(ExtAuthzResponse{Headers: map[string]string{
+				"X-Policy-Decision": "allow",
+				"Host":              "evil.example",
+			}})
+		}
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+// newExtAuthzRouter memasang ExtAuthzMiddleware di belakang middleware yang
+// mengisi klaim dari header X-Test-User (kosong = tanpa autentikasi).
+// Handler akhir mengembalikan header X-Policy-Decision dan Host yang diterima.
+func newExtAuthzRouter(cfg config.ExtAuthzConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if user := c.GetHeader("X-Test-User"); user != "" {
+			c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: user, Username: user, Roles: []string{"employee"}})
+		}
+	})
+	router.Use(ExtAuthzMiddleware("/api", cfg, []string{"X-User-Id"}))
+	router.Any("/*path", func(c *gin.Context) {
+		c.Header("X-Seen-Decision", c.GetHeader("X-Policy-Decision"))
+		c.Header("X-Seen-Host", c.Request.Host)
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func serveExtAuthz(router *gin.Engine, method, path, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if user != "" {
+		req.Header.Set("X-Test-User", user)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func problemCode(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response bukan JSON: %s", w.Body.String())
+	}
+	return body.Code
+}
+
+func TestExtAuthzDecision(t *testing.T) {
+	policy := newPolicyServer(t)
+	router := newExtAuthzRouter(config.ExtAuthzConfig{URL: policy.URL})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"diizinkan", "/allow", http.StatusNoContent, ""},
+		{"ditolak", "/deny", http.StatusForbidden, problem.CodeExtAuthzDenied},
+		{"status tak dikenal dianggap gagal", "/error", http.StatusServiceUnavailable, problem.CodeExtAuthzUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveExtAuthz(router, http.MethodGet, tt.path, "alice")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantCode != "" {
+				if code := problemCode(t, w); code != tt.wantCode {
+					t.Errorf("kode %q, want %q", code, tt.wantCode)
+				}
+				return
+			}
+			if got := w.Header().Get("X-Seen-Decision"); got != "allow" {
+				t.Errorf("header policy tidak diteruskan ke upstream: %q", got)
+			}
+			if got := w.Header().Get("X-Seen-Host"); got == "evil.example" {
+				t.Error("policy service bisa mengganti Host")
+			}
+		})
+	}
+}
+
+func TestExtAuthzRequestOmitsCredentialsAndSpoofedIdentity(t *testing.T) {
+	policy := newPolicyServer(t)
+	router := newExtAuthzRouter(config.ExtAuthzConfig{URL: policy.URL})
+
+	req := httptest.NewRequest(http.MethodGet, "/allow?q=1", nil)
+	req.Header.Set("X-Test-User", "alice")
+	req.Header.Set("Authorization", "Bearer rahasia")
+	req.Header.Set("X-User-Id", "admin")
+	req.Header.Set("X-Custom", "nilai")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	sent := policy.last.Load()
+	if sent == nil {
+		t.Fatal("policy service tidak dipanggil")
+	}
+	if _, ok := sent.Headers["Authorization"]; ok {
+		t.Error("header Authorization dikirim ke policy service")
+	}
+	if _, ok := sent.Headers["X-User-Id"]; ok {
+		t.Error("header identitas dari klien dikirim ke policy service")
+	}
+	if sent.Headers["X-Custom"] != "nilai" || sent.Query != "q=1" || sent.Route != "/api" {
+		t.Errorf("request ext_authz tidak lengkap: %+v", sent)
+	}
+	if sent.Identity == nil || sent.Identity.UserID != "alice" || sent.Identity.Machine {
+		t.Errorf("identitas = %+v, want alice bukan machine", sent.Identity)
+	}
+}
+
+func TestExtAuthzCache(t *testing.T) {
+	tests := []struct {
+		name      string
+		key       []string
+		requests  [][3]string // method, path, user
+		wantCalls int32
+	}{
+		{"request sama memakai cache", nil, [][3]string{{"GET", "/allow", "alice"}, {"GET", "/allow", "alice"}}, 1},
+		{"penolakan juga di-cache", nil, [][3]string{{"GET", "/deny", "alice"}, {"GET", "/deny", "alice"}}, 1},
+		{"pengguna berbeda", nil, [][3]string{{"GET", "/allow", "alice"}, {"GET", "/allow", "bob"}}, 2},
+		{"tanpa autentikasi dipisah dari pengguna", nil, [][3]string{{"GET", "/allow", ""}, {"GET", "/allow", "alice"}}, 2},
+		{"method berbeda", nil, [][3]string{{"GET", "/allow", "alice"}, {"POST", "/allow", "alice"}}, 2},
+		{"path berbeda", nil, [][3]string{{"GET", "/allow", "alice"}, {"GET", "/allow/2", "alice"}}, 2},
+		{"kunci hanya path", []string{config.ExtAuthzKeyPath}, [][3]string{{"GET", "/allow", "alice"}, {"POST", "/allow", "bob"}}, 1},
+		{"kunci role sama untuk pengguna berbeda", []string{config.ExtAuthzKeyPath, config.ExtAuthzKeyRoles}, [][3]string{{"GET", "/allow", "alice"}, {"GET", "/allow", "bob"}}, 1},
+		{"kegagalan tidak di-cache", nil, [][3]string{{"GET", "/error", "alice"}, {"GET", "/error", "alice"}}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newPolicyServer(t)
+			router := newExtAuthzRouter(config.ExtAuthzConfig{
+				URL:   policy.URL,
+				Cache: config.ExtAuthzCacheConfig{TTL: time.Minute, Key: tt.key},
+			})
+			var first int
+			for i, r := range tt.requests {
+				w := serveExtAuthz(router, r[0], r[1], r[2])
+				if i == 0 {
+					first = w.Code
+				} else if r == tt.requests[0] && w.Code != first {
+					t.Errorf("request %d status %d, want %d seperti request pertama", i, w.Code, first)
+				}
+			}
+			if got := policy.calls.Load(); got != tt.wantCalls {
+				t.Errorf("policy dipanggil %d kali, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestExtAuthzCacheExpires(t *testing.T) {
+	policy := newPolicyServer(t)
+	router := newExtAuthzRouter(config.ExtAuthzConfig{
+		URL:   policy.URL,
+		Cache: config.ExtAuthzCacheConfig{TTL: 50 * time.Millisecond},
+	})
+	serveExtAuthz(router, http.MethodGet, "/allow", "alice")
+	serveExtAuthz(router, http.MethodGet, "/allow", "alice")
+	if got := policy.calls.Load(); got != 1 {
+		t.Fatalf("policy dipanggil %d kali sebelum TTL habis, want 1", got)
+	}
+	time.Sleep(80 * time.Millisecond)
+	serveExtAuthz(router, http.MethodGet, "/allow", "alice")
+	if got := policy.calls.Load(); got != 2 {
+		t.Errorf("policy dipanggil %d kali setelah TTL habis, want 2", got)
+	}
+}
+
+func TestExtAuthzCacheEvictsWhenFull(t *testing.T) {
+	cache := newExtAuthzCache(2)
+	expires := time.Now().Add(time.Minute)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.put(key, &extAuthzDecision{allow: true, expires: expires})
+	}
+	if n := len(cache.entries); n != 2 {
+		t.Errorf("%d entri, want 2", n)
+	}
+	if _, ok := cache.get("c"); !ok {
+		t.Error("entri terbaru terbuang")
+	}
+}
+
+func TestExtAuthzFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		failOpen   bool
+		timeout    time.Duration
+		slow       bool
+		wantStatus int
+	}{
+		{name: "gagal ditolak", wantStatus: http.StatusServiceUnavailable},
+		{name: "gagal diizinkan dengan FAIL_OPEN", failOpen: true, wantStatus: http.StatusNoContent},
+		{name: "timeout ditolak", timeout: 20 * time.Millisecond, slow: true, wantStatus: http.StatusServiceUnavailable},
+		{name: "timeout diizinkan dengan FAIL_OPEN", failOpen: true, timeout: 20 * time.Millisecond, slow: true, wantStatus: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newPolicyServer(t)
+			url := policy.URL
+			if tt.slow {
+				release := make(chan struct{})
+				slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					<-release
+				}))
+				t.Cleanup(slow.Close)
+				t.Cleanup(func() { close(release) })
+				url = slow.URL
+			} else {
+				policy.failing.Store(true)
+			}
+			router := newExtAuthzRouter(config.ExtAuthzConfig{
+				URL:      url,
+				FailOpen: tt.failOpen,
+				Timeout:  tt.timeout,
+				Cache:    config.ExtAuthzCacheConfig{TTL: time.Minute},
+			})
+
+			w := serveExtAuthz(router, http.MethodGet, "/allow", "alice")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.slow {
+				return
+			}
+			// Policy service pulih: keputusan berikutnya diambil langsung, bukan
+			// dari cache kegagalan atau izin FAIL_OPEN
+			policy.failing.Store(false)
+			w = serveExtAuthz(router, http.MethodGet, "/allow", "alice")
+			if w.Code != http.StatusNoContent || w.Header().Get("X-Seen-Decision") != "allow" {
+				t.Errorf("setelah pulih status %d decision %q, want 204 allow", w.Code, w.Header().Get("X-Seen-Decision"))
+			}
+			if got := policy.calls.Load(); got != 2 {
+				t.Errorf("policy dipanggil %d kali, want 2", got)
+			}
+		})
+	}
+}
@@ -2,13 +2,19 @@
 package middleware
 
 import (
+	"api-gateway-go/pkg/requestid"
 	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-func LoggingMiddleware() gin.HandlerFunc {
+// LoggingMiddleware mencatat satu baris log per request. Nilai query parameter
+// API key (redactParam) disamarkan karena request yang ditolak sebelum
+// APIKeyMiddleware menghapusnya masih membawa key di URL.
+func LoggingMiddleware(redactParam string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		c.Next() // Proses request berikutnya dalam chain
@@ -19,21 +25,42 @@ func LoggingMiddleware() gin.HandlerFunc {
 		statusCode := c.Writer.Status()
 		path := c.Request.URL.Path
 		if c.Request.URL.RawQuery != "" {
-			path = path + "?" + c.Request.URL.RawQuery
+			path = RedactQuery(path+"?"+c.Request.URL.RawQuery, redactParam)
 		}
 
-		log.Printf("[GATEWAY] | %3d | %13v | %15s | %-7s | %s",
+		id := requestid.Get(c)
+
+		log.Printf("[GATEWAY] | %3d | %13v | %15s | %-7s | %s | request_id=%s",
 			statusCode,
 			latency,
 			clientIP,
 			method,
 			path,
+			id,
 		)
 
 		if len(c.Errors) > 0 {
 			for _, e := range c.Errors.Errors() {
-				log.Printf("[ERROR] %s", e)
+				log.Printf("[ERROR] %s request_id=%s", e, id)
 			}
 		}
 	}
 }
+
+// RedactQuery mengganti nilai query parameter name di path dengan "REDACTED".
+func RedactQuery(path, name string) string {
+	p, rawQuery, found := strings.Cut(path, "?")
+	if name == "" || !found {
+		return path
+	}
+	// Bagian yang gagal di-parse dibuang karena bisa saja berisi key; pasangan
+	// yang valid tetap dikembalikan
+	q, err := url.ParseQuery(rawQuery)
+	if err == nil && !q.Has(name) {
+		return path
+	}
+	if q.Has(name) {
+		q.Set(name, "REDACTED")
+	}
+	return p + "?" + q.Encode()
+}
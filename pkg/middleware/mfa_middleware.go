@@ -0,0 +1,29 @@
+// pkg/middleware/mfa_middleware.go
+package middleware
+
+import (
+	"api-gateway-go/pkg/auth"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAEnrollmentMiddleware menerima mfa_token enrollment dari /auth/login
+// (pengguna yang wajib MFA tetapi belum mendaftar) sebagai Bearer token;
+// token lain diautentikasi dengan accessAuth. Untuk mfa_token, claims hanya
+// berisi user ID dan challenge disimpan di auth.MFAChallengeContextKey.
+func MFAEnrollmentMiddleware(keys *auth.KeySet, revoked *auth.RevocationStore, accessAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if ok {
+			challenge, err := keys.ParseMFAChallenge(token, auth.MFAPurposeEnroll)
+			if err == nil && !revoked.IsTokenRevoked(challenge.ID) {
+				c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: challenge.Subject})
+				c.Set(auth.MFAChallengeContextKey, challenge)
+				c.Next()
+				return
+			}
+		}
+		accessAuth(c)
+	}
+}
@@ -2,6 +2,7 @@
 package middleware
 
 import (
+	"api-gateway-go/pkg/problem"
 	"net/http"
 	"sync" // Untuk map yang aman dari concurrent access
 
@@ -9,7 +10,7 @@ import (
 	"golang.org/x/time/rate"
 )
 
-// Untuk rate limiting per IP, kita butuh map untuk menyimpan limiter per IP
+// Map limiter per IP, dan per consumer API key dengan key "consumer:<id>"
 var (
 	mu       sync.Mutex
 	limiters = make(map[string]*rate.Limiter)
@@ -20,23 +21,33 @@ var (
 // b adalah burst size (misal, 5 request burst)
 func RateLimitMiddlewarePerIP(r rate.Limit, b int) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ip := c.ClientIP()
-
-		mu.Lock()
-		limiter, exists := limiters[ip]
-		if !exists {
-			limiter = rate.NewLimiter(r, b)
-			limiters[ip] = limiter
-		}
-		mu.Unlock()
-
+		limiter := limiterFor(c.ClientIP(), r, b)
 		if !limiter.Allow() {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"error":   "Too many requests",
-				"message": "You have exceeded the request limit. Please try again later.",
-			})
+			problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited,
+				"You have exceeded the request limit. Please try again later."))
 			return
 		}
 		c.Next()
 	}
 }
+
+// limiterFor mengembalikan limiter untuk key (IP atau consumer). Limiter
+// dipakai lintas reload konfigurasi; rate atau burst disesuaikan jika berubah
+// agar tidak perlu mereset state klien.
+func limiterFor(key string, r rate.Limit, b int) *rate.Limiter {
+	mu.Lock()
+	limiter, exists := limiters[key]
+	if !exists {
+		limiter = rate.NewLimiter(r, b)
+		limiters[key] = limiter
+	}
+	mu.Unlock()
+
+	if limiter.Limit() != r {
+		limiter.SetLimit(r)
+	}
+	if limiter.Burst() != b {
+		limiter.SetBurst(b)
+	}
+	return limiter
+}
@@ -0,0 +1,15 @@
+// pkg/middleware/recovery_middleware.go
+package middleware
+
+import (
+	"api-gateway-go/pkg/problem"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecoveryHandler dipakai dengan gin.CustomRecovery: panic dicatat oleh gin
+// lalu klien menerima error INTERNAL_ERROR dalam format problem+json.
+func RecoveryHandler(c *gin.Context, err interface{}) {
+	problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, "An unexpected error occurred."))
+}
@@ -0,0 +1,32 @@
+// pkg/middleware/requestid_middleware.go
+package middleware
+
+import (
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/requestid"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDMiddleware memastikan setiap request punya request ID. ID dari klien
+// dipakai jika TRUST_INCOMING aktif dan formatnya aman; jika tidak, ID baru
+// dibuat. ID disimpan di gin.Context dan context request, diteruskan ke
+// upstream lewat header yang sama, dan dikembalikan di response.
+func RequestIDMiddleware(cfg config.RequestIDConfig) gin.HandlerFunc {
+	header := cfg.Header
+	if header == "" {
+		header = requestid.DefaultHeader
+	}
+	return func(c *gin.Context) {
+		id := c.GetHeader(header)
+		if !cfg.TrustIncoming || !requestid.Valid(id) {
+			id = requestid.Generate(cfg.Format)
+		}
+
+		c.Set(requestid.ContextKey, id)
+		c.Request = c.Request.WithContext(requestid.WithID(c.Request.Context(), id))
+		c.Request.Header.Set(header, id)
+		c.Header(header, id)
+		c.Next()
+	}
+}
@@ -0,0 +1,147 @@
+// pkg/problem/problem.go
+package problem
+
+import (
+	"api-gateway-go/pkg/requestid"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType adalah media type untuk error RFC 7807.
+const ContentType = "application/problem+json"
+
+// typePrefix adalah awalan URI "type" setiap problem; diikuti kode dalam huruf
+// kecil dengan tanda hubung, misal urn:api-gateway:problem:rate-limited.
+const typePrefix = "urn:api-gateway:problem:"
+
+// Kode error yang stabil. Klien sebaiknya bercabang berdasarkan kode ini,
+// bukan berdasarkan teks title atau detail.
+const (
+	CodeInvalidRequest      = "INVALID_REQUEST"
+	CodeRouteNotFound       = "ROUTE_NOT_FOUND"
+	CodeRateLimited         = "RATE_LIMITED"
+	CodeInternalError       = "INTERNAL_ERROR"
+	CodeInvalidCredentials  = "AUTH_INVALID_CREDENTIALS"
+	CodeAccountDisabled     = "AUTH_ACCOUNT_DISABLED"
+	CodeLoginThrottled      = "AUTH_LOGIN_THROTTLED"
+	CodeLoginBusy           = "AUTH_LOGIN_BUSY"
+	CodeMFATokenInvalid     = "AUTH_MFA_TOKEN_INVALID"
+	CodeMFACodeInvalid      = "AUTH_MFA_CODE_INVALID"
+	CodeMFAAlreadyEnabled   = "MFA_ALREADY_ENABLED"
+	CodeMFANotEnabled       = "MFA_NOT_ENABLED"
+	CodeTokenMissing        = "AUTH_TOKEN_MISSING"
+	CodeTokenMalformed      = "AUTH_TOKEN_MALFORMED"
+	CodeTokenExpired        = "AUTH_TOKEN_EXPIRED"
+	CodeTokenInvalid        = "AUTH_TOKEN_INVALID"
+	CodeTokenRevoked        = "AUTH_TOKEN_REVOKED"
+	CodeInsufficientRole    = "AUTH_INSUFFICIENT_ROLE"
+	CodeInsufficientScope   = "AUTH_INSUFFICIENT_SCOPE"
+	CodeMachineRequired     = "AUTH_MACHINE_IDENTITY_REQUIRED"
+	CodeExtAuthzDenied      = "EXT_AUTHZ_DENIED"
+	CodeExtAuthzUnavailable = "EXT_AUTHZ_UNAVAILABLE"
+	CodeCSRFInvalid         = "AUTH_CSRF_INVALID"
+	CodeRefreshTokenInvalid = "AUTH_REFRESH_TOKEN_INVALID"
+	CodeRefreshTokenReused  = "AUTH_REFRESH_TOKEN_REUSED"
+	CodeAPIKeyMissing       = "AUTH_API_KEY_MISSING"
+	CodeAPIKeyInvalid       = "AUTH_API_KEY_INVALID"
+	CodeAPIKeyExpired       = "AUTH_API_KEY_EXPIRED"
+	CodeAPIKeyRevoked       = "AUTH_API_KEY_REVOKED"
+	CodeOAuthInvalidClient  = "OAUTH_INVALID_CLIENT"
+	CodeOAuthInvalidScope   = "OAUTH_INVALID_SCOPE"
+	CodeOAuthUnsupported    = "OAUTH_UNSUPPORTED_GRANT_TYPE"
+	CodeOIDCStateInvalid    = "OIDC_STATE_INVALID"
+	CodeOIDCLoginFailed     = "OIDC_LOGIN_FAILED"
+	CodeOIDCNotProvisioned  = "OIDC_USER_NOT_PROVISIONED"
+	CodeOIDCAccountConflict = "OIDC_ACCOUNT_CONFLICT"
+	CodeOIDCUnavailable     = "OIDC_PROVIDER_UNAVAILABLE"
+	CodeOIDCMFARequired     = "OIDC_MFA_REQUIRED"
+	CodeAdminUnauthorized   = "ADMIN_UNAUTHORIZED"
+	CodeUserNotFound        = "USER_NOT_FOUND"
+	CodeConsumerNotFound    = "CONSUMER_NOT_FOUND"
+	CodeConsumerExists      = "CONSUMER_EXISTS"
+	CodeAPIKeyNotFound      = "API_KEY_NOT_FOUND"
+	CodeClientNotFound      = "CLIENT_NOT_FOUND"
+	CodeClientExists        = "CLIENT_EXISTS"
+	CodeLockoutNotFound     = "LOCKOUT_NOT_FOUND"
+	CodeUpstreamError       = "UPSTREAM_UNAVAILABLE"
+	CodeUpstreamTimeout     = "UPSTREAM_TIMEOUT"
+	CodeCircuitOpen         = "UPSTREAM_CIRCUIT_OPEN"
+	CodeNoHealthyUpstream   = "UPSTREAM_NO_HEALTHY_INSTANCE"
+)
+
+// Problem adalah body error RFC 7807 dengan tambahan kode error dan request ID.
+// Field tambahan (misal retry_after) disimpan di Extensions dan diserialisasi
+// sejajar dengan field standar.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// New membuat Problem dengan title dari teks status HTTP.
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   typePrefix + strings.ToLower(strings.ReplaceAll(code, "_", "-")),
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// With menambahkan field tambahan ke problem.
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	base, err := json.Marshal((*plain)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return base, err
+	}
+	fields := make(map[string]interface{}, len(p.Extensions)+7)
+	for k, v := range p.Extensions {
+		fields[k] = v
+	}
+	// Field standar tidak boleh ditimpa oleh extension
+	if err := json.Unmarshal(base, &fields); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// Abort menghentikan chain gin dan mengirim problem sebagai response.
+func Abort(c *gin.Context, p *Problem) {
+	p.RequestID = requestid.Get(c)
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// Write mengirim problem lewat http.ResponseWriter biasa, misal dari
+// ErrorHandler httputil.ReverseProxy.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	p.RequestID = requestid.FromContext(r.Context())
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
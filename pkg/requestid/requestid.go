@@ -0,0 +1,100 @@
+// pkg/requestid/requestid.go
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DefaultHeader adalah header request ID jika REQUEST_ID.HEADER tidak diisi.
+const DefaultHeader = "X-Request-ID"
+
+// ContextKey adalah kunci request ID di gin.Context (c.GetString(ContextKey)).
+const ContextKey = "request_id"
+
+// Format request ID yang bisa dibuat gateway untuk REQUEST_ID.FORMAT.
+const (
+	FormatUUIDv7 = "uuidv7" // Default; terurut menurut waktu
+	FormatUUIDv4 = "uuidv4"
+	FormatHex    = "hex" // 16 byte acak dalam heksadesimal
+)
+
+// maxLength adalah panjang maksimum request ID dari klien yang diterima.
+const maxLength = 128
+
+type contextKey struct{}
+
+// ValidFormat melaporkan apakah format dikenal.
+func ValidFormat(format string) bool {
+	switch format {
+	case FormatUUIDv7, FormatUUIDv4, FormatHex:
+		return true
+	}
+	return false
+}
+
+// Generate membuat request ID baru dengan format yang diminta. Format yang
+// tidak dikenal diperlakukan sebagai uuidv7.
+func Generate(format string) string {
+	switch format {
+	case FormatUUIDv4:
+		return uuid.NewString()
+	case FormatHex:
+		b := make([]byte, 16)
+		rand.Read(b)
+		return hex.EncodeToString(b)
+	}
+	id, err := uuid.NewV7()
+	if err != nil {
+		return uuid.NewString()
+	}
+	return id.String()
+}
+
+// Valid melaporkan apakah request ID dari klien aman untuk dipakai ulang:
+// tidak kosong, maksimal 128 karakter, dan hanya berisi karakter ASCII yang
+// terlihat selain tanda kutip dan backslash (aman untuk log dan header).
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		if ch <= ' ' || ch > '~' || ch == '"' || ch == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// WithID menyimpan request ID di context request.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext mengembalikan request ID dari context, atau "" jika tidak ada.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Get mengembalikan request ID dari gin.Context.
+func Get(c *gin.Context) string {
+	return c.GetString(ContextKey)
+}
+
+// Logf seperti log.Printf tetapi menambahkan request ID dari ctx di akhir baris
+// agar log gateway bisa dicocokkan dengan log upstream.
+func Logf(ctx context.Context, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if id := FromContext(ctx); id != "" {
+		msg += " request_id=" + id
+	}
+	log.Print(msg)
+}
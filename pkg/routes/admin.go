@@ -0,0 +1,183 @@
+// pkg/routes/admin.go
+package routes
+
+import (
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/database"
+	"api-gateway-go/pkg/handlers"
+	"api-gateway-go/pkg/middleware"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/requestid"
+	"api-gateway-go/pkg/upstream"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAdminRoutes mendaftarkan endpoint admin pada engine terpisah yang
+// listen di ADMIN.ADDR, sehingga tidak ikut terekspos di port publik.
+func SetupAdminRoutes(router *gin.Engine, gateway *Gateway, cfg config.AdminConfig) {
+	// Config.Validate menjamin cfg.Token terisi saat server admin aktif
+	admin := router.Group("/admin")
+	admin.Use(middleware.AdminTokenMiddleware(cfg.Token))
+
+	// Status generasi konfigurasi aktif dan hasil reload terakhir
+	admin.GET("/reload", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gateway.ReloadStatus())
+	})
+
+	// Status kesehatan setiap instance upstream di generasi aktif
+	admin.GET("/upstreams", func(c *gin.Context) {
+		statuses := make([]upstream.ServiceStatus, 0)
+		for _, svc := range gateway.Services() {
+			statuses = append(statuses, svc.Status())
+		}
+		c.JSON(http.StatusOK, gin.H{"services": statuses})
+	})
+
+	// Mencabut semua access dan refresh token pengguna, misal saat akun dicuri
+	admin.POST("/users/:username/revoke-tokens", func(c *gin.Context) {
+		deps := gateway.deps
+		user, err := deps.Users.FindByUsername(c.Request.Context(), c.Param("username"))
+		if errors.Is(err, database.ErrUserNotFound) {
+			problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeUserNotFound, "User not found"))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, err.Error()))
+			return
+		}
+		n, err := handlers.RevokeUserTokens(c.Request.Context(), deps.Revocations, deps.RefreshTokens, user.ID, gateway.Config().Auth.AccessTokenTTL)
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, err.Error()))
+			return
+		}
+		requestid.Logf(c.Request.Context(), "[AUTH] Admin mencabut semua token pengguna %s (UserID %s)", user.Username, user.ID)
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":                user.ID,
+			"username":               user.Username,
+			"refresh_tokens_revoked": n,
+		})
+	})
+
+	// Menonaktifkan pengguna sekaligus mencabut semua tokennya, sehingga access
+	// token yang masih berlaku langsung ditolak
+	admin.POST("/users/:username/disable", func(c *gin.Context) {
+		deps := gateway.deps
+		user, err := deps.Users.FindByUsername(c.Request.Context(), c.Param("username"))
+		if errors.Is(err, database.ErrUserNotFound) {
+			problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeUserNotFound, "User not found"))
+			return
+		}
+		if err == nil {
+			err = deps.Users.SetDisabled(c.Request.Context(), user.Username, true)
+		}
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, err.Error()))
+			return
+		}
+		n, err := handlers.RevokeUserTokens(c.Request.Context(), deps.Revocations, deps.RefreshTokens, user.ID, gateway.Config().Auth.AccessTokenTTL)
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, err.Error()))
+			return
+		}
+		requestid.Logf(c.Request.Context(), "[AUDIT] event=user_disabled user=%q by=admin refresh_tokens_revoked=%d", user.Username, n)
+		c.JSON(http.StatusOK, gin.H{
+			"user_id":                user.ID,
+			"username":               user.Username,
+			"disabled":               true,
+			"refresh_tokens_revoked": n,
+		})
+	})
+
+	admin.POST("/users/:username/enable", func(c *gin.Context) {
+		err := gateway.deps.Users.SetDisabled(c.Request.Context(), c.Param("username"), false)
+		if errors.Is(err, database.ErrUserNotFound) {
+			problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeUserNotFound, "User not found"))
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, err.Error()))
+			return
+		}
+		requestid.Logf(c.Request.Context(), "[AUDIT] event=user_enabled user=%q by=admin", c.Param("username"))
+		c.Status(http.StatusNoContent)
+	})
+
+	// Menonaktifkan MFA pengguna yang kehilangan authenticator dan recovery code
+	admin.POST("/users/:username/reset-mfa", func(c *gin.Context) {
+		users := gateway.deps.Users
+		user, err := users.FindByUsername(c.Request.Context(), c.Param("username"))
+		if errors.Is(err, database.ErrUserNotFound) {
+			problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeUserNotFound, "User not found"))
+			return
+		}
+		if err == nil {
+			err = users.DisableMFA(c.Request.Context(), user.ID)
+		}
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusInternalServerError, problem.CodeInternalError, err.Error()))
+			return
+		}
+		requestid.Logf(c.Request.Context(), "[AUDIT] event=mfa_disabled user=%q by=admin", user.Username)
+		c.Status(http.StatusNoContent)
+	})
+
+	// Username dan IP yang sedang dikunci karena terlalu banyak login gagal
+	admin.GET("/lockouts", func(c *gin.Context) {
+		lockouts := []auth.Lockout{}
+		if guard := gateway.deps.LoginGuard; guard != nil {
+			lockouts = guard.Lockouts()
+		}
+		c.JSON(http.StatusOK, gin.H{"lockouts": lockouts})
+	})
+
+	// Membuka kunci dan mereset hitungan kegagalan satu username atau IP
+	admin.DELETE("/lockouts/:kind/:subject", func(c *gin.Context) {
+		kind, subject := c.Param("kind"), c.Param("subject")
+		if kind != auth.LoginSubjectUser && kind != auth.LoginSubjectIP {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidRequest, "kind must be user or ip"))
+			return
+		}
+		guard := gateway.deps.LoginGuard
+		if guard == nil || !guard.Unlock(kind, subject) {
+			problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeLockoutNotFound, "No failed login attempts recorded for this subject"))
+			return
+		}
+		requestid.Logf(c.Request.Context(), "[AUDIT] event=login_unlock kind=%s subject=%q by=admin", kind, subject)
+		c.Status(http.StatusNoContent)
+	})
+
+	// Consumer dan API key untuk klien mesin
+	apiKeys := handlers.NewAPIKeyAdminHandler(gateway.deps.APIKeys)
+	admin.GET("/consumers", apiKeys.ListConsumers)
+	admin.POST("/consumers", apiKeys.CreateConsumer)
+	admin.PUT("/consumers/:name", apiKeys.UpdateConsumer)
+	admin.DELETE("/consumers/:name", apiKeys.DeleteConsumer)
+	admin.GET("/consumers/:name/keys", apiKeys.ListKeys)
+	admin.POST("/consumers/:name/keys", apiKeys.CreateKey)
+	admin.POST("/api-keys/:prefix/revoke", apiKeys.RevokeKey)
+	admin.POST("/api-keys/:prefix/expire", apiKeys.ExpireKey)
+
+	// OAuth client untuk grant client_credentials
+	clients := handlers.NewOAuthClientAdminHandler(gateway.deps.OAuthClients, gateway.deps.Revocations, func() config.ClientCredentialsConfig {
+		return gateway.Config().Auth.ClientCredentials
+	})
+	admin.GET("/clients", clients.List)
+	admin.POST("/clients", clients.Create)
+	admin.PUT("/clients/:client_id", clients.Update)
+	admin.DELETE("/clients/:client_id", clients.Delete)
+	admin.POST("/clients/:client_id/secret", clients.RotateSecret)
+	admin.POST("/clients/:client_id/revoke-tokens", clients.RevokeTokens)
+
+	// Memicu reload manual, setara dengan mengirim SIGHUP
+	admin.POST("/reload", func(c *gin.Context) {
+		if err := gateway.Reload("admin-api"); err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gateway.ReloadStatus())
+			return
+		}
+		c.JSON(http.StatusOK, gateway.ReloadStatus())
+	})
+}
@@ -0,0 +1,241 @@
+// pkg/routes/gateway.go
+package routes
+
+import (
+	"api-gateway-go/pkg/auth"
+	"api-gateway-go/pkg/config"
+	"api-gateway-go/pkg/middleware"
+	"api-gateway-go/pkg/requestid"
+	"api-gateway-go/pkg/upstream"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Loader membaca dan memvalidasi konfigurasi terbaru, misalnya config.LoadConfig.
+type Loader func() (config.Config, error)
+
+// generation adalah satu set rute, proxy, dan middleware yang dibangun dari
+// satu versi konfigurasi. Generasi tidak pernah diubah setelah dibuat.
+type generation struct {
+	id       int64
+	cfg      config.Config
+	engine   *gin.Engine
+	services map[string]*upstream.Service
+	pool     *upstream.TransportPool
+	verifier *auth.Verifier
+	loadedAt time.Time
+
+	stopHealthChecks []func()
+	stopVerifier     func()
+}
+
+// close menghentikan pekerjaan background generasi (health check dan refresh
+// JWKS) dan menutup
+// koneksi upstream yang idle. Request yang masih berjalan di generasi ini tidak
+// terpengaruh.
+func (gen *generation) close() {
+	for _, stop := range gen.stopHealthChecks {
+		stop()
+	}
+	gen.stopVerifier()
+	gen.pool.CloseIdleConnections()
+}
+
+// ReloadStatus merangkum generasi aktif dan hasil reload terakhir.
+type ReloadStatus struct {
+	Generation      int64      `json:"generation"`
+	LoadedAt        time.Time  `json:"loaded_at"`
+	LastAttemptAt   *time.Time `json:"last_attempt_at,omitempty"`
+	LastTrigger     string     `json:"last_trigger,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	LastSucceeded   bool       `json:"last_succeeded"`
+	TotalReloads    int        `json:"total_reloads"`
+	FailedReloads   int        `json:"failed_reloads"`
+	RestartRequired []string   `json:"restart_required,omitempty"`
+}
+
+// Gateway adalah http.Handler yang meneruskan setiap request ke generasi aktif.
+// Reload membangun generasi baru lalu menukarnya secara atomik; request yang
+// sedang berjalan tetap diselesaikan oleh generasi lama.
+type Gateway struct {
+	load    Loader
+	deps    Dependencies
+	started config.Config // Konfigurasi saat proses dimulai
+	current atomic.Pointer[generation]
+
+	mu     sync.Mutex // Serialisasi reload dan melindungi status
+	status ReloadStatus
+}
+
+// NewGateway membangun generasi pertama dari cfg. load dipakai oleh Reload
+// untuk membaca konfigurasi berikutnya; deps dipakai bersama oleh semua generasi.
+func NewGateway(cfg config.Config, load Loader, deps Dependencies) (*Gateway, error) {
+	gen, err := buildGeneration(1, cfg, nil, deps)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &Gateway{load: load, deps: deps, started: cfg}
+	g.current.Store(gen)
+	g.status = ReloadStatus{Generation: gen.id, LoadedAt: gen.loadedAt, LastSucceeded: true}
+	return g, nil
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.current.Load().engine.ServeHTTP(w, r)
+}
+
+// Config mengembalikan konfigurasi generasi aktif.
+func (g *Gateway) Config() config.Config {
+	return g.current.Load().cfg
+}
+
+// ReloadStatus mengembalikan salinan status reload terbaru.
+func (g *Gateway) ReloadStatus() ReloadStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.status
+}
+
+// Reload membaca ulang konfigurasi lalu menukar generasi aktif. Jika konfigurasi
+// baru gagal dimuat, divalidasi, atau dibangun, generasi lama tetap dipakai dan
+// alasannya dicatat di log serta di ReloadStatus.
+func (g *Gateway) Reload(trigger string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	g.status.LastAttemptAt = &now
+	g.status.LastTrigger = trigger
+	g.status.TotalReloads++
+
+	old := g.current.Load()
+	cfg, err := g.load()
+	var gen *generation
+	if err == nil {
+		gen, err = buildGeneration(old.id+1, cfg, old, g.deps)
+	}
+	if err != nil {
+		g.status.LastSucceeded = false
+		g.status.LastError = err.Error()
+		g.status.FailedReloads++
+		log.Printf("Reload konfigurasi (%s) gagal, generasi %d tetap aktif: %v", trigger, old.id, err)
+		return err
+	}
+
+	g.current.Store(gen)
+	go old.close()
+	g.status.Generation = gen.id
+	g.status.LoadedAt = gen.loadedAt
+	g.status.LastSucceeded = true
+	g.status.LastError = ""
+	g.status.RestartRequired = restartRequired(g.started, cfg)
+	log.Printf("Reload konfigurasi (%s) berhasil, generasi %d aktif", trigger, gen.id)
+	for _, key := range g.status.RestartRequired {
+		log.Printf("Peringatan: perubahan %s baru berlaku setelah restart", key)
+	}
+	return nil
+}
+
+// Services mengembalikan service upstream generasi aktif, diurutkan menurut nama.
+func (g *Gateway) Services() []*upstream.Service {
+	gen := g.current.Load()
+	services := make([]*upstream.Service, 0, len(gen.services))
+	for _, svc := range gen.services {
+		services = append(services, svc)
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services
+}
+
+func buildGeneration(id int64, cfg config.Config, prev *generation, deps Dependencies) (*generation, error) {
+	var prevServices map[string]*upstream.Service
+	var prevVerifier *auth.Verifier
+	if prev != nil {
+		prevServices = prev.services
+		prevVerifier = prev.verifier
+	}
+	services, err := BuildServices(cfg, prevServices)
+	if err != nil {
+		return nil, err
+	}
+	// Kunci JWT dimuat ulang setiap reload agar rotasi kunci tidak butuh restart
+	verifier, err := auth.NewVerifier(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("gagal memuat kunci AUTH.SIGNING: %w", err)
+	}
+	verifier.InheritState(prevVerifier)
+
+	pool := upstream.NewTransportPool(cfg.Transport)
+	engine := gin.New()
+	// c.ClientIP() (dipakai rate limiter) hanya membaca X-Forwarded-For dan
+	// X-Real-IP dari proxy di TRUSTED_PROXIES; kosong berarti tidak ada.
+	if err := engine.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+	engine.Use(gin.LoggerWithFormatter(ginLogFormatter(cfg.Auth.APIKeys.QueryParam)), gin.CustomRecovery(middleware.RecoveryHandler))
+	if err := SetupRoutes(engine, cfg, services, pool, verifier, deps); err != nil {
+		return nil, err
+	}
+
+	gen := &generation{id: id, cfg: cfg, engine: engine, services: services, pool: pool, verifier: verifier, loadedAt: time.Now()}
+	for _, svc := range services {
+		gen.stopHealthChecks = append(gen.stopHealthChecks, svc.StartHealthChecks())
+	}
+	gen.stopVerifier = verifier.Start()
+	return gen, nil
+}
+
+// ginLogFormatter sama dengan format bawaan gin.Logger ditambah request ID.
+// Nilai query parameter API key (redactParam) disamarkan karena gin mencatat
+// path sebelum APIKeyMiddleware sempat menghapusnya.
+func ginLogFormatter(redactParam string) gin.LogFormatter {
+	return func(param gin.LogFormatterParams) string {
+		id, _ := param.Keys[requestid.ContextKey].(string)
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v request_id=%s\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			middleware.RedactQuery(param.Path, redactParam),
+			id,
+			param.ErrorMessage,
+		)
+	}
+}
+
+// restartRequired mendaftar kunci konfigurasi yang berbeda dari saat proses
+// dimulai tetapi tidak bisa diterapkan tanpa restart.
+func restartRequired(started, cfg config.Config) []string {
+	var keys []string
+	if started.ServerPort != cfg.ServerPort {
+		keys = append(keys, "SERVER_PORT")
+	}
+	if started.AppEnv != cfg.AppEnv {
+		keys = append(keys, "APP_ENV")
+	}
+	if started.Server != cfg.Server {
+		keys = append(keys, "SERVER")
+	}
+	if started.Database != cfg.Database {
+		keys = append(keys, "DATABASE")
+	}
+	if started.Auth.Revocation != cfg.Auth.Revocation {
+		keys = append(keys, "AUTH.REVOCATION")
+	}
+	if started.Auth.LoginProtection != cfg.Auth.LoginProtection {
+		keys = append(keys, "AUTH.LOGIN_PROTECTION")
+	}
+	if started.Admin != cfg.Admin {
+		keys = append(keys, "ADMIN")
+	}
+	return keys
+}
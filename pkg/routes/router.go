@@ -2,28 +2,61 @@
 package routes
 
 import (
+	"api-gateway-go/pkg/auth"
 	"api-gateway-go/pkg/config" // Sesuaikan dengan nama modul Anda
+	"api-gateway-go/pkg/database"
 	"api-gateway-go/pkg/handlers"
 	"api-gateway-go/pkg/middleware"
+	"api-gateway-go/pkg/problem"
+	"api-gateway-go/pkg/upstream"
+	"fmt"
 	"log"
 	"net/http"
-	"net/url"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
-func SetupRoutes(router *gin.Engine, cfg config.Config) {
-	// Middleware Global
-	router.Use(middleware.LoggingMiddleware())
+// Dependencies berisi komponen yang hidup sepanjang proses dan dipakai bersama
+// oleh semua generasi konfigurasi, misalnya koneksi database.
+type Dependencies struct {
+	Users         *database.UserRepository
+	RefreshTokens *database.RefreshTokenRepository
+	Revocations   *auth.RevocationStore
+	APIKeys       *database.APIKeyRepository
+	OAuthClients  *database.OAuthClientRepository
+	LoginGuard    *auth.LoginGuard // nil jika AUTH.LOGIN_PROTECTION dimatikan
+	Passwords     *auth.PasswordLimiter
+}
+
+// SetupRoutes mendaftarkan middleware global, rute bawaan, dan semua rute proxy.
+// cfg diasumsikan sudah lolos config.Validate dan services berisi setiap
+// upstream yang dirujuk oleh cfg.Routes (lihat BuildServices). pool menyediakan
+// http.Transport ke upstream untuk setiap rute, dan verifier memverifikasi
+// token JWT (lihat auth.NewVerifier).
+func SetupRoutes(router *gin.Engine, cfg config.Config, services map[string]*upstream.Service, pool *upstream.TransportPool, verifier *auth.Verifier, deps Dependencies) error {
+	// Middleware Global. Request ID dipasang pertama agar tersedia di semua log.
+	router.Use(middleware.RequestIDMiddleware(cfg.RequestID))
+	router.Use(middleware.LoggingMiddleware(cfg.Auth.APIKeys.QueryParam))
 
 	// CORS Configuration
 	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowAllOrigins = true // HATI-HATI: Untuk produksi, batasi origin
-	// corsConfig.AllowOrigins = []string{"http://localhost:3000", "https://yourfrontend.com"}
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		// Cookie sesi hanya boleh dikirim lintas origin ke frontend yang dikenal
+		corsConfig.AllowOrigins = cfg.CORS.AllowedOrigins
+		corsConfig.AllowCredentials = true
+	} else {
+		corsConfig.AllowAllOrigins = true // HATI-HATI: Untuk produksi, batasi origin dengan CORS.ALLOWED_ORIGINS
+	}
 	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	if cfg.Auth.APIKeys.Header != "" {
+		corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, cfg.Auth.APIKeys.Header)
+	}
+	if cfg.Auth.Session.Enabled {
+		corsConfig.AllowHeaders = append(corsConfig.AllowHeaders, cfg.Auth.Session.CSRFHeader)
+	}
 	router.Use(cors.New(corsConfig))
 
 	// Rate Limiting Per IP (Contoh: 5 request per detik, dengan burst 10)
@@ -45,81 +78,171 @@ func SetupRoutes(router *gin.Engine, cfg config.Config) {
 		public.GET("/health", handlers.HealthCheck)
 	}
 
+	authMiddleware := middleware.AuthMiddleware(verifier, deps.Revocations, cfg.Auth.Session)
+	apiKeyMiddleware := middleware.APIKeyMiddleware(cfg.Auth.APIKeys, deps.APIKeys)
+	router.GET("/.well-known/jwks.json", handlers.JWKSHandler(verifier.Keys()))
+
 	// Authentication Route
 	authRoutes := router.Group("/auth")
 	{
-		authRoutes.POST("/login", handlers.LoginHandler(cfg)) // Mengirim config ke handler jika diperlukan
+		authHandler := handlers.NewAuthHandler(cfg, verifier.Keys(), deps.Users, deps.RefreshTokens, deps.Revocations, deps.LoginGuard, deps.Passwords)
+		authRoutes.POST("/login", authHandler.Login)
+		authRoutes.POST("/refresh", authHandler.Refresh)
+		authRoutes.POST("/logout", authMiddleware, authHandler.Logout)
+		authRoutes.GET("/me", authMiddleware, handlers.Me)
+
+		// Introspeksi token (RFC 7662) hanya untuk consumer API key atau OAuth
+		// client. Token dibaca dari header saja (tanpa cookie sesi) dan token
+		// pengguna ditolak meskipun punya role yang disyaratkan.
+		if cfg.Auth.Introspection.Enabled {
+			introspection := handlers.NewIntrospectionHandler(verifier, deps.Revocations, deps.Users, deps.RefreshTokens)
+			bearerOnly := middleware.AuthMiddleware(verifier, deps.Revocations, config.SessionConfig{})
+			chain := []gin.HandlerFunc{
+				middleware.EitherAuthMiddleware(cfg.Auth.APIKeys, bearerOnly, apiKeyMiddleware),
+				middleware.MachineOnlyMiddleware(),
+			}
+			if roles := cfg.Auth.Introspection.Roles; len(roles) > 0 {
+				chain = append(chain, middleware.AuthorizeMiddleware([]config.AuthzRuleConfig{{Roles: roles}}))
+			}
+			authRoutes.POST("/introspect", append(chain, introspection.Introspect)...)
+		}
+
+		// Login lewat identity provider perusahaan (authorization code + PKCE)
+		if cfg.Auth.OIDC.Enabled {
+			oidcHandler := handlers.NewOIDCHandler(cfg, authHandler, auth.NewOIDCProvider(cfg.Auth.OIDC))
+			authRoutes.GET("/oidc/login", oidcHandler.Login)
+			authRoutes.GET("/oidc/callback", oidcHandler.Callback)
+		}
+
+		// TOTP multi-factor authentication
+		enrollAuth := middleware.MFAEnrollmentMiddleware(verifier.Keys(), deps.Revocations, authMiddleware)
+		authRoutes.POST("/mfa/verify", authHandler.VerifyMFA)
+		authRoutes.GET("/mfa", authMiddleware, authHandler.MFAStatus)
+		authRoutes.POST("/mfa/enroll", enrollAuth, authHandler.EnrollMFA)
+		authRoutes.POST("/mfa/confirm", enrollAuth, authHandler.ConfirmMFA)
+		authRoutes.POST("/mfa/disable", authMiddleware, authHandler.DisableMFA)
+		authRoutes.POST("/mfa/recovery-codes", authMiddleware, authHandler.RegenerateRecoveryCodes)
 	}
 
-	// API v1 Group
-	apiV1 := router.Group("/api/v1")
-	{
-		// Service Pengguna
-		userServiceTarget, ok := cfg.ServiceEndpoints["user_service"]
-		if !ok || userServiceTarget == "" {
-			log.Fatalf("URL untuk user_service tidak ditemukan di konfigurasi")
+	// Grant client_credentials untuk panggilan antar service
+	if cfg.Auth.ClientCredentials.Enabled {
+		oauthHandler := handlers.NewOAuthHandler(cfg.Auth.ClientCredentials, verifier.Keys(), deps.OAuthClients)
+		router.POST("/oauth/token", oauthHandler.Token)
+	}
+
+	// Rute proxy dibangun dari tabel ROUTES di konfigurasi
+	if err := setupProxyRoutes(router, cfg, services, pool, verifier.Keys(), authMiddleware, apiKeyMiddleware); err != nil {
+		return err
+	}
+
+	// Fallback untuk rute yang tidak ditemukan
+	router.NoRoute(func(c *gin.Context) {
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeRouteNotFound, "No route matches this path."))
+	})
+
+	return nil
+}
+
+// BuildServices membuat satu upstream.Service untuk setiap upstream yang dirujuk
+// oleh cfg.Routes. Service dipakai bersama oleh semua rute yang mengarah ke
+// service tersebut agar hitungan koneksi dan giliran load balancing konsisten.
+// Status kesehatan dan circuit breaker diwarisi dari prev (generasi sebelumnya).
+func BuildServices(cfg config.Config, prev map[string]*upstream.Service) (map[string]*upstream.Service, error) {
+	services := make(map[string]*upstream.Service)
+	for _, route := range cfg.Routes {
+		if _, ok := services[route.Upstream]; ok {
+			continue
+		}
+		svcCfg, ok := cfg.Service(route.Upstream)
+		if !ok {
+			return nil, fmt.Errorf("service %s tidak ditemukan", route.Upstream)
 		}
-		userServiceURL, err := url.Parse(userServiceTarget)
+		service, err := upstream.NewService(route.Upstream, svcCfg)
 		if err != nil {
-			log.Fatalf("URL user_service tidak valid: %v", err)
+			return nil, err
 		}
-		userProxy := handlers.NewProxyHandler(userServiceURL)
-
-		userRoutes := apiV1.Group("/users")
-		userRoutes.Use(middleware.AuthMiddleware(cfg.AuthSecret)) // Semua endpoint user butuh auth
-		{
-			// Path /*proxyPath akan menangkap semua sub-path
-			// Contoh: /api/v1/users/profile -> proxyPath = /profile
-			// Contoh: /api/v1/users/123/orders -> proxyPath = /123/orders
-			userRoutes.Any("/*proxyPath", userProxy.Handle)
+		service.InheritState(prev[route.Upstream])
+		services[route.Upstream] = service
+	}
+	return services, nil
+}
+
+// setupProxyRoutes membuat satu grup proxy untuk setiap entri cfg.Routes.
+func setupProxyRoutes(router *gin.Engine, cfg config.Config, services map[string]*upstream.Service, pool *upstream.TransportPool, keys *auth.KeySet, authMiddleware, apiKeyMiddleware gin.HandlerFunc) error {
+	exposeUpstream := cfg.AppEnv == "development"
+	trustedProxies := handlers.NewTrustedProxies(cfg.TrustedProxies)
+	retryBudget := upstream.NewRetryBudget(cfg.RetryBudget)
+	identityHeaders := cfg.IdentityHeaderNames()
+
+	for _, route := range cfg.Routes {
+		service, ok := services[route.Upstream]
+		if !ok {
+			return fmt.Errorf("service %s untuk rute %s belum dibuat", route.Upstream, route.PathPrefix)
 		}
 
-		// Service Produk
-		productServiceTarget, ok := cfg.ServiceEndpoints["product_service"]
-		if !ok || productServiceTarget == "" {
-			log.Fatalf("URL untuk product_service tidak ditemukan di konfigurasi")
+		timeouts := route.Timeouts.WithDefaults(cfg.Transport)
+		opts := handlers.ProxyOptions{
+			ExposeUpstream:  exposeUpstream,
+			RequestIDHeader: cfg.RequestID.Header,
+			TrustedProxies:  trustedProxies,
+			Transport:       pool.Get(timeouts),
+			Timeout:         timeouts.Total,
+			Retry:           upstream.NewRetryPolicy(route.Retry),
+			RetryBudget:     retryBudget,
+			Identity:        handlers.NewIdentityHeaders(cfg.IdentityFor(route), identityHeaders, keys, route.Upstream),
+			StripCookies:    cfg.Auth.Session.CookieNames(),
 		}
-		productServiceURL, err := url.Parse(productServiceTarget)
-		if err != nil {
-			log.Fatalf("URL product_service tidak valid: %v", err)
+		if route.StripPrefix {
+			opts.StripPrefix = route.PathPrefix
 		}
-		productProxy := handlers.NewProxyHandler(productServiceURL)
-
-		productRoutes := apiV1.Group("/products")
-		{
-			// GET produk bisa publik
-			productRoutes.GET("/*proxyPath", productProxy.Handle)
-
-			// POST, PUT, DELETE produk butuh auth
-			productProtected := productRoutes.Group("") // Grup kosong untuk menerapkan middleware tambahan
-			productProtected.Use(middleware.AuthMiddleware(cfg.AuthSecret))
-			{
-				productProtected.POST("/*proxyPath", productProxy.Handle)
-				productProtected.PUT("/*proxyPath", productProxy.Handle)
-				productProtected.DELETE("/*proxyPath", productProxy.Handle)
-			}
+		proxy := handlers.NewProxyHandler(service, opts)
+		authn := routeAuthMiddleware(route, cfg.Auth.APIKeys, authMiddleware, apiKeyMiddleware)
+
+		// Path /*proxyPath akan menangkap semua sub-path
+		// Contoh: /api/v1/users/123/orders -> proxyPath = /123/orders
+		// Satu middleware ext_authz per rute agar cache keputusan dipakai
+		// bersama oleh semua method
+		var extAuthz gin.HandlerFunc
+		if route.ExtAuthz != nil {
+			extAuthz = middleware.ExtAuthzMiddleware(route.PathPrefix, *route.ExtAuthz, identityHeaders)
 		}
 
-		// Tambahkan service lain di sini jika ada
-		// Contoh: Order Service (semua butuh auth)
-		orderServiceTarget, ok := cfg.ServiceEndpoints["order_service"]
-		if ok && orderServiceTarget != "" { // Hanya jika dikonfigurasi
-			orderServiceURL, err := url.Parse(orderServiceTarget)
-			if err != nil {
-				log.Printf("Peringatan: URL order_service tidak valid: %v", err)
-			} else {
-				orderProxy := handlers.NewProxyHandler(orderServiceURL)
-				orderRoutes := apiV1.Group("/orders")
-				orderRoutes.Use(middleware.AuthMiddleware(cfg.AuthSecret))
-				{
-					orderRoutes.Any("/*proxyPath", orderProxy.Handle)
+		group := router.Group(route.PathPrefix)
+		for _, method := range route.AllowedMethods() {
+			var chain []gin.HandlerFunc
+			if route.RequiresAuth(method) {
+				chain = append(chain, authn)
+				if rules := route.AuthorizationFor(method); len(rules) > 0 {
+					chain = append(chain, middleware.AuthorizeMiddleware(rules))
 				}
 			}
+			if extAuthz != nil && route.ExtAuthz.AppliesTo(method) {
+				chain = append(chain, extAuthz)
+			}
+			group.Handle(method, "/*proxyPath", append(chain, proxy.Handle)...)
 		}
+		log.Printf("Rute %s -> %s (%d instance), auth: %v %v, ext_authz: %v", route.PathPrefix, route.Upstream, len(service.Targets), route.AuthMethods, route.AcceptedAuthSchemes(), route.ExtAuthz != nil)
 	}
+	return nil
+}
 
-	// Fallback untuk rute yang tidak ditemukan
-	router.NoRoute(func(c *gin.Context) {
-		c.JSON(http.StatusNotFound, gin.H{"code": "ROUTE_NOT_FOUND", "message": "Endpoint tidak ditemukan."})
-	})
+// routeAuthMiddleware memilih middleware autentikasi sesuai AUTH_SCHEMES rute.
+func routeAuthMiddleware(route config.RouteConfig, apiKeys config.APIKeyConfig, jwtAuth, apiKeyAuth gin.HandlerFunc) gin.HandlerFunc {
+	var acceptJWT, acceptAPIKey bool
+	for _, scheme := range route.AcceptedAuthSchemes() {
+		switch scheme {
+		case config.AuthSchemeJWT:
+			acceptJWT = true
+		case config.AuthSchemeAPIKey:
+			acceptAPIKey = true
+		}
+	}
+	switch {
+	case acceptJWT && acceptAPIKey:
+		return middleware.EitherAuthMiddleware(apiKeys, jwtAuth, apiKeyAuth)
+	case acceptAPIKey:
+		return apiKeyAuth
+	default:
+		return jwtAuth
+	}
 }
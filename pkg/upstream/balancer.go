@@ -0,0 +1,163 @@
+// pkg/upstream/balancer.go
+package upstream
+
+import (
+	"api-gateway-go/pkg/config"
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// balancer memilih satu instance dari candidates. candidates selalu berisi
+// minimal satu elemen dan merupakan subset dari Service.Targets.
+type balancer interface {
+	next(candidates []*Target, key string) *Target
+}
+
+func newBalancer(strategy string, targets []*Target) balancer {
+	switch strategy {
+	case config.StrategyWeighted:
+		return &weightedBalancer{current: make(map[*Target]int)}
+	case config.StrategyLeastConnections:
+		return &leastConnBalancer{}
+	case config.StrategyPowerOfTwo:
+		return p2cBalancer{}
+	case config.StrategyConsistentHash:
+		return newHashRing(targets)
+	default:
+		return &roundRobinBalancer{}
+	}
+}
+
+// roundRobinBalancer memilih instance secara bergiliran.
+type roundRobinBalancer struct {
+	counter uint64
+}
+
+func (b *roundRobinBalancer) next(candidates []*Target, _ string) *Target {
+	n := atomic.AddUint64(&b.counter, 1) - 1
+	return candidates[n%uint64(len(candidates))]
+}
+
+// weightedBalancer memakai smooth weighted round-robin (seperti nginx) sehingga
+// instance berbobot besar tidak mendapat request secara beruntun.
+type weightedBalancer struct {
+	mu      sync.Mutex
+	current map[*Target]int
+}
+
+func (b *weightedBalancer) next(candidates []*Target, _ string) *Target {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var best *Target
+	total := 0
+	for _, t := range candidates {
+		b.current[t] += t.Weight
+		total += t.Weight
+		if best == nil || b.current[t] > b.current[best] {
+			best = t
+		}
+	}
+	b.current[best] -= total
+	return best
+}
+
+// leastConnBalancer memilih instance dengan request aktif paling sedikit
+// relatif terhadap bobotnya. Titik mulai digilir agar seri tidak selalu jatuh
+// ke instance pertama.
+type leastConnBalancer struct {
+	counter uint64
+}
+
+func (b *leastConnBalancer) next(candidates []*Target, _ string) *Target {
+	start := int(atomic.AddUint64(&b.counter, 1) % uint64(len(candidates)))
+	var best *Target
+	for i := range candidates {
+		t := candidates[(start+i)%len(candidates)]
+		if best == nil || t.Active()*int64(best.Weight) < best.Active()*int64(t.Weight) {
+			best = t
+		}
+	}
+	return best
+}
+
+// p2cBalancer memilih dua instance secara acak lalu mengambil yang lebih sepi.
+type p2cBalancer struct{}
+
+func (p2cBalancer) next(candidates []*Target, _ string) *Target {
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	i := rand.IntN(len(candidates))
+	j := rand.IntN(len(candidates) - 1)
+	if j >= i {
+		j++
+	}
+	a, b := candidates[i], candidates[j]
+	if b.Active()*int64(a.Weight) < a.Active()*int64(b.Weight) {
+		return b
+	}
+	return a
+}
+
+// hashRing memetakan kunci (header atau IP klien) ke instance dengan consistent
+// hashing, sehingga kunci yang sama selalu ke instance yang sama selama instance
+// tersebut tersedia.
+type hashRing struct {
+	points []uint64
+	owners map[uint64]*Target
+}
+
+// virtualNodes adalah jumlah titik per satuan bobot di ring.
+const virtualNodes = 100
+
+func newHashRing(targets []*Target) *hashRing {
+	ring := &hashRing{owners: make(map[uint64]*Target)}
+	for _, t := range targets {
+		for i := 0; i < virtualNodes*t.Weight; i++ {
+			h := hashKey(t.URL.String() + "#" + strconv.Itoa(i))
+			if _, taken := ring.owners[h]; taken {
+				continue
+			}
+			ring.owners[h] = t
+			ring.points = append(ring.points, h)
+		}
+	}
+	sort.Slice(ring.points, func(i, j int) bool { return ring.points[i] < ring.points[j] })
+	return ring
+}
+
+func (r *hashRing) next(candidates []*Target, key string) *Target {
+	allowed := make(map[*Target]bool, len(candidates))
+	for _, t := range candidates {
+		allowed[t] = true
+	}
+
+	h := hashKey(key)
+	start := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	for i := 0; i < len(r.points); i++ {
+		t := r.owners[r.points[(start+i)%len(r.points)]]
+		if allowed[t] {
+			return t
+		}
+	}
+	return candidates[0]
+}
+
+// hashKey memakai FNV-1a lalu finalizer splitmix64, karena FNV saja
+// menghasilkan nilai yang berdekatan untuk kunci pendek yang mirip.
+func hashKey(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,196 @@
+// pkg/upstream/breaker.go
+package upstream
+
+import (
+	"api-gateway-go/pkg/config"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// BreakerState adalah state circuit breaker.
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// Result adalah hasil satu request yang dilaporkan ke circuit breaker.
+type Result int
+
+const (
+	ResultSuccess Result = iota
+	ResultFailure
+	ResultIgnored // Misal klien membatalkan request; tidak dihitung
+)
+
+// CircuitOpenError dikembalikan saat circuit terbuka dan request ditolak
+// tanpa menghubungi upstream.
+type CircuitOpenError struct {
+	Name       string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker %s is open", e.Name)
+}
+
+// Breaker adalah circuit breaker closed/open/half-open untuk satu service atau
+// satu instance.
+type Breaker struct {
+	name string
+	cfg  config.CircuitBreakerConfig
+
+	mu                  sync.Mutex
+	state               BreakerState
+	consecutiveFailures int
+	openedAt            time.Time
+	halfOpenInFlight    int
+	halfOpenSuccesses   int
+	window              rollingWindow // Indeks 0 = sukses, 1 = gagal
+}
+
+// NewBreaker membuat circuit breaker; cfg.Enabled harus true.
+func NewBreaker(name string, cfg config.CircuitBreakerConfig) *Breaker {
+	cfg = cfg.WithDefaults()
+	return &Breaker{name: name, cfg: cfg, window: newRollingWindow(cfg.Window)}
+}
+
+// State mengembalikan state saat ini. Circuit terbuka yang OPEN_TIMEOUT-nya
+// sudah lewat dilaporkan sebagai half-open.
+func (b *Breaker) State() BreakerState {
+	if b == nil {
+		return StateClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && time.Since(b.openedAt) >= b.cfg.OpenTimeout {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// retryAfter mengembalikan sisa waktu sampai circuit terbuka boleh dicoba lagi.
+func (b *Breaker) retryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != StateOpen {
+		return 0
+	}
+	return b.cfg.OpenTimeout - time.Since(b.openedAt)
+}
+
+// Allow memeriksa apakah request boleh diteruskan. Jika boleh, pemanggil wajib
+// memanggil done tepat satu kali dengan hasil request. Breaker nil selalu
+// mengizinkan request.
+func (b *Breaker) Allow() (done func(Result), err error) {
+	if b == nil {
+		return func(Result) {}, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	if b.state == StateOpen {
+		if wait := b.cfg.OpenTimeout - now.Sub(b.openedAt); wait > 0 {
+			return nil, &CircuitOpenError{Name: b.name, RetryAfter: wait}
+		}
+		b.setState(StateHalfOpen, "open timeout elapsed")
+	}
+	if b.state == StateHalfOpen {
+		if b.halfOpenInFlight >= b.cfg.HalfOpenRequests {
+			return nil, &CircuitOpenError{Name: b.name, RetryAfter: time.Second}
+		}
+		b.halfOpenInFlight++
+	}
+
+	halfOpen := b.state == StateHalfOpen
+	var once sync.Once
+	return func(r Result) {
+		once.Do(func() { b.record(r, halfOpen) })
+	}, nil
+}
+
+func (b *Breaker) record(r Result, halfOpen bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if halfOpen && b.state == StateHalfOpen && b.halfOpenInFlight > 0 {
+		b.halfOpenInFlight--
+	}
+	if r == ResultIgnored {
+		return
+	}
+
+	now := time.Now()
+	if r == ResultSuccess {
+		b.window.add(now, 0)
+		b.consecutiveFailures = 0
+	} else {
+		b.window.add(now, 1)
+		b.consecutiveFailures++
+	}
+
+	switch b.state {
+	case StateHalfOpen:
+		if !halfOpen {
+			return // Hasil request yang dimulai sebelum circuit terbuka
+		}
+		if r == ResultFailure {
+			b.trip(now, "request percobaan half-open gagal")
+			return
+		}
+		b.halfOpenSuccesses++
+		if b.halfOpenSuccesses >= b.cfg.HalfOpenRequests {
+			b.window.reset()
+			b.setState(StateClosed, "request percobaan half-open berhasil")
+		}
+	case StateClosed:
+		if r == ResultSuccess {
+			return
+		}
+		if b.consecutiveFailures >= b.cfg.ConsecutiveFailures {
+			b.trip(now, fmt.Sprintf("%d kegagalan berturut-turut", b.consecutiveFailures))
+			return
+		}
+		if b.cfg.ErrorRate > 0 {
+			counts := b.window.sum(now)
+			failures, total := counts[1], counts[0]+counts[1]
+			if total >= b.cfg.MinRequests && float64(failures)/float64(total) >= b.cfg.ErrorRate {
+				b.trip(now, fmt.Sprintf("rasio error %.0f%% dari %d request", 100*float64(failures)/float64(total), total))
+			}
+		}
+	}
+}
+
+func (b *Breaker) trip(now time.Time, reason string) {
+	b.openedAt = now
+	b.setState(StateOpen, reason)
+}
+
+func (b *Breaker) setState(state BreakerState, reason string) {
+	if b.state == state {
+		return
+	}
+	log.Printf("[CIRCUIT] %s: %s -> %s (%s)", b.name, b.state, state, reason)
+	b.state = state
+	b.halfOpenInFlight = 0
+	b.halfOpenSuccesses = 0
+	if state == StateClosed {
+		b.consecutiveFailures = 0
+	}
+}
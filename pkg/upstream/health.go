@@ -0,0 +1,214 @@
+// pkg/upstream/health.go
+package upstream
+
+import (
+	"api-gateway-go/pkg/config"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// healthState adalah hasil probe aktif terakhir untuk satu instance.
+type healthState struct {
+	mu                   sync.Mutex
+	healthy              bool
+	consecutiveSuccesses int
+	consecutiveFailures  int
+	lastCheck            time.Time
+	lastError            string
+}
+
+// TargetStatus adalah snapshot kondisi instance untuk endpoint admin.
+type TargetStatus struct {
+	URL                  string     `json:"url"`
+	Weight               int        `json:"weight"`
+	Healthy              bool       `json:"healthy"`
+	ActiveRequests       int64      `json:"active_requests"`
+	ConsecutiveSuccesses int        `json:"consecutive_successes"`
+	ConsecutiveFailures  int        `json:"consecutive_failures"`
+	LastCheck            *time.Time `json:"last_check,omitempty"`
+	LastError            string     `json:"last_error,omitempty"`
+	Circuit              string     `json:"circuit,omitempty"`
+}
+
+// Healthy melaporkan apakah instance boleh menerima request. Instance baru
+// dianggap sehat sampai probe membuktikan sebaliknya.
+func (t *Target) Healthy() bool {
+	t.health.mu.Lock()
+	defer t.health.mu.Unlock()
+	return t.health.healthy
+}
+
+// Status mengembalikan snapshot kondisi instance.
+func (t *Target) Status() TargetStatus {
+	t.health.mu.Lock()
+	defer t.health.mu.Unlock()
+	st := TargetStatus{
+		URL:                  t.URL.String(),
+		Weight:               t.Weight,
+		Healthy:              t.health.healthy,
+		ActiveRequests:       t.Active(),
+		ConsecutiveSuccesses: t.health.consecutiveSuccesses,
+		ConsecutiveFailures:  t.health.consecutiveFailures,
+		LastError:            t.health.lastError,
+	}
+	if !t.health.lastCheck.IsZero() {
+		last := t.health.lastCheck
+		st.LastCheck = &last
+	}
+	if t.breaker != nil {
+		st.Circuit = t.breaker.State().String()
+	}
+	return st
+}
+
+// recordProbe mencatat hasil probe dan mengembalikan true jika status sehat berubah.
+func (t *Target) recordProbe(err error, cfg config.HealthCheckConfig) (changed bool) {
+	h := &t.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastCheck = time.Now()
+	if err == nil {
+		h.lastError = ""
+		h.consecutiveSuccesses++
+		h.consecutiveFailures = 0
+		if !h.healthy && h.consecutiveSuccesses >= cfg.HealthyThreshold {
+			h.healthy = true
+			return true
+		}
+		return false
+	}
+
+	h.lastError = err.Error()
+	h.consecutiveFailures++
+	h.consecutiveSuccesses = 0
+	if h.healthy && h.consecutiveFailures >= cfg.UnhealthyThreshold {
+		h.healthy = false
+		return true
+	}
+	return false
+}
+
+// InheritState mewarisi status dari prev (Service generasi sebelumnya) supaya
+// instance yang sedang down atau circuit yang sedang terbuka tidak langsung
+// kembali ke rotasi setelah reload konfigurasi. Status kesehatan disalin per
+// URL instance, hanya jika HEALTH_CHECK aktif di konfigurasi baru: tanpa probe
+// tidak ada yang bisa memulihkan instance yang tercatat down. Circuit breaker
+// dipakai ulang jika konfigurasinya tidak berubah.
+func (s *Service) InheritState(prev *Service) {
+	if prev == nil {
+		return
+	}
+	sameBreaker := s.circuitBreaker == prev.circuitBreaker
+	if sameBreaker && s.breaker != nil {
+		s.breaker = prev.breaker
+	}
+	old := make(map[string]*Target, len(prev.Targets))
+	for _, t := range prev.Targets {
+		old[t.URL.String()] = t
+	}
+	for _, t := range s.Targets {
+		p, ok := old[t.URL.String()]
+		if !ok {
+			continue
+		}
+		if sameBreaker && t.breaker != nil && p.breaker != nil {
+			t.breaker = p.breaker
+		}
+		if !s.healthCheck.Enabled {
+			continue
+		}
+		p.health.mu.Lock()
+		t.health.healthy = p.health.healthy
+		t.health.consecutiveSuccesses = p.health.consecutiveSuccesses
+		t.health.consecutiveFailures = p.health.consecutiveFailures
+		t.health.lastCheck = p.health.lastCheck
+		t.health.lastError = p.health.lastError
+		p.health.mu.Unlock()
+	}
+}
+
+// StartHealthChecks menjalankan probe berkala ke setiap instance di background
+// jika HEALTH_CHECK diaktifkan. Fungsi stop yang dikembalikan menghentikan
+// semua probe dan menunggu sampai selesai.
+func (s *Service) StartHealthChecks() (stop func()) {
+	if !s.healthCheck.Enabled {
+		return func() {}
+	}
+	cfg := s.healthCheck.WithDefaults()
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		// Jangan ikuti redirect; status 3xx dinilai sesuai EXPECTED_STATUS.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, t := range s.Targets {
+		wg.Add(1)
+		go func(t *Target) {
+			defer wg.Done()
+			ticker := time.NewTicker(cfg.Interval)
+			defer ticker.Stop()
+			for {
+				s.probe(ctx, client, t, cfg)
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(t)
+	}
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func (s *Service) probe(ctx context.Context, client *http.Client, t *Target, cfg config.HealthCheckConfig) {
+	url := t.URL.Scheme + "://" + t.URL.Host + cfg.Path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return
+	}
+	req.Header.Set("User-Agent", "api-gateway-health-check")
+
+	resp, err := client.Do(req)
+	if ctx.Err() != nil {
+		return // Generasi sudah diganti, hasil probe diabaikan
+	}
+	if err == nil {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		if !expectedStatus(resp.StatusCode, cfg.ExpectedStatus) {
+			err = fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+	}
+
+	if t.recordProbe(err, cfg) {
+		if err != nil {
+			log.Printf("[HEALTH] Instance %s (service %s) UNHEALTHY, dikeluarkan dari rotasi: %v", t.URL.Host, s.Name, err)
+		} else {
+			log.Printf("[HEALTH] Instance %s (service %s) HEALTHY, kembali ke rotasi", t.URL.Host, s.Name)
+		}
+	}
+}
+
+func expectedStatus(code int, expected []int) bool {
+	if len(expected) == 0 {
+		return code >= 200 && code < 300
+	}
+	for _, e := range expected {
+		if code == e {
+			return true
+		}
+	}
+	return false
+}
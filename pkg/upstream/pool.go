@@ -0,0 +1,70 @@
+// pkg/upstream/pool.go
+package upstream
+
+import (
+	"api-gateway-go/pkg/config"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TransportPool membuat http.Transport untuk rute-rute dalam satu generasi
+// konfigurasi. Rute dengan timeout koneksi dan timeout header response yang
+// sama memakai http.Transport yang sama sehingga connection pool-nya dipakai
+// bersama.
+type TransportPool struct {
+	cfg config.TransportConfig
+
+	mu         sync.Mutex
+	transports map[transportKey]*http.Transport
+}
+
+type transportKey struct {
+	connect        time.Duration
+	responseHeader time.Duration
+}
+
+// NewTransportPool membuat TransportPool dari konfigurasi UPSTREAM_TRANSPORT.
+func NewTransportPool(cfg config.TransportConfig) *TransportPool {
+	return &TransportPool{cfg: cfg, transports: make(map[transportKey]*http.Transport)}
+}
+
+// Get mengembalikan http.Transport untuk timeout rute yang sudah diisi
+// default-nya (lihat config.TimeoutsConfig.WithDefaults).
+func (p *TransportPool) Get(timeouts config.TimeoutsConfig) *http.Transport {
+	key := transportKey{connect: timeouts.Connect, responseHeader: timeouts.ResponseHeader}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if t, ok := p.transports[key]; ok {
+		return t
+	}
+	dialer := &net.Dialer{
+		Timeout:   key.connect,
+		KeepAlive: 30 * time.Second,
+	}
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   p.cfg.TLSHandshakeTimeout,
+		ResponseHeaderTimeout: key.responseHeader,
+		ExpectContinueTimeout: time.Second,
+		MaxIdleConns:          p.cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   p.cfg.MaxIdleConnsPerHost,
+		IdleConnTimeout:       p.cfg.IdleConnTimeout,
+	}
+	p.transports[key] = t
+	return t
+}
+
+// CloseIdleConnections menutup koneksi idle di semua transport. Dipanggil saat
+// generasi diganti; koneksi yang sedang dipakai tetap berjalan sampai selesai.
+func (p *TransportPool) CloseIdleConnections() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, t := range p.transports {
+		t.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,196 @@
+// pkg/upstream/retry.go
+package upstream
+
+import (
+	"api-gateway-go/pkg/config"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrPerTryTimeout dikembalikan saat satu percobaan tidak menerima header
+// response dalam PER_TRY_TIMEOUT.
+var ErrPerTryTimeout = errors.New("upstream per-try timeout exceeded")
+
+// RetryPolicy adalah aturan retry untuk satu rute.
+type RetryPolicy struct {
+	maxAttempts     int
+	perTryTimeout   time.Duration
+	backoff         time.Duration
+	maxBackoff      time.Duration
+	retryableStatus []int
+	retryOnErrors   []string
+	maxBodyBytes    int64
+}
+
+// NewRetryPolicy membuat RetryPolicy dari konfigurasi rute. Mengembalikan nil
+// jika retry tidak aktif (MAX_ATTEMPTS <= 1) dan PER_TRY_TIMEOUT tidak diisi.
+func NewRetryPolicy(cfg config.RetryConfig) *RetryPolicy {
+	if cfg.MaxAttempts <= 1 && cfg.PerTryTimeout <= 0 {
+		return nil
+	}
+	p := &RetryPolicy{
+		maxAttempts:     max(cfg.MaxAttempts, 1),
+		perTryTimeout:   cfg.PerTryTimeout,
+		backoff:         cfg.Backoff,
+		maxBackoff:      cfg.MaxBackoff,
+		retryableStatus: cfg.RetryableStatus,
+		retryOnErrors:   cfg.RetryOnErrors,
+		maxBodyBytes:    cfg.MaxBodyBytes,
+	}
+	if p.backoff <= 0 {
+		p.backoff = 50 * time.Millisecond
+	}
+	if p.maxBackoff <= 0 {
+		p.maxBackoff = time.Second
+	}
+	if len(p.retryableStatus) == 0 {
+		p.retryableStatus = []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	}
+	if len(p.retryOnErrors) == 0 {
+		p.retryOnErrors = []string{config.RetryOnConnect, config.RetryOnReset, config.RetryOnTimeout}
+	}
+	if p.maxBodyBytes <= 0 {
+		p.maxBodyBytes = 1 << 20
+	}
+	return p
+}
+
+// appliesTo melaporkan apakah request boleh di-retry: method idempoten atau
+// request dengan header Idempotency-Key.
+func (p *RetryPolicy) appliesTo(req *http.Request) bool {
+	if p.maxAttempts <= 1 {
+		return false
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// shouldRetry melaporkan apakah hasil satu percobaan layak di-retry.
+func (p *RetryPolicy) shouldRetry(resp *http.Response, err error) bool {
+	if err == nil {
+		return slices.Contains(p.retryableStatus, resp.StatusCode)
+	}
+	var circuitOpen *CircuitOpenError
+	if errors.As(err, &circuitOpen) || errors.Is(err, ErrNoAvailableTarget) {
+		return false
+	}
+	switch {
+	case errors.Is(err, ErrPerTryTimeout):
+		return slices.Contains(p.retryOnErrors, config.RetryOnTimeout)
+	case isDialError(err):
+		return slices.Contains(p.retryOnErrors, config.RetryOnConnect)
+	case IsTimeout(err):
+		return slices.Contains(p.retryOnErrors, config.RetryOnTimeout)
+	case isResetError(err):
+		return slices.Contains(p.retryOnErrors, config.RetryOnReset)
+	}
+	return false
+}
+
+// backoffFor mengembalikan jeda sebelum percobaan ke-attempt (mulai dari 2)
+// dengan exponential backoff dan full jitter.
+func (p *RetryPolicy) backoffFor(attempt int) time.Duration {
+	ceiling := p.backoff << (attempt - 2)
+	if ceiling <= 0 || ceiling > p.maxBackoff {
+		ceiling = p.maxBackoff
+	}
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+// IsTimeout melaporkan apakah err disebabkan timeout: per-try timeout, batas
+// waktu total request, timeout koneksi, atau timeout menunggu header response.
+func IsTimeout(err error) bool {
+	if errors.Is(err, ErrPerTryTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+func isResetError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// bufferBody membaca body request (maksimal limit byte) agar bisa dikirim ulang.
+// Jika body lebih besar dari limit, replay bernilai false dan req.Body diganti
+// dengan reader yang tetap berisi seluruh body untuk satu kali percobaan.
+func bufferBody(req *http.Request, limit int64) (replay bool, err error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return true, nil
+	}
+	buf, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+	if err != nil {
+		return false, err
+	}
+	if int64(len(buf)) > limit {
+		req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+		return false, nil
+	}
+	req.Body.Close()
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return true, nil
+}
+
+// RetryBudget membatasi retry secara global: dalam satu window, jumlah retry
+// tidak boleh melebihi ratio x jumlah request ditambah minRetries.
+type RetryBudget struct {
+	ratio      float64
+	minRetries int
+
+	mu     sync.Mutex
+	window rollingWindow // Indeks 0 = request, 1 = retry
+}
+
+// NewRetryBudget membuat RetryBudget dari konfigurasi RETRY_BUDGET.
+func NewRetryBudget(cfg config.RetryBudgetConfig) *RetryBudget {
+	return &RetryBudget{ratio: cfg.Ratio, minRetries: cfg.MinRetries, window: newRollingWindow(cfg.Window)}
+}
+
+func (b *RetryBudget) recordRequest() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.window.add(time.Now(), 0)
+}
+
+// tryRetry mengambil satu jatah retry jika budget masih tersedia.
+func (b *RetryBudget) tryRetry() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	counts := b.window.sum(now)
+	if float64(counts[1]+1) > b.ratio*float64(counts[0])+float64(b.minRetries) {
+		return false
+	}
+	b.window.add(now, 1)
+	return true
+}
@@ -0,0 +1,163 @@
+// pkg/upstream/service.go
+package upstream
+
+import (
+	"api-gateway-go/pkg/config"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrNoAvailableTarget dikembalikan saat tidak ada instance yang bisa dipilih.
+var ErrNoAvailableTarget = errors.New("no available upstream instance")
+
+// Target adalah satu instance dari sebuah service upstream.
+type Target struct {
+	URL    *url.URL
+	Weight int
+
+	active  int64 // Jumlah request yang sedang berjalan ke instance ini
+	health  healthState
+	breaker *Breaker // nil jika circuit breaker per instance tidak aktif
+}
+
+// Active mengembalikan jumlah request yang sedang berjalan ke instance ini.
+func (t *Target) Active() int64 {
+	return atomic.LoadInt64(&t.active)
+}
+
+func (t *Target) acquire() { atomic.AddInt64(&t.active, 1) }
+func (t *Target) release() { atomic.AddInt64(&t.active, -1) }
+
+// Service adalah sekumpulan instance upstream beserta strategi load balancing-nya.
+type Service struct {
+	Name     string
+	Strategy string
+	Targets  []*Target
+
+	balancer       balancer
+	hashKey        string
+	healthCheck    config.HealthCheckConfig
+	circuitBreaker config.CircuitBreakerConfig
+	breaker        *Breaker // nil jika circuit breaker tidak aktif
+}
+
+// NewService membuat Service dari konfigurasi. cfg diasumsikan sudah lolos
+// config.Validate.
+func NewService(name string, cfg config.ServiceConfig) (*Service, error) {
+	strategy := cfg.Strategy
+	if strategy == "" {
+		strategy = config.StrategyRoundRobin
+	}
+	svc := &Service{
+		Name:           name,
+		Strategy:       strategy,
+		hashKey:        cfg.HashKey,
+		healthCheck:    cfg.HealthCheck,
+		circuitBreaker: cfg.CircuitBreaker,
+	}
+	if cfg.CircuitBreaker.Enabled {
+		svc.breaker = NewBreaker(name, cfg.CircuitBreaker)
+	}
+	for _, tc := range cfg.Targets {
+		u, err := url.Parse(tc.URL)
+		if err != nil {
+			return nil, fmt.Errorf("URL instance %s tidak valid: %w", name, err)
+		}
+		weight := tc.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		target := &Target{URL: u, Weight: weight}
+		target.health.healthy = true
+		if cfg.CircuitBreaker.Enabled && len(cfg.Targets) > 1 {
+			target.breaker = NewBreaker(name+"/"+u.Host, cfg.CircuitBreaker)
+		}
+		svc.Targets = append(svc.Targets, target)
+	}
+	svc.balancer = newBalancer(strategy, svc.Targets)
+	return svc, nil
+}
+
+// Pick memilih instance sehat yang circuit-nya tidak terbuka untuk request r,
+// melewati instance di exclude (misalnya instance yang sudah dicoba saat retry).
+func (s *Service) Pick(r *http.Request, exclude map[*Target]bool) (*Target, error) {
+	candidates := make([]*Target, 0, len(s.Targets))
+	var circuitOpen *CircuitOpenError
+	for _, t := range s.Targets {
+		if exclude[t] || !t.Healthy() {
+			continue
+		}
+		if t.breaker.State() == StateOpen {
+			// Simpan waktu tunggu terpendek untuk Retry-After jika semua circuit terbuka
+			if wait := t.breaker.retryAfter(); circuitOpen == nil || wait < circuitOpen.RetryAfter {
+				circuitOpen = &CircuitOpenError{Name: s.Name, RetryAfter: wait}
+			}
+			continue
+		}
+		candidates = append(candidates, t)
+	}
+	if len(candidates) == 0 {
+		if circuitOpen != nil {
+			return nil, circuitOpen
+		}
+		return nil, ErrNoAvailableTarget
+	}
+	return s.balancer.next(candidates, s.requestKey(r)), nil
+}
+
+// requestKey mengembalikan kunci consistent hash untuk request r.
+func (s *Service) requestKey(r *http.Request) string {
+	if name, ok := strings.CutPrefix(s.hashKey, "header:"); ok {
+		if v := r.Header.Get(name); v != "" {
+			return v
+		}
+	}
+	return ClientIP(r)
+}
+
+// ServiceStatus adalah snapshot kondisi service untuk endpoint admin.
+type ServiceStatus struct {
+	Name        string         `json:"name"`
+	Strategy    string         `json:"strategy"`
+	HealthCheck bool           `json:"health_check"`
+	Circuit     string         `json:"circuit,omitempty"`
+	Targets     []TargetStatus `json:"targets"`
+}
+
+// Status mengembalikan snapshot kondisi service dan semua instance-nya.
+func (s *Service) Status() ServiceStatus {
+	st := ServiceStatus{Name: s.Name, Strategy: s.Strategy, HealthCheck: s.healthCheck.Enabled}
+	if s.breaker != nil {
+		st.Circuit = s.breaker.State().String()
+	}
+	for _, t := range s.Targets {
+		st.Targets = append(st.Targets, t.Status())
+	}
+	return st
+}
+
+type clientIPKey struct{}
+
+// WithClientIP menyimpan IP klien (hasil c.ClientIP()) di context request
+// agar bisa dipakai sebagai kunci hash oleh transport.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey{}, ip)
+}
+
+// ClientIP mengembalikan IP klien dari context, atau dari RemoteAddr jika tidak ada.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok && ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
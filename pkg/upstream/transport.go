@@ -0,0 +1,247 @@
+// pkg/upstream/transport.go
+package upstream
+
+import (
+	"api-gateway-go/pkg/requestid"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Transport adalah http.RoundTripper yang memilih instance dari Service untuk
+// setiap request, menulis ulang URL ke instance tersebut, lalu meneruskannya
+// lewat Base. Path request digabung di belakang path URL instance. Jika Retry
+// diisi, percobaan yang gagal diulang sesuai RetryPolicy selama Budget masih
+// tersedia.
+type Transport struct {
+	Service *Service
+	Base    http.RoundTripper
+	Retry   *RetryPolicy // nil berarti satu percobaan tanpa per-try timeout
+	Budget  *RetryBudget // Dipakai bersama oleh semua rute
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	policy := t.Retry
+	if policy == nil || !policy.appliesTo(req) {
+		resp, _, err := t.attempt(req, nil)
+		return resp, err
+	}
+
+	replay, err := bufferBody(req, policy.maxBodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	t.Budget.recordRequest()
+
+	var exclude map[*Target]bool
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			req.Body, _ = req.GetBody()
+		}
+		resp, target, err := t.attempt(req, exclude)
+
+		if !replay || attempt >= policy.maxAttempts || req.Context().Err() != nil || !policy.shouldRetry(resp, err) {
+			return resp, err
+		}
+		if !t.Budget.tryRetry() {
+			requestid.Logf(req.Context(), "[RETRY] Retry budget habis, tidak me-retry request ke service %s", t.Service.Name)
+			return resp, err
+		}
+
+		reason := "error: " + errString(err)
+		if resp != nil {
+			reason = "status " + resp.Status
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		// Percobaan berikutnya diarahkan ke instance lain jika ada; jika semua
+		// instance sudah dicoba, mulai lagi dari semua instance.
+		if exclude == nil {
+			exclude = make(map[*Target]bool)
+		}
+		exclude[target] = true
+		if len(exclude) >= len(t.Service.Targets) {
+			exclude = nil
+		}
+
+		wait := policy.backoffFor(attempt + 1)
+		requestid.Logf(req.Context(), "[RETRY] Percobaan %d/%d ke service %s gagal (%s), retry dalam %v", attempt, policy.maxAttempts, t.Service.Name, reason, wait)
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// attempt menjalankan satu percobaan: memeriksa circuit breaker, memilih
+// instance, lalu mengirim request dengan per-try timeout jika dikonfigurasi.
+func (t *Transport) attempt(req *http.Request, exclude map[*Target]bool) (*http.Response, *Target, error) {
+	// Circuit breaker level service: gagal cepat tanpa menyentuh upstream.
+	serviceDone, err := t.Service.breaker.Allow()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, targetDone, err := t.pickTarget(req, exclude)
+	if err != nil {
+		serviceDone(ResultIgnored)
+		return nil, nil, err
+	}
+	done := func(r Result) {
+		targetDone(r)
+		serviceDone(r)
+	}
+
+	ctx := req.Context()
+	cancel := context.CancelFunc(func() {})
+	var timedOut atomic.Bool
+	var timer *time.Timer
+	if t.Retry != nil && t.Retry.perTryTimeout > 0 {
+		ctx, cancel = context.WithCancel(ctx)
+		timer = time.AfterFunc(t.Retry.perTryTimeout, func() {
+			timedOut.Store(true)
+			cancel()
+		})
+	}
+
+	out := req.Clone(ctx)
+	out.URL = rewriteURL(req.URL, target.URL)
+	out.Host = target.URL.Host
+	requestid.Logf(req.Context(), "Proxying request to: %s%s (service %s)", target.URL.Scheme+"://"+target.URL.Host, out.URL.Path, t.Service.Name)
+
+	target.acquire()
+	resp, err := t.base().RoundTrip(out)
+	if timer != nil {
+		// Per-try timeout hanya berlaku sampai header response diterima
+		timer.Stop()
+	}
+	if err != nil {
+		target.release()
+		cancel()
+		switch {
+		case timedOut.Load():
+			err = fmt.Errorf("%w after %v: %v", ErrPerTryTimeout, t.Retry.perTryTimeout, err)
+			done(ResultFailure)
+		case req.Context().Err() == context.Canceled:
+			done(ResultIgnored) // Klien membatalkan request, bukan kesalahan upstream
+		default:
+			done(ResultFailure)
+		}
+		return nil, target, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		done(ResultFailure)
+	} else {
+		done(ResultSuccess)
+	}
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		// Body koneksi upgrade harus tetap io.ReadWriteCloser untuk ReverseProxy,
+		// jadi jangan dibungkus; hitungan koneksi aktif dilepas di sini.
+		target.release()
+		return resp, target, nil
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: func() {
+		target.release()
+		cancel()
+	}}
+	return resp, target, nil
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// pickTarget memilih instance dan meminta izin ke circuit breaker instance
+// tersebut. Instance yang ditolak breaker-nya (misal kuota half-open penuh)
+// dilewati dan instance lain dicoba.
+func (t *Transport) pickTarget(req *http.Request, excluded map[*Target]bool) (*Target, func(Result), error) {
+	exclude := excluded
+	var lastOpen error
+	for {
+		target, err := t.Service.Pick(req, exclude)
+		if err != nil {
+			if lastOpen != nil {
+				return nil, nil, lastOpen
+			}
+			return nil, nil, err
+		}
+		done, err := target.breaker.Allow()
+		if err == nil {
+			return target, done, nil
+		}
+		lastOpen = err
+		exclude = maps.Clone(exclude)
+		if exclude == nil {
+			exclude = make(map[*Target]bool)
+		}
+		exclude[target] = true
+	}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// releaseOnClose melepas hitungan request aktif instance saat body response
+// selesai dibaca dan ditutup oleh ReverseProxy.
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseOnClose) Close() error {
+	b.once.Do(b.release)
+	return b.ReadCloser.Close()
+}
+
+// rewriteURL mengarahkan u ke target dengan cara yang sama seperti
+// httputil.NewSingleHostReverseProxy: skema dan host dari target, path target
+// digabung dengan path request, dan query target digabung dengan query request.
+func rewriteURL(u, target *url.URL) *url.URL {
+	out := *u
+	out.Scheme = target.Scheme
+	out.Host = target.Host
+	out.Path, out.RawPath = joinURLPath(target, u)
+	if target.RawQuery == "" || u.RawQuery == "" {
+		out.RawQuery = target.RawQuery + u.RawQuery
+	} else {
+		out.RawQuery = target.RawQuery + "&" + u.RawQuery
+	}
+	return &out
+}
+
+func joinURLPath(a, b *url.URL) (path, rawpath string) {
+	if a.RawPath == "" && b.RawPath == "" {
+		return singleJoiningSlash(a.Path, b.Path), ""
+	}
+	apath := a.EscapedPath()
+	bpath := b.EscapedPath()
+	return singleJoiningSlash(a.Path, b.Path), singleJoiningSlash(apath, bpath)
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
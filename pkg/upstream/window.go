@@ -0,0 +1,51 @@
+// pkg/upstream/window.go
+package upstream
+
+import "time"
+
+// windowBuckets adalah jumlah bucket dalam satu rolling window.
+const windowBuckets = 10
+
+type windowBucket struct {
+	start  time.Time
+	counts [2]int
+}
+
+// rollingWindow menghitung dua jenis kejadian (misal sukses/gagal atau
+// request/retry) dalam jendela waktu bergulir. Tidak aman untuk dipakai
+// bersamaan; pemanggil wajib memegang lock sendiri.
+type rollingWindow struct {
+	size    time.Duration
+	buckets [windowBuckets]windowBucket
+}
+
+// newRollingWindow membuat rolling window sepanjang window. window minimal
+// config.MinWindow (divalidasi saat konfigurasi dimuat) agar ukuran bucket
+// tidak nol.
+func newRollingWindow(window time.Duration) rollingWindow {
+	return rollingWindow{size: window}
+}
+
+func (w *rollingWindow) add(now time.Time, kind int) {
+	bucketSize := w.size / windowBuckets
+	start := now.Truncate(bucketSize)
+	b := &w.buckets[(start.UnixNano()/int64(bucketSize))%windowBuckets]
+	if !b.start.Equal(start) {
+		*b = windowBucket{start: start}
+	}
+	b.counts[kind]++
+}
+
+func (w *rollingWindow) sum(now time.Time) (counts [2]int) {
+	for _, b := range w.buckets {
+		if now.Sub(b.start) < w.size {
+			counts[0] += b.counts[0]
+			counts[1] += b.counts[1]
+		}
+	}
+	return counts
+}
+
+func (w *rollingWindow) reset() {
+	w.buckets = [windowBuckets]windowBucket{}
+}